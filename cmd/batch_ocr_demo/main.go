@@ -3,65 +3,49 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
 	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyun"
 	"log/slog"
 	"os"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId     string
-	apiKey    string
-	apiSecret string
+	flags = config.RegisterFlags(nil)
+
 	inputDir  string
 	outputDir string
 	category  string
-	logLevel  string
 	workers   int
+	qps       float64
+	inflight  int
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("提示: 未找到 .env 文件。")
-	}
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	apiKey = os.Getenv("XFYUN_API_KEY")
-	apiSecret = os.Getenv("XFYUN_API_SECRET")
-
 	flag.StringVar(&inputDir, "input", "", "指定输入目录路径（包含要识别的图片文件）")
 	flag.StringVar(&outputDir, "output", "", "指定输出目录路径（用于保存识别结果）")
 	flag.StringVar(&category, "cat", "ch_en", "指定识别类型 (例如: general, hm_general_ocr, ...)")
-	flag.StringVar(&logLevel, "level", "info", "设置日志级别 (debug, info, warn, error)")
 	flag.IntVar(&workers, "workers", 3, "并发工作线程数")
+	flag.Float64Var(&qps, "qps", 0, "每秒最多发出的请求数（按 AppID 共享），0 表示不限制")
+	flag.IntVar(&inflight, "max-inflight", 0, "同时进行中的请求数上限（按 AppID 共享），0 表示不限制")
 	flag.Parse()
 }
 
 func main() {
-	var level slog.Level
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
-
-	if appId == "" || apiKey == "" || apiSecret == "" {
-		logger.Error("凭证未配置，请在 .env 文件中设置 XFYUN_APP_ID, XFYUN_API_KEY, 和 XFYUN_API_SECRET。")
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
+
+	creds, err := cfg.Credentials(xfyun.ServiceOCR)
+	if err != nil {
+		logger.Error("凭证未配置，请在 .env 文件中设置 XFYUN_APP_ID, XFYUN_API_KEY, 和 XFYUN_API_SECRET。", "error", err)
 		os.Exit(1)
 	}
 
@@ -97,7 +81,18 @@ func main() {
 
 	logger.Info("找到图片文件", "count", len(imageFiles), "input", inputDir, "output", outputDir)
 
-	client := ocr.NewClient(appId, apiKey, apiSecret, ocr.WithLogger(logger))
+	// 同一 AppID 的配额在所有工作线程之间共享
+	// -qps 与 -max-inflight 覆盖配置文件中的 rate_limit
+	var opts []ocr.Option
+	if qps > 0 || inflight > 0 {
+		limiter := ratelimit.ForAppID(creds.AppID, ratelimit.Config{QPS: qps, Burst: 1, MaxInFlight: inflight})
+		opts = append(opts, ocr.WithLimiter(limiter))
+	}
+	client, err := cfg.NewOCR(opts...)
+	if err != nil {
+		logger.Error("创建客户端失败", "error", err)
+		os.Exit(1)
+	}
 
 	// 创建并发处理的工作池
 	processImages(logger, client, imageFiles, outputDir, workers)
@@ -227,24 +222,32 @@ func processSingleImage(logger *slog.Logger, client *ocr.Client, imagePath strin
 		return fmt.Errorf("OCR 识别失败: %w", err)
 	}
 
-	// 解码识别结果
-	decodedText, err := base64.StdEncoding.DecodeString(resp.Payload.OcrOutputText.Text)
+	// 解析识别结果，并按阅读顺序排列各行
+	result, err := resp.Result()
 	if err != nil {
-		return fmt.Errorf("Base64 解码识别结果失败: %w", err)
+		return fmt.Errorf("解析识别结果失败: %w", err)
 	}
+	result.SortReadingOrder()
 
-	// 生成输出文件名（保持原文件名，只改变扩展名）
+	// 生成输出文件名（保持原文件名，只改变扩展名）：.json 为结构化结果，.txt 为纯文本
 	baseName := filepath.Base(imagePath)
 
 	outputName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
-	outputName += "_" + resp.Header.Sid + ".json"
+	outputName += "_" + resp.Header.Sid
 
-	outputPath := filepath.Join(outputDir, outputName)
+	outputPath := filepath.Join(outputDir, outputName+".json")
 
 	// 保存结果到文件
-	if err := os.WriteFile(outputPath, decodedText, 0644); err != nil {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化识别结果失败: %w", err)
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
 		return fmt.Errorf("保存结果文件失败: %w", err)
 	}
+	if err := os.WriteFile(filepath.Join(outputDir, outputName+".txt"), []byte(result.PlainText()), 0644); err != nil {
+		return fmt.Errorf("保存纯文本失败: %w", err)
+	}
 
 	logger.Debug("结果已保存", "input", imagePath, "output", outputPath)
 	return nil
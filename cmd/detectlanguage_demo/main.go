@@ -3,51 +3,29 @@ package main
 import (
 	"bufio"
 	"flag"
-	"github.com/fruitbars/goxfyunclient/pkg/service/detectlanguage"
-	"log/slog"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"os"
 	"strings"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId     string
-	apiKey    string
-	apiSecret string
-	filePath  string
-	logLevel  string
+	flags = config.RegisterFlags(nil)
+
+	filePath string
 )
 
 func init() {
-	_ = godotenv.Load() // 忽略 .env 文件不存在的情况
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	apiKey = os.Getenv("XFYUN_API_KEY")
-	apiSecret = os.Getenv("XFYUN_API_SECRET")
-
 	flag.StringVar(&filePath, "file", "", "指定包含待识别文本的文件路径")
-	flag.StringVar(&logLevel, "level", "info", "日志级别：debug|info|warn|error")
 	flag.Parse()
 }
 
 func main() {
-	// 初始化日志
-	var level slog.Level
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
 
-	if appId == "" || apiKey == "" || apiSecret == "" {
-		logger.Error("凭证未配置，请在 .env 或环境变量中设置 XFYUN_APP_ID, XFYUN_API_KEY, XFYUN_API_SECRET")
+	client, err := cfg.NewDetectLanguage()
+	if err != nil {
+		logger.Error("凭证未配置，请在 .env 或环境变量中设置 XFYUN_APP_ID, XFYUN_API_KEY, XFYUN_API_SECRET", "error", err)
 		os.Exit(1)
 	}
 
@@ -64,8 +42,6 @@ func main() {
 	}
 	defer file.Close()
 
-	// 创建客户端
-	client := detectlanguage.NewClient(appId, apiKey, apiSecret, detectlanguage.WithLogger(logger))
 	logger.Info("开始进行语种识别", "file", filePath)
 
 	scanner := bufio.NewScanner(file)
@@ -7,63 +7,35 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/fruitbars/goxfyunclient/pkg/service/iocrld"
-	"log/slog"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"os"
 	"strings"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId       string
-	apiKey      string
-	apiSecret   string
+	flags = config.RegisterFlags(nil)
+
 	imagePath   string
 	jsonPayload string
-	logLevel    string
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("提示: 未找到 .env 文件。")
-	}
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	apiKey = os.Getenv("XFYUN_API_KEY")
-	apiSecret = os.Getenv("XFYUN_API_SECRET")
-
 	flag.StringVar(&imagePath, "file", "", "指定要识别的图片文件路径")
 	flag.StringVar(&jsonPayload, "payload", `{"param":{"extract_title":true}}`, "指定业务处理的 JSON 字符串")
-	flag.StringVar(&logLevel, "level", "info", "设置日志级别 (debug, info, warn, error)")
 	flag.Parse()
 }
 
 func main() {
-	var level slog.Level
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
 
-	if appId == "" || apiKey == "" || apiSecret == "" {
-		logger.Error("凭证未配置")
+	client, err := cfg.NewIOCRLD()
+	if err != nil {
+		logger.Error("凭证未配置", "error", err)
 		os.Exit(1)
 	}
 
-	// 创建 iocrld 客户端
-	client := iocrld.NewClient(appId, apiKey, apiSecret, iocrld.WithLogger(logger))
-
 	var localImagePath string
 	if imagePath == "" {
 		localImagePath = "test_iocrld.jpg"
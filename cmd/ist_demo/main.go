@@ -6,62 +6,37 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"github.com/fruitbars/goxfyunclient/pkg/service/ist"
-	"log/slog"
 	"os"
-	"strings"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId      string
-	secretKey  string
+	flags = config.RegisterFlags(nil)
+
 	audioPath  string
 	lang       string
 	useSpeaker string
-	logLevel   string
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("提示: 未找到 .env 文件。")
-	}
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	secretKey = os.Getenv("XFYUN_SECRET_KEY")
-
 	flag.StringVar(&audioPath, "file", "", "指定要转写的音频文件路径")
 	flag.StringVar(&lang, "lang", "cn", "指定语种 (例如: cn, en)")
 	flag.StringVar(&useSpeaker, "speaker", "true", "是否开启说话人分离 (true/false)")
-	flag.StringVar(&logLevel, "level", "info", "设置日志级别 (debug, info, warn, error)")
 	flag.Parse()
 }
 
 func main() {
-	var level slog.Level
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo // Default to info if unknown
-	}
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
 
-	if appId == "" || secretKey == "" {
-		logger.Error("凭证未配置，请在 .env 文件中设置 XFYUN_APP_ID 和 XFYUN_SECRET_KEY。")
+	client, err := cfg.NewIST()
+	if err != nil {
+		logger.Error("凭证未配置，请在 .env 文件中设置 XFYUN_APP_ID 和 XFYUN_SECRET_KEY。", "error", err)
 		os.Exit(1)
 	}
 
-	client := ist.NewClient(appId, secretKey, ist.WithLogger(logger))
-
 	var localAudioPath string
 	if audioPath == "" {
 		localAudioPath = "test_ist.pcm"
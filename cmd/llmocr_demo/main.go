@@ -6,58 +6,30 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr"
-	"log"
-	"log/slog"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"os"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId     string
-	apiKey    string
-	apiSecret string
+	flags = config.RegisterFlags(nil)
+
 	imagePath string
-	logLevel  string
 )
 
 func init() {
-	// 加载 .env 文件
-	if err := godotenv.Load(); err != nil {
-		log.Println("提示: 未找到 .env 文件，将使用常量中定义的凭证。")
-	}
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	apiKey = os.Getenv("XFYUN_API_KEY")
-	apiSecret = os.Getenv("XFYUN_API_SECRET")
-
 	// 定义命令行参数
 	flag.StringVar(&imagePath, "file", "", "指定要识别的图片文件路径")
-	flag.StringVar(&logLevel, "level", "info", "设置日志级别 (debug, info, warn, error)")
 	flag.Parse()
 }
 
 func main() {
-	// --- 1. 初始化日志 ---
-	var level slog.Level
-	switch logLevel {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
 
-	if appId == "" || apiKey == "" || apiSecret == "" {
-		logger.Error("凭证未配置，请在 .env 文件中或代码中设置 APP_ID, API_KEY, 和 API_SECRET。")
+	client, err := cfg.NewLLMOCR()
+	if err != nil {
+		logger.Error("凭证未配置，请在 .env 文件中或代码中设置 APP_ID, API_KEY, 和 API_SECRET。", "error", err)
 		os.Exit(1)
 	}
 
@@ -77,9 +49,6 @@ func main() {
 		logger.Info("将使用指定的图片文件", "path", localImagePath)
 	}
 
-	// 创建 llmocr 客户端，并注入 logger
-	client := llmocr.NewClient(appId, apiKey, apiSecret, llmocr.WithLogger(logger))
-
 	// 创建一个带超时的上下文
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -6,42 +6,34 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
-	"github.com/joho/godotenv"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"log"
-	"log/slog"
 	"os"
 )
 
-var XFYUN_APP_ID = "your_app_id"
-var XFYUN_API_KEY = ""
-var XFYUN_API_SECRET = ""
-
 var (
+	flags = config.RegisterFlags(nil)
+
 	imagePath string
 	category  string
-	logLevel  string
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("提示: 未找到 .env 文件。")
-	}
-
-	XFYUN_APP_ID = os.Getenv("XFYUN_APP_ID")
-	XFYUN_API_KEY = os.Getenv("XFYUN_API_KEY")
-	XFYUN_API_SECRET = os.Getenv("XFYUN_API_SECRET")
-
 	flag.StringVar(&imagePath, "file", "", "指定要识别的图片文件路径")
 	flag.StringVar(&category, "cat", "ch_en", "指定识别类型 (例如: general, hm_general_ocr, ...)")
-	flag.StringVar(&logLevel, "level", "info", "设置日志级别 (debug, info, warn, error)")
 	flag.Parse()
 }
 
 // RunOCR 执行 OCR 识别，传入图片路径与类别，返回识别文本
 func RunOCR(imagePath, category, logLevel string) (sid, text string, err error) {
-
-	client := ocr.NewClient(XFYUN_APP_ID, XFYUN_API_KEY, XFYUN_API_SECRET)
+	cfg, err := flags.Load()
+	if err != nil {
+		return "", "", err
+	}
+	client, err := cfg.NewOCR()
+	if err != nil {
+		return "", "", err
+	}
 
 	if imagePath == "" {
 		return "", "", errors.New("imagePath 不能为空")
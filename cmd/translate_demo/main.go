@@ -4,64 +4,38 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"github.com/fruitbars/goxfyunclient/pkg/service/translate"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"log/slog"
 	"os"
 	"strings"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId      string
-	apiKey     string
-	apiSecret  string
+	flags = config.RegisterFlags(nil)
+
 	filePath   string
 	sourceLang string
 	targetLang string
-	logLevel   string
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("提示: 未找到 .env 文件。")
-	}
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	apiKey = os.Getenv("XFYUN_API_KEY")
-	apiSecret = os.Getenv("XFYUN_API_SECRET")
-
 	flag.StringVar(&filePath, "file", "", "包含待翻译文本的文件路径")
 	flag.StringVar(&sourceLang, "from", "cn", "源语种 (例如: en, cn)")
 	flag.StringVar(&targetLang, "to", "en", "目标语种 (例如: en, cn)")
-	flag.StringVar(&logLevel, "level", "info", "设置日志级别 (debug, info, warn, error)")
 	flag.Parse()
 }
 
 func main() {
-	var level slog.Level
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
 
-	if appId == "" || apiKey == "" || apiSecret == "" {
-		logger.Error("凭证未配置, 请在 .env 文件中设置 XFYUN_APP_ID, XFYUN_API_KEY, 和 XFYUN_API_SECRET。")
+	client, err := cfg.NewTranslate()
+	if err != nil {
+		logger.Error("凭证未配置, 请在 .env 文件中设置 XFYUN_APP_ID, XFYUN_API_KEY, 和 XFYUN_API_SECRET。", "error", err)
 		os.Exit(1)
 	}
 
-	client := translate.NewClient(appId, apiKey, apiSecret, translate.WithLogger(logger))
-
 	textToTranslate, err := getTextToTranslate()
 	if err != nil {
 		logger.Error("获取待翻译文本失败", "error", err)
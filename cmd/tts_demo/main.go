@@ -4,72 +4,41 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"github.com/fruitbars/goxfyunclient/pkg/service/tts"
+	"github.com/fruitbars/goxfyunclient/pkg/config"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	appId      string
-	apiKey     string
-	apiSecret  string
+	flags = config.RegisterFlags(nil)
+
 	filePath   string
 	outputFile string
-	logLevel   string
 	vcn        string
 	aue        string
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("提示: 未找到 .env 文件。")
-	}
-
-	appId = os.Getenv("XFYUN_APP_ID")
-	apiKey = os.Getenv("XFYUN_API_KEY")
-	apiSecret = os.Getenv("XFYUN_API_SECRET")
-
 	flag.StringVar(&filePath, "file", "", "包含待合成文本的文件路径")
 	flag.StringVar(&outputFile, "out", "", "指定输出音频文件路径 (例如: output/tts_result.mp3)")
-	flag.StringVar(&logLevel, "level", "debug", "设置日志级别 (debug, info, warn, error)")
 	flag.StringVar(&vcn, "vcn", "xiaoyan", "设置发音人 (例如: xiaoyan)")
 	flag.StringVar(&aue, "aue", "mp3", "设置音频编码格式 (例如: mp3, raw)")
 	flag.Parse()
 }
 
 func main() {
-	var level slog.Level
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-	logger := slog.New(
-		slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-			Level:     level,
-			AddSource: true, // 开启文件名和行号
-		}),
-	)
-
-	if appId == "" || apiKey == "" || apiSecret == "" {
-		logger.Error("凭证未配置, 请在 .env 文件中设置 XFYUN_APP_ID, XFYUN_API_KEY, 和 XFYUN_API_SECRET。")
+	cfg := flags.MustLoad()
+	logger := cfg.Logger()
+
+	client, err := cfg.NewTTS()
+	if err != nil {
+		logger.Error("凭证未配置, 请在 .env 文件中设置 XFYUN_APP_ID, XFYUN_API_KEY, 和 XFYUN_API_SECRET。", "error", err)
 		os.Exit(1)
 	}
 
-	client := tts.NewTTSClient(appId, apiKey, apiSecret, tts.WithLogger(logger))
-
 	textToConvert, err := getTextToConvert()
 	if err != nil {
 		logger.Error("获取待合成文本失败", "error", err)
@@ -0,0 +1,127 @@
+// xfyun-emulator 在本地模拟全部讯飞服务，客户端通过 WithHost 指向它即可离线运行。
+//
+//	go run ./cmd/xfyun-emulator -addr :8080
+//	go run ./cmd/xfyun-emulator -config emulator.json -latency 200ms
+//	XFYUN_APP_ID=... XFYUN_API_KEY=... XFYUN_API_SECRET=... go run ./cmd/xfyun-emulator -verify
+//
+// 配置文件为每个服务指定按调用顺序返回的回复，最后一个回复重复使用：
+//
+//	{
+//	  "services": {
+//	    "translate": [{"code": 11202}, {"result": "Hello"}],
+//	    "ist": [{"pending": 2, "latency": "1s"}],
+//	    "tts": [{"result_file": "testdata/hello.pcm"}]
+//	  }
+//	}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/emulator"
+)
+
+type responseConfig struct {
+	Result     string `json:"result"`
+	ResultFile string `json:"result_file"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	HTTPStatus int    `json:"http_status"`
+	Latency    string `json:"latency"`
+	Pending    int    `json:"pending"`
+	FailType   int    `json:"fail_type"`
+}
+
+type config struct {
+	Services map[string][]responseConfig `json:"services"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "监听地址")
+	configPath := flag.String("config", "", "回复配置文件（JSON）")
+	latency := flag.Duration("latency", 0, "每次回复前的默认延迟")
+	verify := flag.Bool("verify", false, "使用 XFYUN_* 环境变量中的凭证校验请求签名")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	opts := []emulator.Option{emulator.WithLatency(*latency), emulator.WithLogger(logger)}
+
+	if *configPath != "" {
+		serviceOpts, err := loadConfig(*configPath)
+		if err != nil {
+			logger.Error("failed to load config", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, serviceOpts...)
+	}
+	if *verify {
+		creds, err := auth.NewEnvProvider(auth.DefaultEnvPrefix).Credentials(context.Background())
+		if err != nil {
+			logger.Error("failed to load credentials", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, emulator.WithCredentials(creds))
+	}
+
+	base := "http://localhost" + *addr
+	for _, service := range emulator.Services {
+		fmt.Printf("%-15s %s\n", service, emulator.Endpoint(base, service))
+	}
+	if err := http.ListenAndServe(*addr, emulator.New(opts...)); err != nil {
+		logger.Error("emulator stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func loadConfig(path string) ([]emulator.Option, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	var opts []emulator.Option
+	for service, items := range cfg.Services {
+		if !slices.Contains(emulator.Services, service) {
+			return nil, fmt.Errorf("unknown service %q", service)
+		}
+		resps := make([]emulator.Response, 0, len(items))
+		for _, item := range items {
+			resp := emulator.Response{
+				Result:     item.Result,
+				Code:       item.Code,
+				Message:    item.Message,
+				HTTPStatus: item.HTTPStatus,
+				Pending:    item.Pending,
+				FailType:   item.FailType,
+			}
+			if item.ResultFile != "" {
+				result, err := os.ReadFile(item.ResultFile)
+				if err != nil {
+					return nil, err
+				}
+				resp.Result = string(result)
+			}
+			if item.Latency != "" {
+				if resp.Latency, err = time.ParseDuration(item.Latency); err != nil {
+					return nil, fmt.Errorf("%s: invalid latency %q: %w", service, item.Latency, err)
+				}
+			}
+			resps = append(resps, resp)
+		}
+		opts = append(opts, emulator.WithSequence(service, resps...))
+	}
+	return opts, nil
+}
@@ -0,0 +1,55 @@
+// xfyun_errcode 查询内嵌的讯飞错误码目录。
+//
+//	go run ./cmd/xfyun_errcode 10163 10313
+//	go run ./cmd/xfyun_errcode -service ist 26601
+//	go run ./cmd/xfyun_errcode -service ist.failType 3
+//	go run ./cmd/xfyun_errcode -service ist          # 列出 ist 的全部错误码
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+func main() {
+	service := flag.String("service", xfyunerr.CatalogCommon, "服务名或命名空间，可选: "+strings.Join(xfyunerr.Namespaces(), ", "))
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		for _, e := range xfyunerr.Entries(*service) {
+			printEntry(e)
+		}
+		return
+	}
+
+	exit := 0
+	for _, arg := range flag.Args() {
+		code, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无效的错误码: %s\n", arg)
+			exit = 1
+			continue
+		}
+		e, ok := xfyunerr.Lookup(*service, code)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%d: 目录中没有该错误码\n", code)
+			exit = 1
+			continue
+		}
+		printEntry(e)
+	}
+	os.Exit(exit)
+}
+
+func printEntry(e xfyunerr.Entry) {
+	category := xfyunerr.CategoryName(e.Category)
+	if category == "" {
+		category = "-"
+	}
+	fmt.Printf("%-14s %6d  %-13s retryable=%-5t %s\n", e.Namespace, e.Code, category, e.Retryable, e.Description)
+}
@@ -1,13 +1,7 @@
 package auth
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
 	"fmt"
-	"net/http"
-	"net/url"
-	"time"
 )
 
 // SchemeType 定义了支持的讯飞鉴权模式的枚举。
@@ -22,65 +16,28 @@ const (
 	// SchemeTypeHMAC 使用 hmac username 字段进行鉴权。
 	// 适用于：LLM OCR 等服务。
 	SchemeTypeHMAC
+
+	// SchemeTypeLFASR 使用 appId、ts、signa 查询参数进行鉴权，签名算法见 Signa。
+	// 适用于：录音文件转写（ist）等 LFASR 风格的服务。
+	// 该模式下签名函数的 apiKey 参数传 AppID，apiSecret 参数传 SecretKey。
+	SchemeTypeLFASR
 )
 
 // AssembleAuthURL generates the final request URL with authentication parameters for Xunfei services.
+// It signs with DefaultSigner using SchemeTypeAPIKey.
 func AssembleAuthURL(requestURL, method, apiKey, apiSecret string) (string, error) {
-	u, err := url.Parse(requestURL)
-	if err != nil {
-		return "", err
-	}
-	host := u.Host
-	path := u.Path
-	date := time.Now().UTC().Format(time.RFC1123)
-
-	// 1. Create the signature string
-	signatureOrigin := fmt.Sprintf("host: %s\ndate: %s\n%s %s HTTP/1.1", host, date, method, path)
-
-	// 2. Encrypt with HMAC-SHA256
-	mac := hmac.New(sha256.New, []byte(apiSecret))
-	mac.Write([]byte(signatureOrigin))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	// 3. Create the authorization string
-	authOrigin := fmt.Sprintf(
-		`api_key="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`,
-		apiKey, signature,
-	)
-
-	// 4. Base64 encode the authorization string
-	authorization := base64.StdEncoding.EncodeToString([]byte(authOrigin))
-
-	// 5. Build the final URL
-	v := url.Values{}
-	v.Add("host", host)
-	v.Add("date", date)
-	v.Add("authorization", authorization)
-
-	return requestURL + "?" + v.Encode(), nil
+	return DefaultSigner.SignURL(requestURL, method, apiKey, apiSecret, SchemeTypeAPIKey)
 }
 
 // AssembleAuthURLWithHostPath generates the final request URL with authentication parameters using provided scheme, host, and path.
+// It signs with DefaultSigner using SchemeTypeAPIKey.
 func AssembleAuthURLWithHostPath(scheme, host, path, method, apiKey, apiSecret string) (string, error) {
-
-	date := time.Now().UTC().Format(time.RFC1123)
-	signatureOrigin := fmt.Sprintf("host: %s\ndate: %s\n%s %s HTTP/1.1", host, date, method, path)
-	mac := hmac.New(sha256.New, []byte(apiSecret))
-	mac.Write([]byte(signatureOrigin))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-	authOrigin := fmt.Sprintf(`api_key="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`, apiKey, signature)
-	authorization := base64.StdEncoding.EncodeToString([]byte(authOrigin))
-
-	v := url.Values{}
-	v.Add("host", host)
-	v.Add("date", date)
-	v.Add("authorization", authorization)
-
-	return fmt.Sprintf("%s://%s%s?%s", scheme, host, path, v.Encode()), nil
+	return DefaultSigner.SignURL(fmt.Sprintf("%s://%s%s", scheme, host, path), method, apiKey, apiSecret, SchemeTypeAPIKey)
 }
 
 // BuildAuthURL 创建一个经过签名的讯飞服务 URL，支持多种鉴权模式。
 // 这个函数是可配置的，旨在替换所有独立的、重复的鉴权函数。
+// 签名使用 DefaultSigner；需要注入时钟或修正时钟偏差时请直接使用 Signer.SignURL。
 //
 // 参数:
 //
@@ -90,40 +47,5 @@ func AssembleAuthURLWithHostPath(scheme, host, path, method, apiKey, apiSecret s
 //	apiSecret:  服务的 API Secret
 //	schemeType: 鉴权模式，使用本包中定义的 SchemeType 常量
 func BuildAuthURL(baseURL, method, apiKey, apiSecret string, schemeType SchemeType) (string, error) {
-	// 1. 解析基础 URL 以获取 Host 和 Path (公共逻辑)
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return "", fmt.Errorf("解析URL失败: %w", err)
-	}
-
-	// 2. 准备签名所需的公共组件 (公共逻辑)
-	date := time.Now().UTC().Format(http.TimeFormat)
-	signatureOrigin := fmt.Sprintf("host: %s\ndate: %s\n%s %s HTTP/1.1", u.Host, date, method, u.Path)
-
-	// 3. 计算 HMAC-SHA256 签名 (公共逻辑)
-	mac := hmac.New(sha256.New, []byte(apiSecret))
-	mac.Write([]byte(signatureOrigin))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	// 4. 【核心区别】根据传入的 schemeType 选择不同的 authorization 格式
-	var authOrigin string
-	switch schemeType {
-	case SchemeTypeAPIKey:
-		authOrigin = fmt.Sprintf(`api_key="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`, apiKey, signature)
-	case SchemeTypeHMAC:
-		authOrigin = fmt.Sprintf(`hmac username="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`, apiKey, signature)
-	default:
-		return "", fmt.Errorf("不支持的鉴权模式: %d", schemeType)
-	}
-
-	// 5. 将 authorization 字符串进行 Base64 编码 (公共逻辑)
-	authorization := base64.StdEncoding.EncodeToString([]byte(authOrigin))
-
-	// 6. 拼接最终的 URL (公共逻辑)
-	v := url.Values{}
-	v.Add("host", u.Host)
-	v.Add("date", date)
-	v.Add("authorization", authorization)
-
-	return baseURL + "?" + v.Encode(), nil
+	return DefaultSigner.SignURL(baseURL, method, apiKey, apiSecret, schemeType)
 }
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultEnvPrefix 是环境变量凭证的默认前缀，对应 XFYUN_APP_ID、XFYUN_API_KEY 等变量。
+const DefaultEnvPrefix = "XFYUN"
+
+// ErrCredentialsNotFound 表示凭证提供者中没有找到可用的凭证。
+// ChainProvider 遇到该错误时会继续尝试下一个提供者。
+var ErrCredentialsNotFound = errors.New("xfyun credentials not found")
+
+// Credentials 是调用讯飞服务所需的一组凭证。
+// 不同服务使用的字段不同：大多数服务使用 AppID/APIKey/APISecret，
+// 语音转写（ist）等 LFASR 类服务使用 AppID/SecretKey。
+type Credentials struct {
+	AppID     string
+	APIKey    string
+	APISecret string
+	SecretKey string
+}
+
+// IsZero 报告凭证是否完全为空。
+func (c Credentials) IsZero() bool {
+	return c == Credentials{}
+}
+
+// CredentialProvider 在每次请求时提供凭证，使得密钥可以在不重建客户端的情况下轮换。
+// 实现必须是并发安全的。
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc 让普通函数实现 CredentialProvider 接口。
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials 实现 CredentialProvider 接口。
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticProvider 始终返回固定的凭证。
+type StaticProvider struct {
+	creds Credentials
+}
+
+// NewStaticProvider 使用固定的凭证创建提供者。
+func NewStaticProvider(creds Credentials) *StaticProvider {
+	return &StaticProvider{creds: creds}
+}
+
+// Credentials 实现 CredentialProvider 接口。
+func (p *StaticProvider) Credentials(ctx context.Context) (Credentials, error) {
+	if p.creds.IsZero() {
+		return Credentials{}, ErrCredentialsNotFound
+	}
+	return p.creds, nil
+}
+
+// EnvProvider 在每次调用时从环境变量读取凭证。
+// 变量名为 <prefix>_APP_ID、<prefix>_API_KEY、<prefix>_API_SECRET、<prefix>_SECRET_KEY。
+type EnvProvider struct {
+	prefix string
+}
+
+// NewEnvProvider 创建环境变量凭证提供者，prefix 为空时使用 DefaultEnvPrefix。
+// 例如 NewEnvProvider("XFYUN_OCR") 读取 XFYUN_OCR_APP_ID 等变量，便于不同产品使用不同的 AppID。
+func NewEnvProvider(prefix string) *EnvProvider {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	return &EnvProvider{prefix: strings.TrimSuffix(prefix, "_")}
+}
+
+// Credentials 实现 CredentialProvider 接口。
+func (p *EnvProvider) Credentials(ctx context.Context) (Credentials, error) {
+	creds := credentialsFromLookup(p.prefix, os.Getenv)
+	if creds.IsZero() {
+		return Credentials{}, fmt.Errorf("env %s_*: %w", p.prefix, ErrCredentialsNotFound)
+	}
+	return creds, nil
+}
+
+// FileProvider 从 .env 格式的文件中读取凭证。
+// 文件修改后会在下一次调用时重新加载，因此可以通过替换文件来轮换密钥。
+type FileProvider struct {
+	path   string
+	prefix string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   Credentials
+}
+
+// NewFileProvider 创建文件凭证提供者，prefix 的含义与 NewEnvProvider 相同。
+func NewFileProvider(path, prefix string) *FileProvider {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	return &FileProvider{path: path, prefix: strings.TrimSuffix(prefix, "_")}
+}
+
+// Credentials 实现 CredentialProvider 接口。
+func (p *FileProvider) Credentials(ctx context.Context) (Credentials, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Credentials{}, fmt.Errorf("credentials file %s: %w", p.path, ErrCredentialsNotFound)
+		}
+		return Credentials{}, fmt.Errorf("stat credentials file %s: %w", p.path, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size && !p.creds.IsZero() {
+		return p.creds, nil
+	}
+
+	values, err := godotenv.Read(p.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("read credentials file %s: %w", p.path, err)
+	}
+	creds := credentialsFromLookup(p.prefix, func(key string) string { return values[key] })
+	if creds.IsZero() {
+		return Credentials{}, fmt.Errorf("credentials file %s: %w", p.path, ErrCredentialsNotFound)
+	}
+
+	p.creds = creds
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	return creds, nil
+}
+
+// ChainProvider 依次尝试多个提供者，返回第一个找到的凭证。
+type ChainProvider struct {
+	providers []CredentialProvider
+}
+
+// NewChainProvider 创建链式凭证提供者，nil 提供者会被忽略。
+func NewChainProvider(providers ...CredentialProvider) *ChainProvider {
+	chain := &ChainProvider{}
+	for _, p := range providers {
+		if p != nil {
+			chain.providers = append(chain.providers, p)
+		}
+	}
+	return chain
+}
+
+// Credentials 实现 CredentialProvider 接口。
+// 只有返回 ErrCredentialsNotFound 的提供者会被跳过，其他错误会立即返回。
+func (p *ChainProvider) Credentials(ctx context.Context) (Credentials, error) {
+	for _, provider := range p.providers {
+		creds, err := provider.Credentials(ctx)
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, ErrCredentialsNotFound) {
+			return Credentials{}, err
+		}
+	}
+	return Credentials{}, ErrCredentialsNotFound
+}
+
+// DefaultProvider 返回命令行程序使用的默认凭证链：先读环境变量，再读当前目录下的 .env 文件。
+func DefaultProvider() CredentialProvider {
+	return NewChainProvider(
+		NewEnvProvider(DefaultEnvPrefix),
+		NewFileProvider(".env", DefaultEnvPrefix),
+	)
+}
+
+// ResolveCredentials 解析一次请求使用的凭证。
+// provider 为 nil 时直接返回 fallback，这是各服务客户端保持原有构造参数行为的方式。
+func ResolveCredentials(ctx context.Context, provider CredentialProvider, fallback Credentials) (Credentials, error) {
+	if provider == nil {
+		return fallback, nil
+	}
+	creds, err := provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("resolve credentials: %w", err)
+	}
+	return creds, nil
+}
+
+func credentialsFromLookup(prefix string, lookup func(string) string) Credentials {
+	return Credentials{
+		AppID:     strings.TrimSpace(lookup(prefix + "_APP_ID")),
+		APIKey:    strings.TrimSpace(lookup(prefix + "_API_KEY")),
+		APISecret: strings.TrimSpace(lookup(prefix + "_API_SECRET")),
+		SecretKey: strings.TrimSpace(lookup(prefix + "_SECRET_KEY")),
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+)
+
+// Signa 计算 SchemeTypeLFASR 的签名：Base64(HMAC-SHA1(secretKey, hex(MD5(appId + ts))))。
+func Signa(appID, ts, secretKey string) string {
+	sum := md5.Sum([]byte(appID + ts))
+	mac := hmac.New(sha1.New, []byte(secretKey))
+	mac.Write([]byte(fmt.Sprintf("%x", sum)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setSigna 以签名器的当前时间生成 ts，并设置 appId、ts、signa 查询参数。
+func (s *Signer) setSigna(v url.Values, appID, secretKey string) {
+	ts := s.Timestamp()
+	v.Set("appId", appID)
+	v.Set("ts", ts)
+	v.Set("signa", Signa(appID, ts, secretKey))
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted 是脱敏后的占位值。
+const Redacted = "REDACTED"
+
+// sensitiveParams 是需要在日志中脱敏的查询参数（小写）。
+var sensitiveParams = map[string]bool{
+	"authorization": true,
+	"signature":     true,
+	"signa":         true,
+}
+
+// RedactURL 返回将签名类查询参数替换为 Redacted 后的 URL，用于日志输出。
+// 无法解析的 URL 会整体替换为 Redacted，避免原样输出。
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Redacted
+	}
+	if u.RawQuery == "" {
+		return rawURL
+	}
+	q := u.Query()
+	changed := false
+	for key := range q {
+		if sensitiveParams[strings.ToLower(key)] {
+			q.Set(key, Redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// RedactHeader 返回 h 的副本，其中 Authorization 等敏感头被替换为 Redacted，用于日志输出。
+func RedactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for key := range out {
+		if sensitiveParams[strings.ToLower(key)] {
+			out[key] = []string{Redacted}
+		}
+	}
+	return out
+}
+
+// RedactError 对 *url.Error 中携带的 URL 脱敏后返回原错误，
+// 避免 http.Client.Do 的错误信息把签名带进日志和调用方的错误链。
+func RedactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "authorization",
+			in:   "https://example.com/v1?authorization=c2VjcmV0&date=now&host=example.com",
+			want: "https://example.com/v1?authorization=REDACTED&date=now&host=example.com",
+		},
+		{
+			name: "ist signa",
+			in:   "https://raasr.xfyun.cn/v2/api/upload?appId=app&signa=abc%3D&ts=1",
+			want: "https://raasr.xfyun.cn/v2/api/upload?appId=app&signa=REDACTED&ts=1",
+		},
+		{
+			name: "no query",
+			in:   "wss://tts-api.xfyun.cn/v2/tts",
+			want: "wss://tts-api.xfyun.cn/v2/tts",
+		},
+		{
+			name: "unparseable",
+			in:   "://bad url?authorization=x",
+			want: Redacted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactURL(tt.in); got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "secret")
+	h.Set("Date", "now")
+
+	got := RedactHeader(h)
+	if got.Get("Authorization") != Redacted {
+		t.Errorf("Expected Authorization to be redacted, got '%s'", got.Get("Authorization"))
+	}
+	if got.Get("Date") != "now" {
+		t.Errorf("Expected Date to be kept, got '%s'", got.Get("Date"))
+	}
+	if h.Get("Authorization") != "secret" {
+		t.Error("Expected original header to be left untouched")
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := &url.Error{Op: "Post", URL: "https://example.com/v1?authorization=c2VjcmV0", Err: errors.New("dial tcp: refused")}
+	// 必须在包装前脱敏：fmt.Errorf 会立即格式化错误信息
+	got := fmt.Errorf("send: %w", RedactError(err)).Error()
+	if strings.Contains(got, "c2VjcmV0") {
+		t.Errorf("Expected signature to be redacted, got '%s'", got)
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSigner 是包级鉴权函数（AssembleAuthURL、BuildAuthURL 等）使用的签名器，
+// 使用系统时钟且不做时钟偏差修正。
+var DefaultSigner = NewSigner()
+
+// Signer 负责讯飞 HMAC-SHA256 签名，签名所用的时间来自可注入的时钟，
+// 并可叠加一个服务端时间偏差，用于修正本机时钟漂移导致的 "date expired" 拒绝。
+// Signer 是并发安全的。
+type Signer struct {
+	now        func() time.Time
+	skew       atomic.Int64 // time.Duration，服务端时间 - 本地时间
+	syncServer bool
+}
+
+// SignerOption 用于配置 Signer。
+type SignerOption func(*Signer)
+
+// WithClock 设置签名使用的时钟，主要用于测试中生成确定的签名。
+func WithClock(now func() time.Time) SignerOption {
+	return func(s *Signer) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// WithSkew 设置固定的时钟偏差，签名时间 = 时钟时间 + skew。
+func WithSkew(skew time.Duration) SignerOption {
+	return func(s *Signer) {
+		s.skew.Store(int64(skew))
+	}
+}
+
+// WithServerTimeSync 开启服务端时间同步：ObserveResponse 会根据响应的 Date 头自动更新偏差。
+func WithServerTimeSync() SignerOption {
+	return func(s *Signer) {
+		s.syncServer = true
+	}
+}
+
+// NewSigner 创建签名器，默认使用 time.Now 且偏差为 0。
+func NewSigner(opts ...SignerOption) *Signer {
+	s := &Signer{now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Now 返回签名使用的当前时间（已叠加偏差，UTC）。
+func (s *Signer) Now() time.Time {
+	return s.now().Add(s.Skew()).UTC()
+}
+
+// Skew 返回当前的时钟偏差。
+func (s *Signer) Skew() time.Duration {
+	return time.Duration(s.skew.Load())
+}
+
+// SetSkew 设置时钟偏差。
+func (s *Signer) SetSkew(skew time.Duration) {
+	s.skew.Store(int64(skew))
+}
+
+// ObserveResponse 在开启 WithServerTimeSync 时，根据服务端响应的 Date 头更新时钟偏差。
+// 未开启同步、resp 为 nil 或 Date 头无法解析时不做任何事。
+func (s *Signer) ObserveResponse(resp *http.Response) {
+	if !s.syncServer || resp == nil {
+		return
+	}
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// Date 头只精确到秒，小于 1 秒的差异不值得修正
+	skew := serverTime.Sub(s.now()).Truncate(time.Second)
+	s.skew.Store(int64(skew))
+}
+
+// Date 返回签名使用的 date 字符串（RFC1123，GMT）。
+func (s *Signer) Date() string {
+	return s.Now().Format(http.TimeFormat)
+}
+
+// Signature 计算 "host date request-line" 的 HMAC-SHA256 签名（Base64）。
+func Signature(host, date, method, path, apiSecret string) string {
+	signatureOrigin := fmt.Sprintf("host: %s\ndate: %s\n%s %s HTTP/1.1", host, date, method, path)
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(signatureOrigin))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Authorization 按鉴权模式拼接 authorization 原文。返回值尚未做 Base64 编码。
+func Authorization(apiKey, signature string, schemeType SchemeType) (string, error) {
+	switch schemeType {
+	case SchemeTypeAPIKey:
+		return fmt.Sprintf(`api_key="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`, apiKey, signature), nil
+	case SchemeTypeHMAC:
+		return fmt.Sprintf(`hmac username="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`, apiKey, signature), nil
+	case SchemeTypeLFASR:
+		return "", fmt.Errorf("SchemeTypeLFASR 使用 signa 查询参数，不生成 authorization")
+	default:
+		return "", fmt.Errorf("不支持的鉴权模式: %d", schemeType)
+	}
+}
+
+// Timestamp 返回 SchemeTypeLFASR 签名使用的 ts（秒级 Unix 时间戳，已叠加偏差）。
+func (s *Signer) Timestamp() string {
+	return strconv.FormatInt(s.Now().Unix(), 10)
+}
+
+// SignURL 为 baseURL 追加 host、date、authorization 查询参数；
+// SchemeTypeLFASR 则追加 appId、ts、signa。
+func (s *Signer) SignURL(baseURL, method, apiKey, apiSecret string, schemeType SchemeType) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("解析URL失败: %w", err)
+	}
+
+	if schemeType == SchemeTypeLFASR {
+		v := url.Values{}
+		s.setSigna(v, apiKey, apiSecret)
+		return baseURL + "?" + v.Encode(), nil
+	}
+
+	date := s.Date()
+	authOrigin, err := Authorization(apiKey, Signature(u.Host, date, method, u.Path, apiSecret), schemeType)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Add("host", u.Host)
+	v.Add("date", date)
+	v.Add("authorization", base64.StdEncoding.EncodeToString([]byte(authOrigin)))
+
+	return baseURL + "?" + v.Encode(), nil
+}
+
+// Mode 决定签名信息放在请求的哪个位置。
+type Mode int
+
+const (
+	// ModeQuery 将 host、date、authorization 放在 URL 查询参数中（默认）。
+	ModeQuery Mode = iota
+
+	// ModeHeader 将签名放在 Authorization、Date、Host 请求头中，URL 中不包含签名，
+	// 避免签名泄露到代理访问日志中。
+	ModeHeader
+)
+
+// String 实现 fmt.Stringer。
+func (m Mode) String() string {
+	switch m {
+	case ModeQuery:
+		return "query"
+	case ModeHeader:
+		return "header"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// Sign 按 mode 为 req 原地签名，签名的 request-line 取自 req.Method 与 req.URL.Path。
+// ModeQuery 下已有的查询参数会被保留。SchemeTypeLFASR 只支持 ModeQuery。
+func (s *Signer) Sign(req *http.Request, mode Mode, apiKey, apiSecret string, schemeType SchemeType) error {
+	if schemeType == SchemeTypeLFASR {
+		if mode != ModeQuery {
+			return fmt.Errorf("SchemeTypeLFASR 不支持签名位置: %s", mode)
+		}
+		q := req.URL.Query()
+		s.setSigna(q, apiKey, apiSecret)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+
+	host := req.URL.Host
+	date := s.Date()
+	authOrigin, err := Authorization(apiKey, Signature(host, date, req.Method, req.URL.Path, apiSecret), schemeType)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case ModeQuery:
+		q := req.URL.Query()
+		q.Set("host", host)
+		q.Set("date", date)
+		q.Set("authorization", base64.StdEncoding.EncodeToString([]byte(authOrigin)))
+		req.URL.RawQuery = q.Encode()
+	case ModeHeader:
+		req.Host = host
+		req.Header.Set("Host", host)
+		req.Header.Set("Date", date)
+		req.Header.Set("Authorization", authOrigin)
+	default:
+		return fmt.Errorf("不支持的签名位置: %s", mode)
+	}
+	return nil
+}
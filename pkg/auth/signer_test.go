@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+var fixedTime = time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+func fixedClock() time.Time { return fixedTime }
+
+func TestSigner_SignURL_Golden(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock))
+
+	tests := []struct {
+		name   string
+		scheme SchemeType
+		want   string
+	}{
+		{
+			name:   "api_key",
+			scheme: SchemeTypeAPIKey,
+			want:   "https://cn-east-1.api.xf-yun.com/v1/ocr?authorization=YXBpX2tleT0ia2V5IiwgYWxnb3JpdGhtPSJobWFjLXNoYTI1NiIsIGhlYWRlcnM9Imhvc3QgZGF0ZSByZXF1ZXN0LWxpbmUiLCBzaWduYXR1cmU9IndSaWlyVHhqTUQ4NlFDNW42eTEvV0pLYmdBS2M1aVAwUFZvQjhpam9ZeUk9Ig%3D%3D&date=Mon%2C+02+Jan+2006+15%3A04%3A05+GMT&host=cn-east-1.api.xf-yun.com",
+		},
+		{
+			name:   "hmac",
+			scheme: SchemeTypeHMAC,
+			want:   "https://cn-east-1.api.xf-yun.com/v1/ocr?authorization=aG1hYyB1c2VybmFtZT0ia2V5IiwgYWxnb3JpdGhtPSJobWFjLXNoYTI1NiIsIGhlYWRlcnM9Imhvc3QgZGF0ZSByZXF1ZXN0LWxpbmUiLCBzaWduYXR1cmU9IndSaWlyVHhqTUQ4NlFDNW42eTEvV0pLYmdBS2M1aVAwUFZvQjhpam9ZeUk9Ig%3D%3D&date=Mon%2C+02+Jan+2006+15%3A04%3A05+GMT&host=cn-east-1.api.xf-yun.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.SignURL("https://cn-east-1.api.xf-yun.com/v1/ocr", "POST", "key", "secret", tt.scheme)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected signed URL\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSigner_SignURL_UnsupportedScheme(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock))
+	if _, err := signer.SignURL("https://example.com/v1", "POST", "key", "secret", SchemeType(99)); err == nil {
+		t.Fatal("Expected an error for unsupported scheme, got nil")
+	}
+}
+
+func TestSigner_Skew(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock), WithSkew(90*time.Second))
+	if want := "Mon, 02 Jan 2006 15:05:35 GMT"; signer.Date() != want {
+		t.Errorf("Expected date '%s', got '%s'", want, signer.Date())
+	}
+}
+
+func TestSigner_ObserveResponse(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Date", fixedTime.Add(-5*time.Minute).Format(http.TimeFormat))
+
+	// 未开启同步时不修改偏差
+	signer := NewSigner(WithClock(fixedClock))
+	signer.ObserveResponse(resp)
+	if signer.Skew() != 0 {
+		t.Errorf("Expected skew 0 without server time sync, got %s", signer.Skew())
+	}
+
+	signer = NewSigner(WithClock(fixedClock), WithServerTimeSync())
+	signer.ObserveResponse(resp)
+	if signer.Skew() != -5*time.Minute {
+		t.Errorf("Expected skew -5m, got %s", signer.Skew())
+	}
+	if want := "Mon, 02 Jan 2006 14:59:05 GMT"; signer.Date() != want {
+		t.Errorf("Expected date '%s', got '%s'", want, signer.Date())
+	}
+
+	// 无法解析的 Date 头不影响已有偏差
+	signer.ObserveResponse(&http.Response{Header: http.Header{"Date": []string{"garbage"}}})
+	if signer.Skew() != -5*time.Minute {
+		t.Errorf("Expected skew to stay -5m, got %s", signer.Skew())
+	}
+}
+
+func TestSigner_Sign_QueryModeMatchesSignURL(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock))
+	const base = "https://cn-east-1.api.xf-yun.com/v1/ocr"
+
+	want, err := signer.SignURL(base, "POST", "key", "secret", SchemeTypeAPIKey)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, base, nil)
+	if err := signer.Sign(req, ModeQuery, "key", "secret", SchemeTypeAPIKey); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := req.URL.String(); got != want {
+		t.Errorf("Expected signed URL\n%s\ngot\n%s", want, got)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("Expected no Authorization header in query mode")
+	}
+}
+
+func TestSigner_Sign_HeaderMode(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock))
+	req, _ := http.NewRequest(http.MethodPost, "https://cn-east-1.api.xf-yun.com/v1/ocr", nil)
+	if err := signer.Sign(req, ModeHeader, "key", "secret", SchemeTypeAPIKey); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if req.URL.RawQuery != "" {
+		t.Errorf("Expected no query parameters in header mode, got '%s'", req.URL.RawQuery)
+	}
+	if want := "Mon, 02 Jan 2006 15:04:05 GMT"; req.Header.Get("Date") != want {
+		t.Errorf("Expected Date header '%s', got '%s'", want, req.Header.Get("Date"))
+	}
+	if req.Host != "cn-east-1.api.xf-yun.com" {
+		t.Errorf("Expected Host 'cn-east-1.api.xf-yun.com', got '%s'", req.Host)
+	}
+	want := `api_key="key", algorithm="hmac-sha256", headers="host date request-line", signature="wRiirTxjMD86QC5n6y1/WJKbgAKc5iP0PVoB8ijoYyI="`
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Expected Authorization header\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestSigner_Sign_UnsupportedMode(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock))
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/v1", nil)
+	if err := signer.Sign(req, Mode(99), "key", "secret", SchemeTypeAPIKey); err == nil {
+		t.Fatal("Expected an error for unsupported mode, got nil")
+	}
+}
+
+func TestSigna_Golden(t *testing.T) {
+	if got, want := Signa("app", "1136214245", "secret-key"), "vVrv/tzjTQ7QsMBB46oOmO1OEdo="; got != want {
+		t.Errorf("Expected signa '%s', got '%s'", want, got)
+	}
+}
+
+func TestSigner_LFASR(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock))
+
+	got, err := signer.SignURL("https://raasr.xfyun.cn/v2/api/getResult", "POST", "app", "secret-key", SchemeTypeLFASR)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := "https://raasr.xfyun.cn/v2/api/getResult?appId=app&signa=vVrv%2FtzjTQ7QsMBB46oOmO1OEdo%3D&ts=1136214245"; got != want {
+		t.Errorf("Expected signed URL\n%s\ngot\n%s", want, got)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://raasr.xfyun.cn/v2/api/getResult?orderId=o1", nil)
+	if err := signer.Sign(req, ModeQuery, "app", "secret-key", SchemeTypeLFASR); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	q := req.URL.Query()
+	if q.Get("orderId") != "o1" || q.Get("ts") != "1136214245" || q.Get("signa") != "vVrv/tzjTQ7QsMBB46oOmO1OEdo=" {
+		t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+	}
+
+	if err := signer.Sign(req, ModeHeader, "app", "secret-key", SchemeTypeLFASR); err == nil {
+		t.Error("Expected an error for SchemeTypeLFASR in header mode, got nil")
+	}
+	if _, err := Authorization("app", "sig", SchemeTypeLFASR); err == nil {
+		t.Error("Expected an error for SchemeTypeLFASR authorization, got nil")
+	}
+}
+
+func TestSigner_Timestamp(t *testing.T) {
+	signer := NewSigner(WithClock(fixedClock), WithSkew(-5*time.Second))
+	if want := "1136214240"; signer.Timestamp() != want {
+		t.Errorf("Expected ts '%s', got '%s'", want, signer.Timestamp())
+	}
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxClockSkew 是讯飞网关允许的签名时间与服务端时间的最大偏差。
+const DefaultMaxClockSkew = 300 * time.Second
+
+// 签名校验失败的原因，可通过 errors.Is 判断。
+var (
+	ErrMissingAuthorization   = errors.New("auth: missing authorization")
+	ErrMalformedAuthorization = errors.New("auth: malformed authorization")
+	ErrUnknownAPIKey          = errors.New("auth: unknown api key")
+	ErrDateOutOfRange         = errors.New("auth: date out of range")
+	ErrSignatureMismatch      = errors.New("auth: signature mismatch")
+)
+
+// VerifyError 是 Verifier 返回的错误。StatusCode 与 Message 与讯飞 HMAC 网关对同类错误
+// 返回的 HTTP 状态码和 {"message": ...} 一致，Detail 是便于排查的具体原因（网关不会返回）。
+type VerifyError struct {
+	Err        error
+	StatusCode int
+	Message    string
+	Detail     string
+}
+
+func (e *VerifyError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Err, e.Detail)
+}
+
+func (e *VerifyError) Unwrap() error { return e.Err }
+
+// 网关对各类 HMAC 鉴权失败返回的状态码与 message。
+var gatewayResponses = map[error]struct {
+	status  int
+	message string
+}{
+	ErrMissingAuthorization:   {http.StatusUnauthorized, "Unauthorized"},
+	ErrMalformedAuthorization: {http.StatusUnauthorized, "HMAC signature cannot be verified"},
+	ErrUnknownAPIKey:          {http.StatusUnauthorized, "HMAC signature cannot be verified"},
+	ErrSignatureMismatch:      {http.StatusUnauthorized, "HMAC signature does not match"},
+	ErrDateOutOfRange:         {http.StatusForbidden, "HMAC signature cannot be verified, a valid date or x-date header is required for HMAC Authentication"},
+}
+
+func verifyError(err error, format string, args ...any) *VerifyError {
+	gw := gatewayResponses[err]
+	return &VerifyError{Err: err, StatusCode: gw.status, Message: gw.message, Detail: fmt.Sprintf(format, args...)}
+}
+
+// ParsedAuthorization 是 authorization 原文解析后的结果。
+type ParsedAuthorization struct {
+	SchemeType SchemeType
+	APIKey     string
+	Algorithm  string
+	Headers    string
+	Signature  string
+}
+
+// ParseAuthorization 解析 Authorization 函数生成的原文（未 Base64 编码），
+// 支持 api_key="..." 与 hmac username="..." 两种形式。
+func ParseAuthorization(s string) (ParsedAuthorization, error) {
+	var p ParsedAuthorization
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(s, "hmac "); ok {
+		p.SchemeType = SchemeTypeHMAC
+		s = rest
+	} else {
+		p.SchemeType = SchemeTypeAPIKey
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ParsedAuthorization{}, fmt.Errorf("%w: bad field %q", ErrMalformedAuthorization, part)
+		}
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "api_key":
+			if p.SchemeType != SchemeTypeAPIKey {
+				return ParsedAuthorization{}, fmt.Errorf("%w: api_key in hmac scheme", ErrMalformedAuthorization)
+			}
+			p.APIKey = value
+		case "username":
+			if p.SchemeType != SchemeTypeHMAC {
+				return ParsedAuthorization{}, fmt.Errorf("%w: username without hmac prefix", ErrMalformedAuthorization)
+			}
+			p.APIKey = value
+		case "algorithm":
+			p.Algorithm = value
+		case "headers":
+			p.Headers = value
+		case "signature":
+			p.Signature = value
+		}
+	}
+
+	switch {
+	case p.APIKey == "" || p.Signature == "":
+		return ParsedAuthorization{}, fmt.Errorf("%w: missing api key or signature", ErrMalformedAuthorization)
+	case p.Algorithm != "hmac-sha256":
+		return ParsedAuthorization{}, fmt.Errorf("%w: unsupported algorithm %q", ErrMalformedAuthorization, p.Algorithm)
+	case p.Headers != "host date request-line":
+		return ParsedAuthorization{}, fmt.Errorf("%w: unsupported headers %q", ErrMalformedAuthorization, p.Headers)
+	}
+	return p, nil
+}
+
+// Verifier 校验讯飞风格的签名，供测试用的本地网关使用。
+// HMAC 签名按 APIKey 查找 APISecret，ist 的 signa 按 AppID 查找 SecretKey。
+type Verifier struct {
+	byAPIKey map[string]Credentials
+	byAppID  map[string]Credentials
+	now      func() time.Time
+	maxSkew  time.Duration
+}
+
+// VerifierOption 用于配置 Verifier。
+type VerifierOption func(*Verifier)
+
+// WithVerifierClock 设置校验时使用的服务端时钟。
+func WithVerifierClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		if now != nil {
+			v.now = now
+		}
+	}
+}
+
+// WithMaxClockSkew 设置允许的最大时间偏差，默认为 DefaultMaxClockSkew。
+func WithMaxClockSkew(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		if d > 0 {
+			v.maxSkew = d
+		}
+	}
+}
+
+// NewVerifier 创建校验器，creds 为服务端认可的凭证列表。
+func NewVerifier(creds []Credentials, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		byAPIKey: make(map[string]Credentials),
+		byAppID:  make(map[string]Credentials),
+		now:      time.Now,
+		maxSkew:  DefaultMaxClockSkew,
+	}
+	for _, c := range creds {
+		if c.APIKey != "" {
+			v.byAPIKey[c.APIKey] = c
+		}
+		if c.AppID != "" {
+			v.byAppID[c.AppID] = c
+		}
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify 校验 req 的 HMAC 签名，签名可以在 URL 查询参数中（ModeQuery），
+// 也可以在 Authorization/Date 请求头中（ModeHeader）。成功时返回签名对应的凭证，
+// 失败时返回 *VerifyError。
+func (v *Verifier) Verify(req *http.Request) (Credentials, error) {
+	q := req.URL.Query()
+	host, date, authorization := q.Get("host"), q.Get("date"), q.Get("authorization")
+	if authorization != "" {
+		decoded, err := base64.StdEncoding.DecodeString(authorization)
+		if err != nil {
+			return Credentials{}, verifyError(ErrMalformedAuthorization, "authorization is not base64: %v", err)
+		}
+		authorization = string(decoded)
+	} else {
+		authorization = req.Header.Get("Authorization")
+		date = req.Header.Get("Date")
+		host = ""
+	}
+	if authorization == "" {
+		return Credentials{}, verifyError(ErrMissingAuthorization, "no authorization in query or header")
+	}
+	if host == "" {
+		host = req.Host
+	}
+
+	parsed, err := ParseAuthorization(authorization)
+	if err != nil {
+		gw := gatewayResponses[ErrMalformedAuthorization]
+		return Credentials{}, &VerifyError{Err: err, StatusCode: gw.status, Message: gw.message}
+	}
+	creds, ok := v.byAPIKey[parsed.APIKey]
+	if !ok {
+		return Credentials{}, verifyError(ErrUnknownAPIKey, "api key %q", parsed.APIKey)
+	}
+
+	signedAt, err := http.ParseTime(date)
+	if err != nil {
+		return Credentials{}, verifyError(ErrDateOutOfRange, "invalid date %q", date)
+	}
+	if err := v.checkWindow(signedAt); err != nil {
+		return Credentials{}, err
+	}
+
+	want := Signature(host, date, req.Method, req.URL.Path, creds.APISecret)
+	if !hmac.Equal([]byte(want), []byte(parsed.Signature)) {
+		return Credentials{}, verifyError(ErrSignatureMismatch, "signed %q", fmt.Sprintf("%s %s %s", host, req.Method, req.URL.Path))
+	}
+	return creds, nil
+}
+
+// VerifySigna 校验 ist（录音文件转写）的 appId、ts、signa 查询参数。
+// 成功时返回 appId 对应的凭证，失败时返回 *VerifyError（StatusCode 与 Message 仅供参考，
+// ist 网关以业务码而非 HTTP 状态码报告鉴权失败）。
+func (v *Verifier) VerifySigna(req *http.Request) (Credentials, error) {
+	q := req.URL.Query()
+	appID, ts, signa := q.Get("appId"), q.Get("ts"), q.Get("signa")
+	if appID == "" || ts == "" || signa == "" {
+		return Credentials{}, verifyError(ErrMissingAuthorization, "appId, ts and signa are required")
+	}
+	creds, ok := v.byAppID[appID]
+	if !ok || creds.SecretKey == "" {
+		return Credentials{}, verifyError(ErrUnknownAPIKey, "app id %q", appID)
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return Credentials{}, verifyError(ErrDateOutOfRange, "invalid ts %q", ts)
+	}
+	if err := v.checkWindow(time.Unix(sec, 0)); err != nil {
+		return Credentials{}, err
+	}
+
+	if !hmac.Equal([]byte(Signa(appID, ts, creds.SecretKey)), []byte(signa)) {
+		return Credentials{}, verifyError(ErrSignatureMismatch, "signa for app id %q", appID)
+	}
+	return creds, nil
+}
+
+func (v *Verifier) checkWindow(signedAt time.Time) error {
+	diff := v.now().Sub(signedAt)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > v.maxSkew {
+		return verifyError(ErrDateOutOfRange, "clock skew %s exceeds %s", diff.Truncate(time.Second), v.maxSkew)
+	}
+	return nil
+}
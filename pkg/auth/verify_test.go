@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var testCreds = Credentials{AppID: "app", APIKey: "key", APISecret: "secret", SecretKey: "secret-key"}
+
+func newTestVerifier() *Verifier {
+	return NewVerifier([]Credentials{testCreds}, WithVerifierClock(fixedClock))
+}
+
+func signedRequest(t *testing.T, signer *Signer, mode Mode, apiSecret string, scheme SchemeType) *http.Request {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "https://cn-east-1.api.xf-yun.com/v1/ocr", nil)
+	if err := signer.Sign(req, mode, "key", apiSecret, scheme); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 模拟服务端看到的请求：Host 取自请求行或 Host 头
+	req.Host = req.URL.Host
+	return req
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	v := newTestVerifier()
+
+	tests := []struct {
+		name       string
+		signer     *Signer
+		mode       Mode
+		secret     string
+		scheme     SchemeType
+		wantErr    error
+		wantStatus int
+	}{
+		{name: "query api_key", signer: NewSigner(WithClock(fixedClock)), mode: ModeQuery, secret: "secret", scheme: SchemeTypeAPIKey},
+		{name: "query hmac", signer: NewSigner(WithClock(fixedClock)), mode: ModeQuery, secret: "secret", scheme: SchemeTypeHMAC},
+		{name: "header", signer: NewSigner(WithClock(fixedClock)), mode: ModeHeader, secret: "secret", scheme: SchemeTypeAPIKey},
+		{name: "wrong secret", signer: NewSigner(WithClock(fixedClock)), mode: ModeQuery, secret: "wrong", scheme: SchemeTypeAPIKey, wantErr: ErrSignatureMismatch, wantStatus: http.StatusUnauthorized},
+		{name: "within skew", signer: NewSigner(WithClock(fixedClock), WithSkew(-4*time.Minute)), mode: ModeQuery, secret: "secret", scheme: SchemeTypeAPIKey},
+		{name: "date expired", signer: NewSigner(WithClock(fixedClock), WithSkew(-10*time.Minute)), mode: ModeQuery, secret: "secret", scheme: SchemeTypeAPIKey, wantErr: ErrDateOutOfRange, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := v.Verify(signedRequest(t, tt.signer, tt.mode, tt.secret, tt.scheme))
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if creds.AppID != "app" {
+					t.Errorf("Expected AppID 'app', got '%s'", creds.AppID)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			var verr *VerifyError
+			if !errors.As(err, &verr) || verr.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %+v", tt.wantStatus, verr)
+			}
+		})
+	}
+}
+
+func TestVerifier_Verify_TamperedPath(t *testing.T) {
+	req := signedRequest(t, NewSigner(WithClock(fixedClock)), ModeQuery, "secret", SchemeTypeAPIKey)
+	req.URL.Path = "/v1/other"
+	if _, err := newTestVerifier().Verify(req); !errors.Is(err, ErrSignatureMismatch) {
+		t.Fatalf("Expected ErrSignatureMismatch, got %v", err)
+	}
+}
+
+func TestVerifier_Verify_MissingAndUnknown(t *testing.T) {
+	v := newTestVerifier()
+
+	req, _ := http.NewRequest(http.MethodPost, "https://cn-east-1.api.xf-yun.com/v1/ocr", nil)
+	if _, err := v.Verify(req); !errors.Is(err, ErrMissingAuthorization) {
+		t.Errorf("Expected ErrMissingAuthorization, got %v", err)
+	}
+
+	signed, _ := NewSigner(WithClock(fixedClock)).SignURL("https://cn-east-1.api.xf-yun.com/v1/ocr", "POST", "other", "secret", SchemeTypeAPIKey)
+	req, _ = http.NewRequest(http.MethodPost, signed, nil)
+	if _, err := v.Verify(req); !errors.Is(err, ErrUnknownAPIKey) {
+		t.Errorf("Expected ErrUnknownAPIKey, got %v", err)
+	}
+}
+
+func TestParseAuthorization(t *testing.T) {
+	p, err := ParseAuthorization(`hmac username="key", algorithm="hmac-sha256", headers="host date request-line", signature="c2ln="`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.SchemeType != SchemeTypeHMAC || p.APIKey != "key" || p.Signature != "c2ln=" {
+		t.Errorf("Unexpected parse result: %+v", p)
+	}
+
+	if _, err := ParseAuthorization(`api_key="key", algorithm="hmac-sha1", headers="host date request-line", signature="x"`); !errors.Is(err, ErrMalformedAuthorization) {
+		t.Errorf("Expected ErrMalformedAuthorization for unsupported algorithm, got %v", err)
+	}
+}
+
+func TestVerifier_VerifySigna(t *testing.T) {
+	v := newTestVerifier()
+	ts := "1136214245" // fixedTime
+	signaReq := func(appID, ts, signa string) *http.Request {
+		q := url.Values{"appId": {appID}, "ts": {ts}, "signa": {signa}}
+		req, _ := http.NewRequest(http.MethodPost, "https://raasr.xfyun.cn/v2/api/upload?"+q.Encode(), nil)
+		return req
+	}
+
+	if _, err := v.VerifySigna(signaReq("app", ts, Signa("app", ts, "secret-key"))); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := v.VerifySigna(signaReq("app", ts, Signa("app", ts, "wrong"))); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Expected ErrSignatureMismatch, got %v", err)
+	}
+	stale := "1136210000"
+	if _, err := v.VerifySigna(signaReq("app", stale, Signa("app", stale, "secret-key"))); !errors.Is(err, ErrDateOutOfRange) {
+		t.Errorf("Expected ErrDateOutOfRange, got %v", err)
+	}
+	if _, err := v.VerifySigna(signaReq("", ts, "x")); !errors.Is(err, ErrMissingAuthorization) {
+		t.Errorf("Expected ErrMissingAuthorization, got %v", err)
+	}
+}
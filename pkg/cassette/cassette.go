@@ -0,0 +1,335 @@
+// Package cassette 录制并回放客户端与讯飞服务之间的交互（HTTP 请求/响应与 WebSocket 帧），
+// 用于在 CI 中不访问网络地运行 ocr、llmocr、translate、tts、ist 等流程。
+//
+// 录制时签名类查询参数与请求头会被脱敏，请求中的 base64 数据（图片、音频、文本）与二进制请求体
+// 只保留摘要；回放时按服务名与请求摘要（PayloadHash）匹配录制的交互：
+//
+//	cas, err := cassette.Open("testdata/ocr.json", cassette.ModeFromEnv())
+//	defer cas.Save()
+//	client := ocr.NewClient(appID, apiKey, apiSecret, ocr.WithMiddleware(cas.Middleware("ocr")))
+//
+// tts 通过 tts.WithDialer 接入 DialWebSocket。
+package cassette
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+)
+
+// Mode 决定 Cassette 录制还是回放。
+type Mode int
+
+const (
+	// ModeReplay 从文件回放录制的交互，不访问网络。
+	ModeReplay Mode = iota
+
+	// ModeRecord 转发真实请求并录制交互，Save 时写入文件。
+	ModeRecord
+)
+
+// EnvRecord 是 ModeFromEnv 读取的环境变量，非空时为录制模式。
+const EnvRecord = "XFYUN_CASSETTE_RECORD"
+
+// ModeFromEnv 在设置了环境变量 XFYUN_CASSETTE_RECORD 时返回 ModeRecord，否则返回 ModeReplay。
+func ModeFromEnv() Mode {
+	if os.Getenv(EnvRecord) != "" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Interaction 是一次录制的交互：HTTP 交互有 Response，WebSocket 会话有 Frames。
+type Interaction struct {
+	Service     string    `json:"service"`
+	PayloadHash string    `json:"payload_hash"`
+	Request     Request   `json:"request"`
+	Response    *Response `json:"response,omitempty"`
+	Frames      []Frame   `json:"frames,omitempty"`
+}
+
+// Request 是脱敏后的请求，仅供阅读，回放时只使用 PayloadHash 匹配。
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response 是录制的 HTTP 响应。
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Frame 是录制的 WebSocket 帧，发送的帧已脱敏。
+type Frame struct {
+	Send bool   `json:"send,omitempty"`
+	Type int    `json:"type"`
+	Data string `json:"data"`
+}
+
+type file struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Cassette 保存一个文件中的全部交互，并发安全。
+type Cassette struct {
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	cursor       map[string]int // 回放时每个 service+hash 已使用的交互数
+}
+
+// Open 以 mode 打开 path。回放模式下文件必须存在；录制模式下从空的交互列表开始，Save 时覆盖文件。
+func Open(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, cursor: make(map[string]int)}
+	if mode == ModeRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cassette: invalid %s: %w", path, err)
+	}
+	c.interactions = f.Interactions
+	return c, nil
+}
+
+// Mode 返回 Cassette 的模式。
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Interactions 返回已录制或已加载的交互。
+func (c *Cassette) Interactions() []*Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Interaction(nil), c.interactions...)
+}
+
+// Save 在录制模式下将交互写入文件，回放模式下不做任何事。
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(file{Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+func (c *Cassette) add(it *Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, it)
+}
+
+// match 按录制顺序返回 service 与 hash 都匹配的下一个交互；用完后重复最后一个，
+// 以便 ist 轮询等重复请求在录制的次数之外也能回放。
+func (c *Cassette) match(service, hash string, websocket bool) (*Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var candidates []*Interaction
+	for _, it := range c.interactions {
+		if it.Service == service && it.PayloadHash == hash && (it.Frames != nil) == websocket {
+			candidates = append(candidates, it)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("cassette: no interaction recorded in %s for service %s with payload hash %s", c.path, service, hash)
+	}
+	key := service + "/" + hash
+	i := c.cursor[key]
+	c.cursor[key]++
+	if i >= len(candidates) {
+		i = len(candidates) - 1
+	}
+	return candidates[i], nil
+}
+
+// 计算 PayloadHash 时忽略的查询参数（小写）：签名、时间戳与 AppID 在录制与回放时不同。
+var volatileParams = map[string]bool{
+	"authorization": true,
+	"date":          true,
+	"host":          true,
+	"signa":         true,
+	"ts":            true,
+	"appid":         true,
+}
+
+// 计算 PayloadHash 时忽略、保存时脱敏的 JSON 字段与请求头（小写）。
+var appIDKeys = map[string]bool{
+	"app_id": true,
+	"appid":  true,
+}
+
+// payloadKeys 是承载用户数据的 JSON 字段，其中的 base64 数据无论长短都会被替换为摘要。
+var payloadKeys = map[string]bool{
+	"image": true,
+	"audio": true,
+	"text":  true,
+}
+
+// PayloadHash 返回请求的摘要：方法、路径、去掉签名类参数后的查询参数，以及去掉 AppID 字段的请求体。
+// 因此用不同凭证、在不同时间发出的相同请求具有相同的摘要。
+func PayloadHash(method string, u *url.URL, body []byte) string {
+	query := url.Values{}
+	for key, values := range u.Query() {
+		if !volatileParams[strings.ToLower(key)] {
+			query[key] = values
+		}
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", method, u.Path, query.Encode())
+	h.Write(normalizeBody(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeBody 去掉 JSON 请求体中的 AppID 字段并重新序列化（json.Marshal 按键排序），非 JSON 请求体原样返回。
+func normalizeBody(body []byte) []byte {
+	var v any
+	if len(body) == 0 || json.Unmarshal(body, &v) != nil {
+		return body
+	}
+	normalized, err := json.Marshal(walk(v, func(key string, s string) (string, bool) {
+		return "", appIDKeys[strings.ToLower(key)]
+	}))
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// scrubBody 返回可写入文件的请求体：JSON 中的 AppID 替换为 auth.Redacted，base64 数据替换为摘要；
+// 非 JSON 的请求体（如 ist 上传的音频）只保留摘要。
+func scrubBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v any
+	if json.Unmarshal(body, &v) != nil {
+		return digest("binary", body)
+	}
+	scrubbed, err := json.Marshal(walk(v, func(key string, s string) (string, bool) {
+		if appIDKeys[strings.ToLower(key)] {
+			return auth.Redacted, true
+		}
+		if isBase64Payload(s) || (payloadKeys[key] && isBase64(s)) {
+			return digest("base64", []byte(s)), true
+		}
+		return "", false
+	}))
+	if err != nil {
+		return digest("binary", body)
+	}
+	return string(scrubbed)
+}
+
+// minBase64Len 是视为 base64 数据的最短字符串长度，避免误伤 "utf8" 这类恰好是合法 base64 的短字符串。
+const minBase64Len = 16
+
+func isBase64Payload(s string) bool {
+	return len(s) >= minBase64Len && isBase64(s)
+}
+
+func isBase64(s string) bool {
+	if s == "" {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
+}
+
+// scrubURL 返回可写入文件的 URL：签名与 AppID 脱敏，并去掉每次请求都不同的 date、host、ts 参数。
+func scrubURL(rawURL string) string {
+	u, err := url.Parse(auth.RedactURL(rawURL))
+	if err != nil {
+		return auth.Redacted
+	}
+	q := u.Query()
+	for key := range q {
+		switch k := strings.ToLower(key); {
+		case k == "date" || k == "host" || k == "ts":
+			q.Del(key)
+		case appIDKeys[k]:
+			q.Set(key, auth.Redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// scrubHeader 返回可写入文件的请求头：签名类请求头与 AppID 请求头替换为 auth.Redacted。
+func scrubHeader(h http.Header) http.Header {
+	out := auth.RedactHeader(h)
+	for key := range out {
+		if appIDKeys[strings.ToLower(key)] {
+			out[key] = []string{auth.Redacted}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func digest(kind string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("<%s sha256=%s len=%d>", kind, hex.EncodeToString(sum[:8]), len(data))
+}
+
+// walk 递归处理 JSON 值：对每个对象字段与数组中的字符串调用 fn，
+// fn 返回 (替换值, true) 时替换该值，替换值为空时删除该字段。
+func walk(v any, fn func(key, s string) (string, bool)) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			s, _ := item.(string)
+			if repl, ok := fn(key, s); ok {
+				if repl != "" {
+					out[key] = repl
+				}
+				continue
+			}
+			out[key] = walk(item, fn)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = walk(item, fn)
+		}
+		return out
+	case string:
+		// 数组中的字符串没有字段名
+		if repl, ok := fn("", v); ok && repl != "" {
+			return repl
+		}
+		return v
+	}
+	return v
+}
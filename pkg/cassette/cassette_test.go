@@ -0,0 +1,194 @@
+package cassette
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/fakegateway"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ist"
+	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/translate"
+	"github.com/fruitbars/goxfyunclient/pkg/service/tts"
+)
+
+// 以下测试回放 testdata 中的录制。设置 XFYUN_CASSETTE_RECORD=1 并通过 XFYUN_APP_ID 等环境变量
+// 提供真实凭证时，会访问讯飞服务并重新录制。
+
+func openCassette(t *testing.T, name string) *Cassette {
+	t.Helper()
+	cas, err := Open(filepath.Join("testdata", name+".json"), ModeFromEnv())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cas.Save(); err != nil {
+			t.Errorf("Failed to save cassette: %v", err)
+		}
+	})
+	return cas
+}
+
+func testCredentials(t *testing.T) auth.Credentials {
+	t.Helper()
+	if ModeFromEnv() == ModeReplay {
+		// 回放时凭证不参与匹配，使用与录制时不同的凭证
+		return auth.Credentials{AppID: "replay-app-id", APIKey: "replay-api-key", APISecret: "replay-api-secret", SecretKey: "replay-secret-key"}
+	}
+	creds, err := auth.NewEnvProvider(auth.DefaultEnvPrefix).Credentials(context.Background())
+	if err != nil {
+		t.Skipf("Recording requires credentials: %v", err)
+	}
+	return creds
+}
+
+func TestReplay_OCR(t *testing.T) {
+	cas := openCassette(t, "ocr")
+	creds := testCredentials(t)
+	client := ocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, ocr.WithMiddleware(cas.Middleware("ocr")))
+
+	resp, err := client.RecognizePath(context.Background(), "testdata/hello.png", "png", "ch_en")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	text, err := resp.RecognizedText()
+	if err != nil || text == "" || resp.Header.Sid == "" {
+		t.Errorf("Expected recognized text and sid, got text=%q sid=%q err=%v", text, resp.Header.Sid, err)
+	}
+}
+
+func TestReplay_LLMOCR(t *testing.T) {
+	cas := openCassette(t, "llmocr")
+	creds := testCredentials(t)
+	client := llmocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, llmocr.WithMiddleware(cas.Middleware("llmocr")))
+
+	text, err := client.RecognizeFile(context.Background(), "testdata/hello.png", "cassette-test")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if text == "" {
+		t.Error("Expected recognized text, got empty string")
+	}
+}
+
+func TestReplay_Translate(t *testing.T) {
+	cas := openCassette(t, "translate")
+	creds := testCredentials(t)
+	client := translate.NewClient(creds.AppID, creds.APIKey, creds.APISecret, translate.WithMiddleware(cas.Middleware("translate")))
+
+	dst, err := client.Translate(context.Background(), "你好，世界", "cn", "en")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(strings.ToLower(dst), "hello") {
+		t.Errorf("Expected translation containing 'hello', got '%s'", dst)
+	}
+
+	// 不同的输入没有录制
+	if _, err := client.Translate(context.Background(), "再见", "cn", "en"); ModeFromEnv() == ModeReplay && err == nil {
+		t.Error("Expected an error for an unrecorded request")
+	}
+}
+
+func TestReplay_IST(t *testing.T) {
+	cas := openCassette(t, "ist")
+	creds := testCredentials(t)
+	client := ist.NewClient(creds.AppID, creds.SecretKey, ist.WithMiddleware(cas.Middleware("ist")))
+
+	result, err := client.Process(context.Background(), "testdata/hello.wav")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Content.OrderInfo.Status != 4 || result.Content.OrderResult == "" {
+		t.Errorf("Expected a finished order with a result, got status=%d", result.Content.OrderInfo.Status)
+	}
+}
+
+func TestReplay_TTS(t *testing.T) {
+	cas := openCassette(t, "tts")
+	creds := testCredentials(t)
+	client := tts.NewTTSClient(creds.AppID, creds.APIKey, creds.APISecret,
+		tts.WithDialer(func(ctx context.Context, url string, h http.Header) (tts.Conn, *http.Response, error) {
+			return cas.DialWebSocket(ctx, "tts", url, h)
+		}))
+
+	audio, err := client.TextToSpeech(context.Background(), "你好", "x4_yezi", "raw")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(audio) == 0 {
+		t.Error("Expected audio data, got none")
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	g := fakegateway.New()
+	defer g.Close()
+	calls := 0
+	g.HandleHMAC("/v1/ocr", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fakegateway.JSON(map[string]any{
+			"header":  map[string]any{"code": 0, "message": "success", "sid": "sid-recorded"},
+			"payload": map[string]any{"ocr_output_text": map[string]any{"text": base64.StdEncoding.EncodeToString([]byte("recorded"))}},
+		}).ServeHTTP(w, r)
+	}))
+
+	path := filepath.Join(t.TempDir(), "ocr.json")
+	image := []byte(strings.Repeat("image-bytes", 16))
+
+	rec, err := Open(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	c := fakegateway.DefaultCredentials
+	client := ocr.NewClient(c.AppID, c.APIKey, c.APISecret, ocr.WithHost(g.URL+"/v1/ocr"), ocr.WithMiddleware(rec.Middleware("ocr")))
+	if _, err := client.RecognizeBytes(context.Background(), image, "jpg", "ch_en"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, secret := range []string{c.AppID, base64.StdEncoding.EncodeToString(image), "hmac-sha256"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected '%s' to be scrubbed from the cassette", secret)
+		}
+	}
+
+	// 回放时使用不同的凭证和默认地址，不访问网关
+	replay, err := Open(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	client = ocr.NewClient("other-app", "other-key", "other-secret", ocr.WithMiddleware(replay.Middleware("ocr")))
+	resp, err := client.RecognizeBytes(context.Background(), image, "jpg", "ch_en")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.Header.Sid != "sid-recorded" || calls != 1 {
+		t.Errorf("Expected the recorded response without calling the gateway, got sid=%s calls=%d", resp.Header.Sid, calls)
+	}
+
+	if _, err := client.RecognizeBytes(context.Background(), []byte("other image"), "jpg", "ch_en"); err == nil || !strings.Contains(err.Error(), "no interaction recorded") {
+		t.Errorf("Expected a no-match error for a different payload, got %v", err)
+	}
+}
+
+func TestPayloadHash_IgnoresVolatileFields(t *testing.T) {
+	a, _ := http.NewRequest(http.MethodPost, "https://h/v2/api/getResult?appId=a&ts=1&signa=x&orderId=o1", nil)
+	b, _ := http.NewRequest(http.MethodPost, "https://h/v2/api/getResult?appId=b&ts=2&signa=y&orderId=o1", nil)
+	if PayloadHash(a.Method, a.URL, []byte(`{"header":{"app_id":"a"},"x":1}`)) != PayloadHash(b.Method, b.URL, []byte(`{"x":1,"header":{"app_id":"b"}}`)) {
+		t.Error("Expected requests differing only in signature, timestamp and AppID to have the same hash")
+	}
+	c, _ := http.NewRequest(http.MethodPost, "https://h/v2/api/getResult?appId=a&ts=1&signa=x&orderId=o2", nil)
+	if PayloadHash(a.Method, a.URL, nil) == PayloadHash(c.Method, c.URL, nil) {
+		t.Error("Expected different order IDs to have different hashes")
+	}
+}
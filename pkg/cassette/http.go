@@ -0,0 +1,82 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+)
+
+// Middleware 返回录制或回放 service 的 HTTP 交互的中间件，通过客户端的 WithMiddleware 挂载。
+// 回放模式下不会调用下一层 RoundTripper，找不到匹配的交互时返回错误。
+func (c *Cassette) Middleware(service string) middleware.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := readBody(req)
+			if err != nil {
+				return nil, err
+			}
+			hash := PayloadHash(req.Method, req.URL, body)
+
+			if c.mode == ModeReplay {
+				it, err := c.match(service, hash, false)
+				if err != nil {
+					return nil, err
+				}
+				return &http.Response{
+					Status:        http.StatusText(it.Response.Status),
+					StatusCode:    it.Response.Status,
+					Proto:         "HTTP/1.1",
+					ProtoMajor:    1,
+					ProtoMinor:    1,
+					Header:        it.Response.Header.Clone(),
+					Body:          io.NopCloser(bytes.NewReader([]byte(it.Response.Body))),
+					ContentLength: int64(len(it.Response.Body)),
+					Request:       req,
+				}, nil
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			respBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+			header := resp.Header.Clone()
+			header.Del("Date") // 回放时过期的 Date 会干扰 auth.WithServerTimeSync
+			header.Del("Set-Cookie")
+			c.add(&Interaction{
+				Service:     service,
+				PayloadHash: hash,
+				Request: Request{
+					Method: req.Method,
+					URL:    scrubURL(req.URL.String()),
+					Header: scrubHeader(req.Header),
+					Body:   scrubBody(body),
+				},
+				Response: &Response{Status: resp.StatusCode, Header: header, Body: string(respBody)},
+			})
+			return resp, nil
+		})
+	}
+}
+
+// readBody 读取请求体并恢复 req.Body，以便录制模式下继续转发。
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,123 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrNoFrame 表示回放的 WebSocket 会话在发送第一帧之前就开始读取，无法确定要回放的交互。
+var ErrNoFrame = errors.New("cassette: read before the first frame was sent")
+
+// WebSocketConn 是录制或回放中的 WebSocket 连接，实现了 tts.Conn。
+// 回放时按第一帧发送的数据匹配录制的会话，之后依次返回录制的服务端帧，帧用完后返回正常关闭。
+type WebSocketConn struct {
+	c       *Cassette
+	service string
+	url     *url.URL
+	conn    *websocket.Conn // 录制模式下的真实连接
+
+	mu   sync.Mutex
+	it   *Interaction
+	next int // 回放时下一个待检查的帧
+}
+
+// DialWebSocket 为 service 建立 WebSocket 连接：录制模式下使用 websocket.DefaultDialer 连接 rawURL
+// 并录制收发的帧，回放模式下不访问网络。用法：
+//
+//	tts.WithDialer(func(ctx context.Context, url string, h http.Header) (tts.Conn, *http.Response, error) {
+//		return cas.DialWebSocket(ctx, "tts", url, h)
+//	})
+func (c *Cassette) DialWebSocket(ctx context.Context, service, rawURL string, header http.Header) (*WebSocketConn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	wc := &WebSocketConn{c: c, service: service, url: u}
+	if c.mode == ModeReplay {
+		return wc, nil, nil
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, rawURL, header)
+	if err != nil {
+		return nil, resp, err
+	}
+	wc.conn = conn
+	wc.it = &Interaction{
+		Service: service,
+		Request: Request{
+			Method: http.MethodGet,
+			URL:    scrubURL(rawURL),
+			Header: scrubHeader(header),
+		},
+		Frames: []Frame{},
+	}
+	c.add(wc.it)
+	return wc, resp, nil
+}
+
+// WriteMessage 发送一帧。录制模式下以第一帧计算 PayloadHash；回放模式下以第一帧匹配录制的会话。
+func (w *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	w.mu.Lock()
+	first := w.it == nil || w.it.PayloadHash == ""
+	if w.conn == nil {
+		if first {
+			it, err := w.c.match(w.service, PayloadHash(http.MethodGet, w.url, data), true)
+			if err != nil {
+				w.mu.Unlock()
+				return err
+			}
+			w.it = it
+		}
+		w.mu.Unlock()
+		return nil
+	}
+
+	w.c.mu.Lock()
+	if first {
+		w.it.PayloadHash = PayloadHash(http.MethodGet, w.url, data)
+	}
+	w.it.Frames = append(w.it.Frames, Frame{Send: true, Type: messageType, Data: scrubBody(data)})
+	w.c.mu.Unlock()
+	w.mu.Unlock()
+	return w.conn.WriteMessage(messageType, data)
+}
+
+// ReadMessage 读取一帧。回放时录制的服务端帧用完后返回 websocket.CloseNormalClosure。
+func (w *WebSocketConn) ReadMessage() (int, []byte, error) {
+	if w.conn != nil {
+		messageType, data, err := w.conn.ReadMessage()
+		if err == nil {
+			w.c.mu.Lock()
+			w.it.Frames = append(w.it.Frames, Frame{Type: messageType, Data: string(data)})
+			w.c.mu.Unlock()
+		}
+		return messageType, data, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.it == nil {
+		return 0, nil, ErrNoFrame
+	}
+	for w.next < len(w.it.Frames) {
+		f := w.it.Frames[w.next]
+		w.next++
+		if !f.Send {
+			return f.Type, []byte(f.Data), nil
+		}
+	}
+	return 0, nil, &websocket.CloseError{Code: websocket.CloseNormalClosure}
+}
+
+// Close 关闭连接，回放模式下不做任何事。
+func (w *WebSocketConn) Close() error {
+	if w.conn != nil {
+		return w.conn.Close()
+	}
+	return nil
+}
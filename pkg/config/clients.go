@@ -0,0 +1,147 @@
+package config
+
+import (
+	"github.com/fruitbars/goxfyunclient/pkg/service/detectlanguage"
+	"github.com/fruitbars/goxfyunclient/pkg/service/iocrld"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ist"
+	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/translate"
+	"github.com/fruitbars/goxfyunclient/pkg/service/tts"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyun"
+)
+
+// 以下构造函数按配置创建客户端，opts 在配置之后应用，可以覆盖任意配置项。
+// 服务没有可用的 AppID 时返回 auth.ErrCredentialsNotFound。
+
+// NewOCR 创建通用文字识别客户端。
+func (c *Config) NewOCR(opts ...ocr.Option) (*ocr.Client, error) {
+	const service = xfyun.ServiceOCR
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []ocr.Option{
+		ocr.WithLogger(c.Logger().With("service", service)),
+		ocr.WithHTTPClient(c.httpClient(service)),
+		ocr.WithRetry(c.RetryPolicy(service)),
+		ocr.WithLimiter(c.Limiter(service)),
+		ocr.WithAuthMode(c.AuthMode()),
+		ocr.WithEndpoints(c.Endpoints(service)...),
+		ocr.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	return ocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, append(base, opts...)...), nil
+}
+
+// NewLLMOCR 创建大模型文字识别客户端。
+func (c *Config) NewLLMOCR(opts ...llmocr.Option) (*llmocr.Client, error) {
+	const service = xfyun.ServiceLLMOCR
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []llmocr.Option{
+		llmocr.WithLogger(c.Logger().With("service", service)),
+		llmocr.WithHTTPClient(c.httpClient(service)),
+		llmocr.WithRetry(c.RetryPolicy(service)),
+		llmocr.WithLimiter(c.Limiter(service)),
+		llmocr.WithAuthMode(c.AuthMode()),
+		llmocr.WithEndpoints(c.Endpoints(service)...),
+		llmocr.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	return llmocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, append(base, opts...)...), nil
+}
+
+// NewIOCRLD 创建文档版面识别客户端。
+func (c *Config) NewIOCRLD(opts ...iocrld.Option) (*iocrld.Client, error) {
+	const service = xfyun.ServiceIOCRLD
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []iocrld.Option{
+		iocrld.WithLogger(c.Logger().With("service", service)),
+		iocrld.WithHTTPClient(c.httpClient(service)),
+		iocrld.WithRetry(c.RetryPolicy(service)),
+		iocrld.WithLimiter(c.Limiter(service)),
+		iocrld.WithAuthMode(c.AuthMode()),
+		iocrld.WithEndpoints(c.Endpoints(service)...),
+		iocrld.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	return iocrld.NewClient(creds.AppID, creds.APIKey, creds.APISecret, append(base, opts...)...), nil
+}
+
+// NewTranslate 创建机器翻译客户端。
+func (c *Config) NewTranslate(opts ...translate.Option) (*translate.Client, error) {
+	const service = xfyun.ServiceTranslate
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []translate.Option{
+		translate.WithLogger(c.Logger().With("service", service)),
+		translate.WithHTTPClient(c.httpClient(service)),
+		translate.WithRetry(c.RetryPolicy(service)),
+		translate.WithLimiter(c.Limiter(service)),
+		translate.WithAuthMode(c.AuthMode()),
+		translate.WithEndpoints(c.Endpoints(service)...),
+		translate.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	return translate.NewClient(creds.AppID, creds.APIKey, creds.APISecret, append(base, opts...)...), nil
+}
+
+// NewDetectLanguage 创建语种识别客户端。
+func (c *Config) NewDetectLanguage(opts ...detectlanguage.Option) (*detectlanguage.Client, error) {
+	const service = xfyun.ServiceDetectLanguage
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []detectlanguage.Option{
+		detectlanguage.WithLogger(c.Logger().With("service", service)),
+		detectlanguage.WithHTTPClient(c.httpClient(service)),
+		detectlanguage.WithRetry(c.RetryPolicy(service)),
+		detectlanguage.WithLimiter(c.Limiter(service)),
+		detectlanguage.WithAuthMode(c.AuthMode()),
+		detectlanguage.WithEndpoints(c.Endpoints(service)...),
+		detectlanguage.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	return detectlanguage.NewClient(creds.AppID, creds.APIKey, creds.APISecret, append(base, opts...)...), nil
+}
+
+// NewIST 创建录音文件转写客户端。timeout 只作用于查询请求，上传仍使用较长的默认超时；
+// ist 使用 signa 鉴权，不使用 retry 与 auth_mode。
+func (c *Config) NewIST(opts ...ist.Option) (*ist.Client, error) {
+	const service = xfyun.ServiceIST
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []ist.Option{
+		ist.WithLogger(c.Logger().With("service", service)),
+		ist.WithHTTPClients(nil, c.httpClient(service)),
+		ist.WithLimiter(c.Limiter(service)),
+		ist.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	if host := c.Host(service); host != "" {
+		base = append(base, ist.WithHost(host))
+	}
+	return ist.NewClient(creds.AppID, creds.SecretKey, append(base, opts...)...), nil
+}
+
+// NewTTS 创建语音合成客户端。tts 使用 WebSocket，不使用 timeout 与 retry。
+func (c *Config) NewTTS(opts ...tts.Option) (*tts.Client, error) {
+	const service = xfyun.ServiceTTS
+	creds, err := c.Credentials(service)
+	if err != nil {
+		return nil, err
+	}
+	base := []tts.Option{
+		tts.WithLogger(c.Logger().With("service", service)),
+		tts.WithLimiter(c.Limiter(service)),
+		tts.WithAuthMode(c.AuthMode()),
+		tts.WithEndpoints(c.Endpoints(service)...),
+		tts.WithMaxResponseSize(c.MaxResponseSize(service)),
+	}
+	return tts.NewTTSClient(creds.AppID, creds.APIKey, creds.APISecret, append(base, opts...)...), nil
+}
@@ -0,0 +1,500 @@
+// Package config 从 YAML 或 TOML 文件加载客户端配置：每个服务的凭证、地址、超时、重试与限流，
+// 支持 profile 与环境变量覆盖，并可据此创建任意服务的客户端。
+//
+//	cfg, err := config.Load("xfyun.yaml", config.WithProfile("staging"))
+//	client := cfg.NewOCR()
+//
+// 优先级从低到高：文件顶层、所选 profile、环境变量。服务自己的配置优先于顶层配置。
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyun"
+)
+
+// 环境变量，<SERVICE> 为大写的服务名，例如 XFYUN_OCR_APP_ID。
+const (
+	EnvConfig   = "XFYUN_CONFIG"    // 配置文件路径，供 Flags.Load 使用
+	EnvProfile  = "XFYUN_PROFILE"   // 使用的 profile
+	EnvBaseURL  = "XFYUN_BASE_URL"  // 覆盖 base_url
+	EnvRegion   = "XFYUN_REGION"    // 覆盖 region
+	EnvTimeout  = "XFYUN_TIMEOUT"   // 覆盖 timeout，例如 "10s"
+	EnvLogLevel = "XFYUN_LOG_LEVEL" // 覆盖 log_level
+)
+
+// Duration 是可以写成 "30s"、"500ms" 的时间长度。
+type Duration time.Duration
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，YAML 与 TOML 都通过它解析时间长度。
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText 实现 encoding.TextMarshaler。
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Credentials 是配置文件中的凭证，空字段继承上一级。
+type Credentials struct {
+	AppID     string `yaml:"app_id" toml:"app_id"`
+	APIKey    string `yaml:"api_key" toml:"api_key"`
+	APISecret string `yaml:"api_secret" toml:"api_secret"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+}
+
+// Retry 是重试配置，零值字段取 retry.DefaultPolicy() 的值。
+type Retry struct {
+	MaxAttempts    int      `yaml:"max_attempts" toml:"max_attempts"`
+	InitialBackoff Duration `yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff     Duration `yaml:"max_backoff" toml:"max_backoff"`
+	Multiplier     float64  `yaml:"multiplier" toml:"multiplier"`
+	Jitter         float64  `yaml:"jitter" toml:"jitter"`
+}
+
+// RateLimit 是限流配置，见 ratelimit.Config。使用同一 AppID 的客户端共享限流器。
+type RateLimit struct {
+	QPS         float64 `yaml:"qps" toml:"qps"`
+	Burst       int     `yaml:"burst" toml:"burst"`
+	MaxInFlight int     `yaml:"max_in_flight" toml:"max_in_flight"`
+}
+
+// Service 是单个服务的配置，空字段继承顶层配置。
+type Service struct {
+	Credentials Credentials `yaml:"credentials" toml:"credentials"`
+	Host        string      `yaml:"host" toml:"host"`
+	Timeout     Duration    `yaml:"timeout" toml:"timeout"`
+	Retry       *Retry      `yaml:"retry" toml:"retry"`
+	RateLimit   *RateLimit  `yaml:"rate_limit" toml:"rate_limit"`
+
+	MaxResponseSize int64 `yaml:"max_response_size" toml:"max_response_size"`
+}
+
+// Settings 是顶层或一个 profile 中的配置。
+type Settings struct {
+	Credentials Credentials `yaml:"credentials" toml:"credentials"`
+
+	// BaseURL 非空时所有服务都使用 xfyun.RegionAt(BaseURL) 中的地址，适用于私有化部署与本地模拟器。
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+
+	// Region 是 endpoint.Default 中登记的区域，各服务使用该区域的主备地址，优先级低于 BaseURL。
+	Region string `yaml:"region" toml:"region"`
+
+	Timeout   Duration   `yaml:"timeout" toml:"timeout"`
+	LogLevel  string     `yaml:"log_level" toml:"log_level"` // debug、info、warn、error
+	AuthMode  string     `yaml:"auth_mode" toml:"auth_mode"` // query（默认）或 header
+	Retry     *Retry     `yaml:"retry" toml:"retry"`
+	RateLimit *RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+
+	// MaxResponseSize 是允许的最大响应体字节数，为 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64 `yaml:"max_response_size" toml:"max_response_size"`
+
+	// Services 以服务名（xfyun.ServiceOCR 等）为键。
+	Services map[string]Service `yaml:"services" toml:"services"`
+}
+
+// file 是配置文件的结构：顶层配置加上若干 profile。
+type file struct {
+	Settings `yaml:",inline"`
+	Profiles map[string]Settings `yaml:"profiles" toml:"profiles"`
+}
+
+// Config 是合并了 profile 与环境变量之后的配置。
+type Config struct {
+	Settings
+
+	// Profile 是使用的 profile，为空表示只使用顶层配置。
+	Profile string
+
+	logger *slog.Logger
+}
+
+// Option is a function that configures Load.
+type Option func(*loader)
+
+type loader struct {
+	profile string
+	lookup  func(string) (string, bool)
+}
+
+// WithProfile selects the profile, overriding XFYUN_PROFILE.
+func WithProfile(name string) Option {
+	return func(l *loader) {
+		l.profile = name
+	}
+}
+
+// WithLookupEnv replaces os.LookupEnv as the source of environment overrides, e.g. in tests.
+func WithLookupEnv(lookup func(string) (string, bool)) Option {
+	return func(l *loader) {
+		if lookup != nil {
+			l.lookup = lookup
+		}
+	}
+}
+
+// Load 读取 path（.yaml、.yml 或 .toml），应用 profile 与环境变量。path 为空时只使用环境变量。
+func Load(path string, opts ...Option) (*Config, error) {
+	var data []byte
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	return parse(data, path, opts...)
+}
+
+// Parse 解析 format（"yaml" 或 "toml"）格式的配置，其余与 Load 相同。
+func Parse(data []byte, format string, opts ...Option) (*Config, error) {
+	return parse(data, "config."+format, opts...)
+}
+
+func parse(data []byte, name string, opts ...Option) (*Config, error) {
+	l := &loader{lookup: os.LookupEnv}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	var f file
+	if len(data) > 0 {
+		var err error
+		switch ext := strings.ToLower(filepath.Ext(name)); ext {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &f)
+		case ".toml":
+			_, err = toml.Decode(string(data), &f)
+		default:
+			err = fmt.Errorf("unsupported format %q", ext)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+
+	profile := l.profile
+	if profile == "" {
+		profile, _ = l.lookup(EnvProfile)
+	}
+	c := &Config{Settings: f.Settings, Profile: profile}
+	if profile != "" {
+		p, ok := f.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("config: %s: profile %q not found", name, profile)
+		}
+		c.Settings = merge(c.Settings, p)
+	}
+	if err := c.applyEnv(l.lookup); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// merge 返回以 over 中的非零字段覆盖 base 的结果，服务按字段逐个合并。
+func merge(base, over Settings) Settings {
+	out := base
+	out.Credentials = mergeCredentials(base.Credentials, over.Credentials)
+	out.BaseURL = firstNonEmpty(over.BaseURL, base.BaseURL)
+	out.Region = firstNonEmpty(over.Region, base.Region)
+	out.Timeout = firstNonZero(over.Timeout, base.Timeout)
+	out.LogLevel = firstNonEmpty(over.LogLevel, base.LogLevel)
+	out.AuthMode = firstNonEmpty(over.AuthMode, base.AuthMode)
+	out.Retry = firstNonNil(over.Retry, base.Retry)
+	out.RateLimit = firstNonNil(over.RateLimit, base.RateLimit)
+	out.MaxResponseSize = firstNonZero(over.MaxResponseSize, base.MaxResponseSize)
+
+	out.Services = make(map[string]Service, len(base.Services)+len(over.Services))
+	for name, svc := range base.Services {
+		out.Services[name] = svc
+	}
+	for name, o := range over.Services {
+		b := out.Services[name]
+		out.Services[name] = Service{
+			Credentials: mergeCredentials(b.Credentials, o.Credentials),
+			Host:        firstNonEmpty(o.Host, b.Host),
+			Timeout:     firstNonZero(o.Timeout, b.Timeout),
+			Retry:       firstNonNil(o.Retry, b.Retry),
+			RateLimit:   firstNonNil(o.RateLimit, b.RateLimit),
+
+			MaxResponseSize: firstNonZero(o.MaxResponseSize, b.MaxResponseSize),
+		}
+	}
+	return out
+}
+
+func mergeCredentials(base, over Credentials) Credentials {
+	return Credentials{
+		AppID:     firstNonEmpty(over.AppID, base.AppID),
+		APIKey:    firstNonEmpty(over.APIKey, base.APIKey),
+		APISecret: firstNonEmpty(over.APISecret, base.APISecret),
+		SecretKey: firstNonEmpty(over.SecretKey, base.SecretKey),
+	}
+}
+
+// applyEnv 应用环境变量：XFYUN_* 覆盖顶层配置，XFYUN_<SERVICE>_* 覆盖服务配置。
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	get := func(key string) string {
+		v, _ := lookup(key)
+		return strings.TrimSpace(v)
+	}
+	envCredentials := func(prefix string) Credentials {
+		return Credentials{
+			AppID:     get(prefix + "_APP_ID"),
+			APIKey:    get(prefix + "_API_KEY"),
+			APISecret: get(prefix + "_API_SECRET"),
+			SecretKey: get(prefix + "_SECRET_KEY"),
+		}
+	}
+
+	c.Settings.Credentials = mergeCredentials(c.Settings.Credentials, envCredentials(auth.DefaultEnvPrefix))
+	c.BaseURL = firstNonEmpty(get(EnvBaseURL), c.BaseURL)
+	c.Region = firstNonEmpty(get(EnvRegion), c.Region)
+	c.LogLevel = firstNonEmpty(get(EnvLogLevel), c.LogLevel)
+	if v := get(EnvTimeout); v != "" {
+		if err := c.Settings.Timeout.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("config: %s: %w", EnvTimeout, err)
+		}
+	}
+
+	if c.Services == nil {
+		c.Services = make(map[string]Service)
+	}
+	for _, name := range services {
+		prefix := auth.DefaultEnvPrefix + "_" + strings.ToUpper(name)
+		svc := c.Services[name]
+		svc.Credentials = mergeCredentials(svc.Credentials, envCredentials(prefix))
+		svc.Host = firstNonEmpty(get(prefix+"_HOST"), svc.Host)
+		if svc != (Service{}) {
+			c.Services[name] = svc
+		}
+	}
+	return nil
+}
+
+// services 是可以配置的服务。
+var services = []string{
+	xfyun.ServiceOCR, xfyun.ServiceLLMOCR, xfyun.ServiceIOCRLD, xfyun.ServiceTranslate,
+	xfyun.ServiceDetectLanguage, xfyun.ServiceIST, xfyun.ServiceTTS,
+}
+
+func (c *Config) validate() error {
+	for name := range c.Services {
+		if !slices.Contains(services, name) {
+			return fmt.Errorf("unknown service %q", name)
+		}
+	}
+	if c.Region != "" && !slices.Contains(endpoint.Default.Regions(), c.Region) {
+		return fmt.Errorf("unknown region %q", c.Region)
+	}
+	if _, err := parseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if _, err := parseAuthMode(c.Settings.AuthMode); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Credentials 返回 service 使用的凭证：服务自己的凭证字段优先，其次是顶层凭证。
+// 没有 AppID 时返回 auth.ErrCredentialsNotFound。
+func (c *Config) Credentials(service string) (auth.Credentials, error) {
+	creds := mergeCredentials(c.Settings.Credentials, c.Services[service].Credentials)
+	if creds.AppID == "" {
+		return auth.Credentials{}, fmt.Errorf("config: %s: %w", service, auth.ErrCredentialsNotFound)
+	}
+	return auth.Credentials(creds), nil
+}
+
+// Endpoints 返回 service 的地址，第一个为主地址：服务的 host 优先，其次是 base_url，
+// 再次是 region 在 endpoint.Default 中登记的主备地址；都未配置时返回 nil（使用客户端默认地址）。
+func (c *Config) Endpoints(service string) []string {
+	if host := c.Services[service].Host; host != "" {
+		return []string{host}
+	}
+	if c.BaseURL != "" {
+		return []string{xfyun.RegionAt(c.BaseURL)[service]}
+	}
+	if c.Region != "" {
+		urls, _ := endpoint.Default.Resolve(service, c.Region) // 区域未登记该服务时使用默认地址
+		return urls
+	}
+	return nil
+}
+
+// Host 返回 service 的主地址，见 Endpoints。
+func (c *Config) Host(service string) string {
+	if urls := c.Endpoints(service); len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
+
+// Timeout 返回 service 的 HTTP 超时，都未配置时返回 0（使用客户端默认值）。
+func (c *Config) Timeout(service string) time.Duration {
+	return time.Duration(firstNonZero(c.Services[service].Timeout, c.Settings.Timeout))
+}
+
+// MaxResponseSize 返回 service 允许的最大响应体字节数，都未配置时返回 0（使用 stream.DefaultMaxResponseSize）。
+func (c *Config) MaxResponseSize(service string) int64 {
+	return firstNonZero(c.Services[service].MaxResponseSize, c.Settings.MaxResponseSize)
+}
+
+// RetryPolicy 返回 service 的重试策略，都未配置时返回 nil（不重试）。
+func (c *Config) RetryPolicy(service string) *retry.Policy {
+	r := firstNonNil(c.Services[service].Retry, c.Settings.Retry)
+	if r == nil {
+		return nil
+	}
+	p := retry.DefaultPolicy()
+	if r.MaxAttempts != 0 {
+		p.MaxAttempts = r.MaxAttempts
+	}
+	if r.InitialBackoff != 0 {
+		p.InitialBackoff = time.Duration(r.InitialBackoff)
+	}
+	if r.MaxBackoff != 0 {
+		p.MaxBackoff = time.Duration(r.MaxBackoff)
+	}
+	if r.Multiplier != 0 {
+		p.Multiplier = r.Multiplier
+	}
+	if r.Jitter != 0 {
+		p.Jitter = r.Jitter
+	}
+	return p
+}
+
+// Limiter 返回 service 的限流器，都未配置时返回 nil（不限制）。
+// 顶层 rate_limit 由使用同一 AppID 的所有服务共享（见 ratelimit.ForAppID）；
+// 服务自己的 rate_limit 只由该服务使用同一 AppID 的客户端共享。
+func (c *Config) Limiter(service string) *ratelimit.Limiter {
+	creds, _ := c.Credentials(service)
+	key := creds.AppID
+	r := c.Settings.RateLimit
+	if own := c.Services[service].RateLimit; own != nil {
+		key, r = creds.AppID+"/"+service, own
+	}
+	if r == nil {
+		return nil
+	}
+	return ratelimit.ForAppID(key, ratelimit.Config{QPS: r.QPS, Burst: r.Burst, MaxInFlight: r.MaxInFlight})
+}
+
+// AuthMode 返回签名放置的位置。
+func (c *Config) AuthMode() auth.Mode {
+	mode, _ := parseAuthMode(c.Settings.AuthMode)
+	return mode
+}
+
+// Logger 返回按 log_level 输出 JSON 到标准错误的日志。
+func (c *Config) Logger() *slog.Logger {
+	if c.logger == nil {
+		level, _ := parseLevel(c.LogLevel)
+		c.logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+	return c.logger
+}
+
+// SetLogger 替换 Logger 返回的日志与各客户端使用的日志。
+func (c *Config) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	c.logger = logger
+}
+
+// httpClient 返回 service 的 HTTP 客户端，未配置超时时返回 nil（使用客户端默认值）。
+func (c *Config) httpClient(service string) *http.Client {
+	if timeout := c.Timeout(service); timeout > 0 {
+		return &http.Client{Timeout: timeout}
+	}
+	return nil
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log_level %q", s)
+}
+
+func parseAuthMode(s string) (auth.Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "query":
+		return auth.ModeQuery, nil
+	case "header":
+		return auth.ModeHeader, nil
+	}
+	return auth.ModeQuery, fmt.Errorf("invalid auth_mode %q", s)
+}
+
+// dotenvLookup 先查环境变量，再查当前目录下的 .env 文件，与 auth.DefaultProvider 的顺序一致。
+func dotenvLookup(path string) func(string) (string, bool) {
+	values, _ := godotenv.Read(path) // 文件不存在或无法解析时只使用环境变量
+	return func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstNonZero[T Duration | int64](values ...T) T {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func firstNonNil[T any](values ...*T) *T {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
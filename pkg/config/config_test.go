@@ -0,0 +1,181 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/emulator"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyun"
+)
+
+// noEnv 使测试不受进程环境变量影响。
+func noEnv(string) (string, bool) { return "", false }
+
+func envMap(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func TestLoad_Formats(t *testing.T) {
+	for _, path := range []string{"testdata/xfyun.yaml", "testdata/xfyun.toml"} {
+		t.Run(path, func(t *testing.T) {
+			cfg, err := Load(path, WithLookupEnv(noEnv))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			creds, err := cfg.Credentials(xfyun.ServiceOCR)
+			if err != nil || creds.AppID != "shared-app" || creds.APISecret != "shared-secret" {
+				t.Errorf("Unexpected ocr credentials: %+v, %v", creds, err)
+			}
+			// 服务的凭证字段优先，未配置的字段继承顶层
+			creds, _ = cfg.Credentials(xfyun.ServiceTTS)
+			if creds.AppID != "tts-app" || creds.APIKey != "tts-key" || creds.APISecret != "shared-secret" {
+				t.Errorf("Unexpected tts credentials: %+v", creds)
+			}
+			if got := cfg.Timeout(xfyun.ServiceOCR); got != 10*time.Second {
+				t.Errorf("Expected timeout 10s, got %v", got)
+			}
+			if p := cfg.RetryPolicy(xfyun.ServiceOCR); p == nil || p.MaxAttempts != 2 || p.InitialBackoff == 0 {
+				t.Errorf("Expected the retry policy to overlay the defaults, got %+v", p)
+			}
+			if cfg.Limiter(xfyun.ServiceOCR) != nil || cfg.Limiter(xfyun.ServiceTTS) == nil {
+				t.Error("Expected only tts to have a limiter")
+			}
+			if cfg.Host(xfyun.ServiceOCR) != "" {
+				t.Errorf("Expected the default host, got %q", cfg.Host(xfyun.ServiceOCR))
+			}
+		})
+	}
+}
+
+func TestLoad_Profile(t *testing.T) {
+	cfg, err := Load("testdata/xfyun.yaml", WithProfile("staging"), WithLookupEnv(noEnv))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.LogLevel != "debug" || cfg.Timeout(xfyun.ServiceOCR) != 3*time.Second || cfg.Timeout(xfyun.ServiceTranslate) != 10*time.Second {
+		t.Errorf("Unexpected profile merge: level=%q ocr=%v translate=%v",
+			cfg.LogLevel, cfg.Timeout(xfyun.ServiceOCR), cfg.Timeout(xfyun.ServiceTranslate))
+	}
+	if cfg.MaxResponseSize(xfyun.ServiceOCR) != 1<<20 || cfg.MaxResponseSize(xfyun.ServiceLLMOCR) != 128<<20 {
+		t.Errorf("Unexpected max response size: ocr=%d llmocr=%d", cfg.MaxResponseSize(xfyun.ServiceOCR), cfg.MaxResponseSize(xfyun.ServiceLLMOCR))
+	}
+	if want := xfyun.RegionAt("http://127.0.0.1:8080")[xfyun.ServiceOCR]; cfg.Host(xfyun.ServiceOCR) != want {
+		t.Errorf("Expected host %s, got %s", want, cfg.Host(xfyun.ServiceOCR))
+	}
+	// 文件顶层的服务配置保留
+	if creds, _ := cfg.Credentials(xfyun.ServiceTTS); creds.AppID != "tts-app" {
+		t.Errorf("Expected the top-level tts credentials to be kept, got %+v", creds)
+	}
+
+	// profile 也可以来自环境变量
+	cfg, err = Load("testdata/xfyun.toml", WithLookupEnv(envMap(map[string]string{EnvProfile: "staging"})))
+	if err != nil || cfg.Profile != "staging" {
+		t.Fatalf("Expected profile staging, got %v", err)
+	}
+	if cfg.MaxResponseSize(xfyun.ServiceLLMOCR) != 128<<20 {
+		t.Errorf("Expected the llmocr max response size from TOML, got %d", cfg.MaxResponseSize(xfyun.ServiceLLMOCR))
+	}
+
+	if _, err := Load("testdata/xfyun.yaml", WithProfile("missing"), WithLookupEnv(noEnv)); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	env := envMap(map[string]string{
+		"XFYUN_APP_ID":           "env-app",
+		"XFYUN_TRANSLATE_APP_ID": "translate-app",
+		"XFYUN_IST_HOST":         "http://ist.local",
+		EnvTimeout:               "5s",
+		EnvLogLevel:              "error",
+	})
+	cfg, err := Load("testdata/xfyun.yaml", WithLookupEnv(env))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if creds, _ := cfg.Credentials(xfyun.ServiceOCR); creds.AppID != "env-app" || creds.APIKey != "shared-key" {
+		t.Errorf("Unexpected ocr credentials: %+v", creds)
+	}
+	if creds, _ := cfg.Credentials(xfyun.ServiceTranslate); creds.AppID != "translate-app" {
+		t.Errorf("Unexpected translate credentials: %+v", creds)
+	}
+	if cfg.Host(xfyun.ServiceIST) != "http://ist.local" || cfg.Timeout(xfyun.ServiceOCR) != 5*time.Second || cfg.LogLevel != "error" {
+		t.Errorf("Unexpected overrides: host=%q timeout=%v level=%q", cfg.Host(xfyun.ServiceIST), cfg.Timeout(xfyun.ServiceOCR), cfg.LogLevel)
+	}
+
+	// 没有配置文件时只使用环境变量
+	cfg, err = Load("", WithLookupEnv(env))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if creds, _ := cfg.Credentials(xfyun.ServiceOCR); creds.AppID != "env-app" {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown service":   "services:\n  asr: {}\n",
+		"invalid level":     "log_level: verbose\n",
+		"invalid auth mode": "auth_mode: cookie\n",
+		"invalid timeout":   "timeout: soon\n",
+	} {
+		if _, err := Parse([]byte(data), "yaml", WithLookupEnv(noEnv)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Parse([]byte("a = 1"), "ini", WithLookupEnv(noEnv)); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+
+	cfg, _ := Parse(nil, "yaml", WithLookupEnv(noEnv))
+	if _, err := cfg.NewOCR(); !errors.Is(err, auth.ErrCredentialsNotFound) {
+		t.Errorf("Expected ErrCredentialsNotFound, got %v", err)
+	}
+}
+
+func TestConfig_NewClients(t *testing.T) {
+	creds := auth.Credentials{AppID: "shared-app", APIKey: "shared-key", APISecret: "shared-secret"}
+	srv := httptest.NewServer(emulator.New(emulator.WithCredentials(creds)))
+	defer srv.Close()
+
+	cfg, err := Load("testdata/xfyun.yaml", WithLookupEnv(envMap(map[string]string{EnvBaseURL: srv.URL})))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg.SetLogger(nil)
+
+	ocrClient, err := cfg.NewOCR()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ocrClient.HTTPClient.Timeout != 10*time.Second || ocrClient.Retry == nil {
+		t.Errorf("Expected the configured timeout and retry, got %v %+v", ocrClient.HTTPClient.Timeout, ocrClient.Retry)
+	}
+	if _, err := ocrClient.RecognizeBytes(context.Background(), []byte("image"), "jpg", "ch_en"); err != nil {
+		t.Errorf("OCR: expected no error, got %v", err)
+	}
+
+	translateClient, err := cfg.NewTranslate()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := translateClient.Translate(context.Background(), "你好", "cn", "en"); err != nil {
+		t.Errorf("Translate: expected no error, got %v", err)
+	}
+
+	ttsClient, err := cfg.NewTTS()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ttsClient.Limiter == nil || ttsClient.AppID != "tts-app" {
+		t.Errorf("Expected the tts limiter and credentials, got limiter=%v app_id=%q", ttsClient.Limiter, ttsClient.AppID)
+	}
+}
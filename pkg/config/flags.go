@@ -0,0 +1,60 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Flags 是命令行工具共用的配置参数，见 RegisterFlags。
+type Flags struct {
+	Path    string // -config，为空时使用 XFYUN_CONFIG
+	Profile string // -profile，为空时使用 XFYUN_PROFILE
+	Level   string // -level，非空时覆盖 log_level
+}
+
+// RegisterFlags 在 fs 上注册 -config、-profile 与 -level，fs 为 nil 时使用 flag.CommandLine。
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	f := &Flags{}
+	fs.StringVar(&f.Path, "config", "", "配置文件路径（YAML 或 TOML），默认读取 "+EnvConfig)
+	fs.StringVar(&f.Profile, "profile", "", "使用的 profile，默认读取 "+EnvProfile)
+	fs.StringVar(&f.Level, "level", "", "日志级别 (debug, info, warn, error)，覆盖配置文件")
+	return f
+}
+
+// Load 按参数加载配置。环境变量先查进程环境，再查当前目录下的 .env 文件。
+func (f *Flags) Load() (*Config, error) {
+	lookup := dotenvLookup(".env")
+	path := f.Path
+	if path == "" {
+		path, _ = lookup(EnvConfig)
+	}
+	opts := []Option{WithLookupEnv(lookup)}
+	if f.Profile != "" {
+		opts = append(opts, WithProfile(f.Profile))
+	}
+	cfg, err := Load(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if f.Level != "" {
+		if _, err := parseLevel(f.Level); err != nil {
+			return nil, err
+		}
+		cfg.LogLevel = f.Level
+	}
+	return cfg, nil
+}
+
+// MustLoad 与 Load 相同，但出错时打印错误并退出，供示例程序使用。
+func (f *Flags) MustLoad() *Config {
+	cfg, err := f.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return cfg
+}
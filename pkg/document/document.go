@@ -0,0 +1,156 @@
+// Package document 将多页文档拆分为逐页图片，并以有界并发对每一页调用识别服务。
+//
+// 支持的输入：
+//   - 多页 TIFF：逐个 IFD 解码，每页编码为 PNG；
+//   - PDF：提取每页内嵌的扫描图片（JPEG 原样使用，Flate、CCITT G4 等解码后编码为 PNG），
+//     不渲染矢量内容，没有图片的页返回 ErrNoImage；
+//   - 其他格式（JPEG、PNG 等）视为单页图片，原样使用。
+//
+// 拆分后通过 Recognize 并发识别，结果按页码排列，每页带有各自的错误：
+//
+//	pages, err := document.SplitFile("scan.pdf")
+//	results := document.Recognize(ctx, pages, 4, func(ctx context.Context, p document.Page) (*ocr.OcrResponse, error) {
+//		return client.RecognizeBytes(ctx, p.Data, p.Format, "ch_en")
+//	})
+//
+// ocr.Client.RecognizeDocument 与 llmocr.Client.RecognizeDocument 封装了上述流程。
+package document
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrency 是 Recognize 的 concurrency 小于等于 0 时同时识别的页数。
+const DefaultConcurrency = 4
+
+var (
+	// ErrNoImage 表示 PDF 页中没有可提取的图片，例如由文字和矢量图形构成的页。
+	ErrNoImage = errors.New("document: page has no embedded image")
+
+	// ErrUnsupported 表示页中的图片使用了不支持的编码，例如 JBIG2 或 JPEG 2000。
+	ErrUnsupported = errors.New("document: unsupported image encoding")
+)
+
+// Page 是文档中的一页。
+type Page struct {
+	Number int    // 页码，从 1 开始
+	Data   []byte // 图片内容
+	Format string // 图片格式，可直接作为识别服务的图片编码，例如 "jpg"、"png"
+
+	// Err 非空表示该页无法转换为图片，Data 为空；Recognize 不会为它调用识别函数。
+	Err error
+}
+
+// Result 是一页的识别结果。
+type Result[T any] struct {
+	Page  int // 页码，从 1 开始
+	Value T
+	Err   error
+}
+
+// SplitFile 读取 path 并按页拆分，见 Split。
+func SplitFile(path string) ([]Page, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Split(data)
+}
+
+// Split 按页拆分 data。只有整个文档无法解析时返回错误，单页的失败记录在 Page.Err 中。
+func Split(data []byte) ([]Page, error) {
+	switch {
+	case isPDF(data):
+		return splitPDF(data)
+	case isTIFF(data):
+		return splitTIFF(data)
+	case len(data) == 0:
+		return nil, errors.New("document: empty input")
+	}
+	return []Page{{Number: 1, Data: data, Format: imageFormat(data)}}, nil
+}
+
+// Recognize 以最多 concurrency 个并发对每一页调用 fn，返回按页码排列的结果。
+// 拆分失败的页（Page.Err 非空）直接以该错误作为结果；ctx 取消后尚未开始的页返回 ctx.Err()。
+func Recognize[T any](ctx context.Context, pages []Page, concurrency int, fn func(ctx context.Context, page Page) (T, error)) []Result[T] {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	results := make([]Result[T], len(pages))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, page := range pages {
+		results[i].Page = page.Number
+		if page.Err != nil {
+			results[i].Err = page.Err
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			v, err := fn(ctx, page)
+			if err != nil {
+				err = fmt.Errorf("page %d: %w", page.Number, err)
+			}
+			results[i].Value, results[i].Err = v, err
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Errors 合并 results 中的错误，全部成功时返回 nil。
+func Errors[T any](results []Result[T]) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// imageFormat 根据内容推断图片格式，无法识别时返回 "jpg"。
+func imageFormat(data []byte) string {
+	contentType := http.DetectContentType(data)
+	switch {
+	case strings.Contains(contentType, "png"):
+		return "png"
+	case strings.Contains(contentType, "bmp"):
+		return "bmp"
+	case strings.Contains(contentType, "gif"):
+		return "gif"
+	case strings.Contains(contentType, "webp"):
+		return "webp"
+	}
+	return "jpg"
+}
+
+func isPDF(data []byte) bool {
+	// 规范允许 %PDF- 之前有少量垃圾数据
+	return bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-"))
+}
+
+func isTIFF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))
+}
@@ -0,0 +1,333 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// buildTIFF 生成未压缩的多页 8 位灰度 TIFF，第 i 页的像素值均为 fills[i]。
+func buildTIFF(width, height int, fills ...byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+	for i, fill := range fills {
+		ifd := buf.Len()
+		pixels := ifd + 2 + 9*12 + 4
+		entries := [][3]uint32{
+			{256, 4, uint32(width)},          // ImageWidth
+			{257, 4, uint32(height)},         // ImageLength
+			{258, 3, 8},                      // BitsPerSample
+			{259, 3, 1},                      // Compression: none
+			{262, 3, 1},                      // PhotometricInterpretation: BlackIsZero
+			{273, 4, uint32(pixels)},         // StripOffsets
+			{277, 3, 1},                      // SamplesPerPixel
+			{278, 4, uint32(height)},         // RowsPerStrip
+			{279, 4, uint32(width * height)}, // StripByteCounts
+		}
+		binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&buf, binary.LittleEndian, uint16(e[0]))
+			binary.Write(&buf, binary.LittleEndian, uint16(e[1]))
+			binary.Write(&buf, binary.LittleEndian, uint32(1))
+			binary.Write(&buf, binary.LittleEndian, e[2])
+		}
+		next := uint32(0)
+		if i < len(fills)-1 {
+			next = uint32(pixels + width*height)
+		}
+		binary.Write(&buf, binary.LittleEndian, next)
+		buf.Write(bytes.Repeat([]byte{fill}, width*height))
+	}
+	return buf.Bytes()
+}
+
+// buildPDF 按顺序写出编号从 1 开始的对象，对象 1 为 /Catalog；空字符串表示该编号的对象位于对象流中。
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\n%%%%EOF\n", len(objects)+1)
+	return buf.Bytes()
+}
+
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func decodePNG(t *testing.T, p Page) image.Image {
+	t.Helper()
+	if p.Err != nil || p.Format != "png" {
+		t.Fatalf("Expected a PNG page, got format %q, error %v", p.Format, p.Err)
+	}
+	img, err := png.Decode(bytes.NewReader(p.Data))
+	if err != nil {
+		t.Fatalf("Expected a valid PNG, got %v", err)
+	}
+	return img
+}
+
+func TestSplit_TIFF(t *testing.T) {
+	pages, err := Split(buildTIFF(4, 3, 0x10, 0x80, 0xF0))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 pages, got %d", len(pages))
+	}
+	for i, fill := range []uint8{0x10, 0x80, 0xF0} {
+		img := decodePNG(t, pages[i])
+		if pages[i].Number != i+1 || img.Bounds() != image.Rect(0, 0, 4, 3) {
+			t.Errorf("Unexpected page %d: %v", pages[i].Number, img.Bounds())
+		}
+		if got := color.GrayModel.Convert(img.At(3, 2)).(color.Gray).Y; got != fill {
+			t.Errorf("Expected page %d to be filled with %#x, got %#x", i+1, fill, got)
+		}
+	}
+
+	// IFD 链成环时整个文档无效
+	looped := buildTIFF(1, 1, 0, 0)
+	binary.LittleEndian.PutUint32(looped[8+2+9*12:], 8)
+	if _, err := Split(looped); err == nil {
+		t.Error("Expected an error for a looped IFD chain")
+	}
+}
+
+func TestSplit_PDF(t *testing.T) {
+	var jpg bytes.Buffer
+	src := image.NewGray(image.Rect(0, 0, 16, 8))
+	jpeg.Encode(&jpg, src, nil)
+
+	// 2x2 RGB，PNG Up 预测：第二行与第一行相同
+	rgb := deflate([]byte{0, 255, 0, 0, 0, 0, 255, 2, 0, 0, 0, 0, 0, 0})
+	gopher, err := os.ReadFile("testdata/gopher.ccitt_group4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第 2 页的页字典（对象 4）位于对象流中
+	page4 := "<< /Type /Page /Parent 2 0 R /Resources << /Font << >> >> >>"
+	header := "4 0 "
+
+	data := buildPDF(
+		`<< /Type /Catalog /Pages 2 0 R >>`,
+		`<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R 6 0 R 12 0 R] /Count 5 /Resources << /XObject << /Im1 8 0 R >> >> >>`,
+		`<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Thumb 13 0 R /Im0 7 0 R >> >> >>`,
+		``,
+		`<< /Type /Page /Parent 2 0 R >>`,
+		`<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Fm0 9 0 R /Im2 10 0 R >> >> >>`,
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 16 /Height 8 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length 14 0 R >>\nstream\n%s\nendstream", jpg.Bytes()),
+		stream(`/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /DecodeParms << /Predictor 12 /Colors 3 /Columns 2 >>`, rgb),
+		stream(`/Type /XObject /Subtype /Form /BBox [0 0 1 1] /Resources << /XObject << /Im2 10 0 R >> >>`, []byte("/Im2 Do")),
+		stream(`/Type /XObject /Subtype /Image /Width 153 /Height 55 /ImageMask true /Filter /CCITTFaxDecode /DecodeParms << /K -1 /Columns 153 >>`, gopher),
+		stream(fmt.Sprintf(`/Type /ObjStm /N 1 /First %d /Filter /FlateDecode`, len(header)), deflate([]byte(header+page4))),
+		`<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im3 15 0 R >> >> >>`,
+		stream(`/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 8`, []byte{1, 2, 3, 4}),
+		fmt.Sprintf("%d", jpg.Len()),
+		stream(`/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 1 /Filter /JBIG2Decode`, []byte{0}),
+	)
+
+	pages, err := Split(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(pages) != 5 {
+		t.Fatalf("Expected 5 pages, got %d", len(pages))
+	}
+
+	// 第 1 页：较大的 JPEG 优先于缩略图，原样返回
+	if p := pages[0]; p.Format != "jpg" || !bytes.Equal(p.Data, jpg.Bytes()) {
+		t.Errorf("Expected the JPEG to be passed through, got format %q, %d bytes, error %v", p.Format, len(p.Data), p.Err)
+	}
+
+	// 第 2 页：只有字体资源，没有图片
+	if !errors.Is(pages[1].Err, ErrNoImage) {
+		t.Errorf("Expected ErrNoImage, got %v", pages[1].Err)
+	}
+
+	// 第 3 页：资源继承自 /Pages 节点，预测编码已还原
+	img := decodePNG(t, pages[2])
+	for _, pt := range []image.Point{{0, 0}, {0, 1}} {
+		if got := color.RGBAModel.Convert(img.At(pt.X, pt.Y)); got != (color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("Expected red at %v, got %v", pt, got)
+		}
+	}
+	if got := color.RGBAModel.Convert(img.At(1, 1)); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected blue at (1, 1), got %v", got)
+	}
+
+	// 第 4 页：CCITT G4 图片，表单 XObject 不参与选择
+	img = decodePNG(t, pages[3])
+	if img.Bounds() != image.Rect(0, 0, 153, 55) {
+		t.Errorf("Unexpected CCITT image bounds %v", img.Bounds())
+	}
+	if got := color.GrayModel.Convert(img.At(0, 0)).(color.Gray).Y; got != 0xFF {
+		t.Errorf("Expected a white corner, got %#x", got)
+	}
+	var black int
+	for y := 0; y < 55; y++ {
+		for x := 0; x < 153; x++ {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y == 0 {
+				black++
+			}
+		}
+	}
+	if black == 0 || black > 153*55/2 {
+		t.Errorf("Expected a mostly white bitmap with some black pixels, got %d black", black)
+	}
+
+	// 第 5 页：JBIG2 不支持
+	if !errors.Is(pages[4].Err, ErrUnsupported) || !strings.Contains(pages[4].Err.Error(), "page 5") {
+		t.Errorf("Expected ErrUnsupported for page 5, got %v", pages[4].Err)
+	}
+}
+
+// malformedPDFs 中的 /Length 与对象流偏移超出 int 的范围，转换前必须先与文件大小比较。
+var malformedPDFs = map[string][]byte{
+	"huge length": buildPDF(
+		`<< /Type /Catalog /Pages 2 0 R >>`,
+		`<< /Type /Pages /Kids [3 0 R] /Count 1 >>`,
+		"<< /Type /Page /Parent 2 0 R >>\nstream\nxx\nendstream",
+		"<< /Length 1e300 >>\nstream\nxx\nendstream",
+	),
+	"huge indirect length": buildPDF(
+		`<< /Type /Catalog /Pages 2 0 R >>`,
+		`<< /Type /Pages /Kids [3 0 R] /Count 1 >>`,
+		`<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im0 4 0 R >> >> >>`,
+		"<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /Length 5 0 R >>\nstream\nxx\nendstream",
+		`99999999999999999999`,
+	),
+	"negative object offset": buildPDF(
+		`<< /Type /Catalog /Pages 2 0 R >>`,
+		`<< /Type /Pages /Kids [4 0 R] /Count 1 >>`,
+		stream(`/Type /ObjStm /N 1 /First 7`, []byte("4 -1e30 << /Type /Page >>")),
+	),
+}
+
+func TestSplit_MalformedPDF(t *testing.T) {
+	for name, data := range malformedPDFs {
+		t.Run(name, func(t *testing.T) {
+			// 无法使用 /Length 时回退到查找 endstream，能否得到图片不重要，只要不 panic
+			Split(data)
+		})
+	}
+}
+
+// FuzzSplit 检查任意输入都不会使 Split panic。
+func FuzzSplit(f *testing.F) {
+	for _, data := range malformedPDFs {
+		f.Add(data)
+	}
+	f.Add(buildTIFF(4, 2, 0x10, 0x80))
+	f.Add(buildPDF(
+		`<< /Type /Catalog /Pages 2 0 R >>`,
+		`<< /Type /Pages /Kids [3 0 R] /Count 1 >>`,
+		`<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im0 4 0 R >> >> >>`,
+		stream(`/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode`, deflate([]byte{1, 2, 3, 4})),
+	))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Split(data)
+	})
+}
+
+func TestSplit_Image(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	pages, err := Split(buf.Bytes())
+	if err != nil || len(pages) != 1 || pages[0].Format != "png" || pages[0].Number != 1 {
+		t.Fatalf("Expected a single PNG page, got %+v, %v", pages, err)
+	}
+	if _, err := Split(nil); err == nil {
+		t.Error("Expected an error for empty input")
+	}
+	if _, err := Split([]byte("%PDF-1.4\nnot really")); err == nil {
+		t.Error("Expected an error for a PDF without objects")
+	}
+}
+
+func TestRecognize(t *testing.T) {
+	pages := make([]Page, 8)
+	for i := range pages {
+		pages[i] = Page{Number: i + 1, Data: []byte{byte(i)}}
+	}
+	pages[2].Err = ErrNoImage
+
+	var running, peak atomic.Int32
+	results := Recognize(context.Background(), pages, 3, func(ctx context.Context, p Page) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		// 让后面的页先完成，检查结果仍按页码排列
+		time.Sleep(time.Duration(len(pages)-p.Number) * time.Millisecond)
+		if p.Number == 5 {
+			return 0, errors.New("boom")
+		}
+		return int(p.Data[0]) * 10, nil
+	})
+
+	if peak.Load() > 3 {
+		t.Errorf("Expected at most 3 concurrent calls, got %d", peak.Load())
+	}
+	for i, r := range results {
+		if r.Page != i+1 {
+			t.Fatalf("Expected results in page order, got page %d at %d", r.Page, i)
+		}
+		switch i {
+		case 2:
+			if !errors.Is(r.Err, ErrNoImage) {
+				t.Errorf("Expected the split error for page 3, got %v", r.Err)
+			}
+		case 4:
+			if r.Err == nil || r.Err.Error() != "page 5: boom" {
+				t.Errorf("Expected a page error, got %v", r.Err)
+			}
+		default:
+			if r.Err != nil || r.Value != i*10 {
+				t.Errorf("Unexpected result for page %d: %+v", i+1, r)
+			}
+		}
+	}
+	if err := Errors(results); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected joined errors, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = Recognize(ctx, pages[:2], 1, func(ctx context.Context, p Page) (int, error) {
+		return 0, nil
+	})
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", r.Err)
+		}
+	}
+}
@@ -0,0 +1,522 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PDF 的解析只覆盖提取扫描图片所需的最小部分：对象与对象流、页树、页资源中的图片 XObject。
+// 对象表通过扫描 "N G obj" 建立而不依赖 xref 表，因此也能处理 xref 损坏的文件；
+// 增量更新中后出现的定义覆盖先出现的定义。文件内容不可信，所有长度与偏移在使用前都与文件大小比较。
+
+type pdfName string
+
+type pdfRef struct {
+	Num, Gen int
+}
+
+type pdfDict map[pdfName]any
+
+type pdfStream struct {
+	Dict pdfDict
+	Data []byte // 未解码的内容
+
+	// start 为内容在文件中的偏移，/Length 为间接对象时在建立对象表之后据此修正 Data
+	start int
+}
+
+// pdfString 是未解码的字符串内容，提取图片时用不到字符串的值。
+type pdfString []byte
+
+type pdfKeyword string
+
+type pdfDoc struct {
+	data    []byte
+	objects map[int]any
+	root    pdfDict
+}
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// splitPDF 提取每一页中最大的一张图片。
+func splitPDF(data []byte) ([]Page, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	pageDicts := doc.pages()
+	if len(pageDicts) == 0 {
+		return nil, errors.New("document: PDF has no pages")
+	}
+	pages := make([]Page, len(pageDicts))
+	for i, p := range pageDicts {
+		pages[i].Number = i + 1
+		img := doc.pageImage(p)
+		if img == nil {
+			pages[i].Err = ErrNoImage
+			continue
+		}
+		pages[i].Data, pages[i].Format, pages[i].Err = doc.extractImage(img)
+		if pages[i].Err != nil {
+			pages[i].Err = fmt.Errorf("page %d: %w", i+1, pages[i].Err)
+		}
+	}
+	return pages, nil
+}
+
+func parsePDF(data []byte) (*pdfDoc, error) {
+	doc := &pdfDoc{data: data, objects: make(map[int]any)}
+	var trailers []pdfDict
+	end := 0
+	for _, m := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		// 跳过已解析对象（例如流的二进制内容）内部的匹配
+		if m[0] < end || (m[0] > 0 && isRegular(data[m[0]-1])) {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		lex := &pdfLexer{data: data, pos: m[1]}
+		obj, err := lex.object()
+		if err != nil {
+			continue
+		}
+		doc.objects[num] = obj
+		end = lex.pos
+		if s, ok := obj.(*pdfStream); ok && s.Dict["Type"] == pdfName("XRef") {
+			trailers = append(trailers, s.Dict)
+		}
+	}
+	if len(doc.objects) == 0 {
+		return nil, errors.New("document: no objects found in PDF")
+	}
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		i += j + len("trailer")
+		lex := &pdfLexer{data: data, pos: i}
+		if d, err := lex.object(); err == nil {
+			if d, ok := d.(pdfDict); ok {
+				trailers = append(trailers, d)
+			}
+		}
+	}
+
+	doc.fixLengths()
+	doc.expandObjectStreams()
+
+	for i := len(trailers) - 1; i >= 0 && doc.root == nil; i-- {
+		doc.root, _ = doc.resolve(trailers[i]["Root"]).(pdfDict)
+	}
+	if doc.root == nil {
+		for _, num := range doc.objectNumbers() {
+			if d, ok := doc.objects[num].(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+				doc.root = d
+				break
+			}
+		}
+	}
+	return doc, nil
+}
+
+// fixLengths 使用间接对象形式的 /Length 修正流的内容范围。
+func (d *pdfDoc) fixLengths() {
+	for _, obj := range d.objects {
+		s, ok := obj.(*pdfStream)
+		if !ok {
+			continue
+		}
+		if _, ok := s.Dict["Length"].(pdfRef); !ok {
+			continue
+		}
+		if end, ok := streamEnd(d.data, s.start, d.resolve(s.Dict["Length"])); ok {
+			s.Data = d.data[s.start:end]
+		}
+	}
+}
+
+// expandObjectStreams 将对象流（/Type /ObjStm）中的对象加入对象表，不覆盖文件中直接定义的对象。
+// PDF 1.5 之后的生成器通常把页字典放在对象流中。
+func (d *pdfDoc) expandObjectStreams() {
+	for _, num := range d.objectNumbers() {
+		s, ok := d.objects[num].(*pdfStream)
+		if !ok || s.Dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, filter, _, err := d.decodeStream(s)
+		if err != nil || filter != "" {
+			continue
+		}
+		n, first := intOf(s.Dict["N"], 0), intOf(s.Dict["First"], 0)
+		if first <= 0 || first > len(data) {
+			continue
+		}
+		header := &pdfLexer{data: data[:first]}
+		for i := 0; i < n; i++ {
+			objNum, err1 := header.object()
+			offset, err2 := header.object()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			num, ok1 := objNum.(float64)
+			off, ok2 := offset.(float64)
+			if !ok1 || !ok2 || off < 0 || off >= float64(len(data)-first) {
+				break
+			}
+			if _, exists := d.objects[int(num)]; exists {
+				continue
+			}
+			lex := &pdfLexer{data: data, pos: first + int(off)}
+			if obj, err := lex.object(); err == nil {
+				d.objects[int(num)] = obj
+			}
+		}
+	}
+}
+
+func (d *pdfDoc) objectNumbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// resolve 返回 v 引用的对象，v 不是引用时原样返回。
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.Num]
+	}
+	return nil
+}
+
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch v := d.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.Dict
+	}
+	return nil
+}
+
+// pages 按页树顺序返回每一页的字典，/Resources 已从上级节点继承。
+// 页树缺失或损坏时按对象编号返回所有 /Type /Page 对象。
+func (d *pdfDoc) pages() []pdfDict {
+	var pages []pdfDict
+	visited := make(map[pdfRef]bool)
+	var walk func(node pdfDict, resources any, depth int)
+	walk = func(node pdfDict, resources any, depth int) {
+		if node == nil || depth > 64 || len(pages) >= maxPages {
+			return
+		}
+		if r, ok := node["Resources"]; ok {
+			resources = r
+		}
+		kids, isTree := d.resolve(node["Kids"]).([]any)
+		if !isTree && node["Type"] != pdfName("Pages") {
+			pages = append(pages, pdfDict{"Resources": resources})
+			return
+		}
+		for _, kid := range kids {
+			if ref, ok := kid.(pdfRef); ok {
+				if visited[ref] {
+					continue
+				}
+				visited[ref] = true
+			}
+			walk(d.dict(kid), resources, depth+1)
+		}
+	}
+	walk(d.dict(d.root["Pages"]), nil, 0)
+	if len(pages) > 0 {
+		return pages
+	}
+	for _, num := range d.objectNumbers() {
+		if p, ok := d.objects[num].(pdfDict); ok && p["Type"] == pdfName("Page") {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// pageImage 返回页资源中像素最多的图片 XObject，没有时返回 nil。扫描件的每一页就是一张图片，
+// 表单 XObject 中的图片不查找。
+func (d *pdfDoc) pageImage(page pdfDict) *pdfStream {
+	var best *pdfStream
+	var bestArea int
+	for _, v := range d.dict(d.dict(page["Resources"])["XObject"]) {
+		s, ok := d.resolve(v).(*pdfStream)
+		if !ok || s.Dict["Subtype"] != pdfName("Image") {
+			continue
+		}
+		// 面积相同时按内容偏移选择，结果与 map 的遍历顺序无关
+		area := intOf(d.resolve(s.Dict["Width"]), 0) * intOf(d.resolve(s.Dict["Height"]), 0)
+		if area > bestArea || (area == bestArea && best != nil && s.start < best.start) {
+			best, bestArea = s, area
+		}
+	}
+	return best
+}
+
+func intOf(v any, def int) int {
+	if f, ok := v.(float64); ok && f >= -maxPixels && f <= maxPixels {
+		return int(f)
+	}
+	return def
+}
+
+// streamEnd 返回从 start 起长度为 length 的流内容的结束位置，length 不是数字、超出 data
+// 或之后不是 endstream 时 ok 为 false。length 来自不可信的输入，先与剩余长度比较再转换为 int，避免溢出。
+func streamEnd(data []byte, start int, length any) (end int, ok bool) {
+	n, isNum := length.(float64)
+	if !isNum || !(n >= 0 && n <= float64(len(data)-start)) {
+		return 0, false
+	}
+	end = start + int(n)
+	i := end
+	for i < len(data) && isSpace(data[i]) {
+		i++
+	}
+	return end, bytes.HasPrefix(data[i:], []byte("endstream"))
+}
+
+// pdfLexer 解析 PDF 的基本对象：数字为 float64，字符串为 pdfString，名称为 pdfName，
+// 数组为 []any，字典为 pdfDict，间接引用为 pdfRef，流为 *pdfStream。
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+var errPDFSyntax = errors.New("document: invalid PDF syntax")
+
+func (l *pdfLexer) object() (any, error) {
+	return l.objectDepth(0)
+}
+
+func (l *pdfLexer) objectDepth(depth int) (any, error) {
+	if depth > 64 {
+		return nil, errPDFSyntax
+	}
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errPDFSyntax
+	}
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		l.pos++
+		return pdfName(l.name()), nil
+	case c == '(':
+		return l.literalString()
+	case c == '[':
+		l.pos++
+		var arr []any
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, errPDFSyntax
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+			v, err := l.objectDepth(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		return l.dictOrStream(depth)
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errPDFSyntax
+		}
+		s := pdfString(l.data[l.pos+1 : l.pos+end])
+		l.pos += end + 1
+		return s, nil
+	case c == '+' || c == '-' || c == '.' || isDigit(c):
+		return l.numberOrRef()
+	case isRegular(c):
+		kw := l.token()
+		switch kw {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return pdfKeyword(kw), nil
+	}
+	return nil, errPDFSyntax
+}
+
+func (l *pdfLexer) dictOrStream(depth int) (any, error) {
+	l.pos += 2
+	dict := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos+1 >= len(l.data) {
+			return nil, errPDFSyntax
+		}
+		if l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			break
+		}
+		key, err := l.objectDepth(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, errPDFSyntax
+		}
+		v, err := l.objectDepth(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = v
+	}
+
+	save := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return dict, nil
+	}
+	l.pos += len("stream")
+	if bytes.HasPrefix(l.data[l.pos:], []byte("\r\n")) {
+		l.pos += 2
+	} else if l.pos < len(l.data) && (l.data[l.pos] == '\n' || l.data[l.pos] == '\r') {
+		l.pos++
+	}
+	s := &pdfStream{Dict: dict, start: l.pos}
+	if end, ok := streamEnd(l.data, l.pos, dict["Length"]); ok {
+		s.Data = l.data[l.pos:end]
+	} else {
+		// /Length 缺失、为间接对象或不正确时以 endstream 为界，fixLengths 随后修正间接的长度
+		i := bytes.Index(l.data[l.pos:], []byte("endstream"))
+		if i < 0 {
+			return nil, errPDFSyntax
+		}
+		s.Data = bytes.TrimSuffix(l.data[l.pos:l.pos+i], []byte("\n"))
+		s.Data = bytes.TrimSuffix(s.Data, []byte("\r"))
+	}
+	end := bytes.Index(l.data[l.pos+len(s.Data):], []byte("endstream"))
+	l.pos += len(s.Data) + end + len("endstream")
+	return s, nil
+}
+
+func (l *pdfLexer) numberOrRef() (any, error) {
+	tok := l.token()
+	n, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return nil, errPDFSyntax
+	}
+	if n != float64(int(n)) || n < 0 || strings.ContainsAny(tok, "+.") {
+		return n, nil
+	}
+	// "num gen R" 为间接引用
+	save := l.pos
+	l.skipSpace()
+	if l.pos < len(l.data) && isDigit(l.data[l.pos]) {
+		if gen, err := strconv.Atoi(l.token()); err == nil {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || !isRegular(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{Num: int(n), Gen: gen}, nil
+			}
+		}
+	}
+	l.pos = save
+	return n, nil
+}
+
+func (l *pdfLexer) name() string {
+	var b []byte
+	for l.pos < len(l.data) && isRegular(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return string(b)
+}
+
+// literalString 跳过括号字符串，只处理嵌套的括号与转义，不解码内容。
+func (l *pdfLexer) literalString() (any, error) {
+	start := l.pos + 1
+	nesting := 0
+	for l.pos++; l.pos < len(l.data); l.pos++ {
+		switch l.data[l.pos] {
+		case '\\':
+			l.pos++
+		case '(':
+			nesting++
+		case ')':
+			if nesting == 0 {
+				l.pos++
+				return pdfString(l.data[start : l.pos-1]), nil
+			}
+			nesting--
+		}
+	}
+	return nil, errPDFSyntax
+}
+
+func (l *pdfLexer) token() string {
+	start := l.pos
+	for l.pos < len(l.data) && isRegular(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isRegular 报告 c 是否为普通字符，即既不是空白也不是分隔符。
+func isRegular(c byte) bool {
+	return !isSpace(c) && bytes.IndexByte([]byte("()<>[]{}/%"), c) < 0
+}
@@ -0,0 +1,289 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/ccitt"
+)
+
+// maxDecodedSize 限制单个流解码后的大小，防止压缩炸弹。
+const maxDecodedSize = 512 << 20
+
+// maxPixels 限制单张图片的像素数。
+const maxPixels = 1 << 28
+
+// extractImage 将图片 XObject 转换为识别服务可接受的图片：DCTDecode 的内容本身就是 JPEG，原样返回；
+// CCITT 与未压缩或 Flate 压缩的像素解码后编码为 PNG。
+func (d *pdfDoc) extractImage(s *pdfStream) ([]byte, string, error) {
+	data, filter, parms, err := d.decodeStream(s)
+	if err != nil {
+		return nil, "", err
+	}
+	var img image.Image
+	switch filter {
+	case "DCTDecode", "DCT":
+		return data, "jpg", nil
+	case "CCITTFaxDecode", "CCF":
+		img, err = d.ccittImage(s, data, parms)
+	case "":
+		img, err = d.rawImage(s, data)
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupported, filter)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "png", nil
+}
+
+// decodeStream 应用流的 FlateDecode 过滤器。遇到图片编码（DCT、CCITT、JBIG2、JPX）时停止，
+// 返回此时的内容、该过滤器的名称与参数；没有图片编码时 filter 为空。其他过滤器返回 ErrUnsupported。
+func (d *pdfDoc) decodeStream(s *pdfStream) (data []byte, filter string, parms pdfDict, err error) {
+	var filters, params []any
+	switch f := d.resolve(s.Dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	switch p := d.resolve(s.Dict["DecodeParms"]).(type) {
+	case pdfDict:
+		params = []any{p}
+	case []any:
+		params = p
+	}
+
+	data = s.Data
+	for i, f := range filters {
+		name, _ := d.resolve(f).(pdfName)
+		parms = nil
+		if i < len(params) {
+			parms = d.dict(params[i])
+		}
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil {
+				data, err = d.unpredict(data, parms)
+			}
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("document: %s: %w", name, err)
+			}
+		case "DCTDecode", "DCT", "CCITTFaxDecode", "CCF", "JBIG2Decode", "JPXDecode":
+			return data, string(name), parms, nil
+		default:
+			return nil, "", nil, fmt.Errorf("%w: filter %s", ErrUnsupported, name)
+		}
+	}
+	return data, "", nil, nil
+}
+
+// ccittImage 解码 CCITT G3/G4 编码的二值图片。
+func (d *pdfDoc) ccittImage(s *pdfStream, data []byte, parms pdfDict) (image.Image, error) {
+	width := intOf(d.resolve(s.Dict["Width"]), intOf(d.resolve(parms["Columns"]), 1728))
+	height := intOf(d.resolve(s.Dict["Height"]), intOf(d.resolve(parms["Rows"]), 0))
+	if err := checkSize(width, height); err != nil {
+		return nil, err
+	}
+	sf := ccitt.Group3
+	if intOf(d.resolve(parms["K"]), 0) < 0 {
+		sf = ccitt.Group4
+	}
+	// 解码结果中 0xFF 为白色。BlackIs1 与 /Decode [1 0] 各自反转一次像素的含义
+	invert := d.resolve(parms["BlackIs1"]) == true
+	if decode, _ := d.resolve(s.Dict["Decode"]).([]any); len(decode) >= 2 && d.resolve(decode[0]) == 1.0 {
+		invert = !invert
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	opts := &ccitt.Options{Align: d.resolve(parms["EncodedByteAlign"]) == true, Invert: invert}
+	if err := ccitt.DecodeIntoGray(img, bytes.NewReader(data), ccitt.MSB, sf, opts); err != nil {
+		return nil, fmt.Errorf("document: CCITTFaxDecode: %w", err)
+	}
+	return img, nil
+}
+
+// rawImage 将未编码的像素数据转换为图片。只支持扫描件常见的 1 位灰度（包括图片蒙版）
+// 与 8 位灰度、RGB、CMYK，其他位深与颜色空间返回 ErrUnsupported。
+func (d *pdfDoc) rawImage(s *pdfStream, data []byte) (image.Image, error) {
+	width, height := intOf(d.resolve(s.Dict["Width"]), 0), intOf(d.resolve(s.Dict["Height"]), 0)
+	if err := checkSize(width, height); err != nil {
+		return nil, err
+	}
+	bpc, comps := intOf(d.resolve(s.Dict["BitsPerComponent"]), 8), 1
+	// 图片蒙版中 0 为绘制（黑色）、1 为透明（白色），与 1 位灰度图相同
+	if d.resolve(s.Dict["ImageMask"]) == true {
+		bpc = 1
+	} else if comps = d.components(s.Dict["ColorSpace"]); comps == 0 {
+		return nil, fmt.Errorf("%w: color space %v", ErrUnsupported, d.resolve(s.Dict["ColorSpace"]))
+	}
+	if bpc != 8 && (bpc != 1 || comps != 1) {
+		return nil, fmt.Errorf("%w: %d bits per component", ErrUnsupported, bpc)
+	}
+
+	stride := (width*comps*bpc + 7) / 8
+	if len(data)/stride < height {
+		return nil, fmt.Errorf("document: image data too short: %d bytes, want %d", len(data), stride*height)
+	}
+	rect := image.Rect(0, 0, width, height)
+	switch {
+	case bpc == 1:
+		img := image.NewGray(rect)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if data[y*stride+x/8]&(0x80>>(x%8)) != 0 {
+					img.Pix[y*img.Stride+x] = 0xFF
+				}
+			}
+		}
+		return img, nil
+	case comps == 1:
+		img := image.NewGray(rect)
+		for y := 0; y < height; y++ {
+			copy(img.Pix[y*img.Stride:], data[y*stride:y*stride+width])
+		}
+		return img, nil
+	case comps == 3:
+		img := image.NewRGBA(rect)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				p, q := img.Pix[y*img.Stride+4*x:], data[y*stride+3*x:]
+				p[0], p[1], p[2], p[3] = q[0], q[1], q[2], 0xFF
+			}
+		}
+		return img, nil
+	}
+	img := image.NewCMYK(rect)
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*img.Stride:], data[y*stride:y*stride+4*width])
+	}
+	return img, nil
+}
+
+// components 返回设备颜色空间的分量数，ICCBased 按其 /N 处理；不支持的颜色空间返回 0。
+func (d *pdfDoc) components(v any) int {
+	var family pdfName
+	var args []any
+	switch v := d.resolve(v).(type) {
+	case nil:
+		return 1
+	case pdfName:
+		family = v
+	case []any:
+		if len(v) > 0 {
+			family, _ = d.resolve(v[0]).(pdfName)
+			args = v[1:]
+		}
+	}
+	switch family {
+	case "DeviceGray", "G", "CalGray":
+		return 1
+	case "DeviceRGB", "RGB", "CalRGB":
+		return 3
+	case "DeviceCMYK", "CMYK":
+		return 4
+	case "ICCBased":
+		if len(args) > 0 {
+			if n := intOf(d.resolve(d.dict(args[0])["N"]), 0); n == 1 || n == 3 || n == 4 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// unpredict 还原 Flate 的 PNG 行过滤（Predictor 10 及以上），其他预测编码返回 ErrUnsupported。
+func (d *pdfDoc) unpredict(data []byte, parms pdfDict) ([]byte, error) {
+	predictor := intOf(d.resolve(parms["Predictor"]), 1)
+	if predictor < 2 {
+		return data, nil
+	}
+	if predictor < 10 {
+		return nil, fmt.Errorf("%w: predictor %d", ErrUnsupported, predictor)
+	}
+	colors := intOf(d.resolve(parms["Colors"]), 1)
+	bpc := intOf(d.resolve(parms["BitsPerComponent"]), 8)
+	columns := intOf(d.resolve(parms["Columns"]), 1)
+	if colors <= 0 || bpc <= 0 || columns <= 0 || colors*bpc > 64 {
+		return nil, errors.New("invalid predictor parameters")
+	}
+	bpp := max(1, (colors*bpc+7)/8)
+	rowLen := (colors*bpc*columns + 7) / 8
+
+	out := make([]byte, 0, len(data)/(rowLen+1)*rowLen)
+	prev := make([]byte, rowLen)
+	for off := 0; off+rowLen+1 <= len(data); off += rowLen + 1 {
+		filter, cur := data[off], bytes.Clone(data[off+1:off+1+rowLen])
+		for i := range cur {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = cur[i-bpp], prev[i-bpp]
+			}
+			switch filter {
+			case 1:
+				cur[i] += left
+			case 2:
+				cur[i] += prev[i]
+			case 3:
+				cur[i] += byte((int(left) + int(prev[i])) / 2)
+			case 4:
+				cur[i] += paeth(left, prev[i], upLeft)
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// inflate 解压 zlib 数据，解压后超过 maxDecodedSize 时返回错误。许多 PDF 生成器写出的流缺少校验和
+// 或被截断，已解压的部分仍然保留。
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(io.LimitReader(zr, maxDecodedSize+1))
+	if len(out) > maxDecodedSize {
+		return nil, errors.New("decoded stream too large")
+	}
+	if err != nil && (len(out) == 0 || !errors.Is(err, io.ErrUnexpectedEOF)) {
+		return nil, err
+	}
+	return out, nil
+}
+
+func checkSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > maxPixels/height {
+		return fmt.Errorf("document: invalid image size %dx%d", width, height)
+	}
+	return nil
+}
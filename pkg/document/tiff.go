@@ -0,0 +1,183 @@
+package document
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/png"
+
+	"golang.org/x/image/tiff"
+)
+
+// maxPages 限制文档的页数，防止损坏的文件导致无限循环或过量内存分配。
+const maxPages = 10000
+
+// splitTIFF 解码多页 TIFF 的每一个 IFD，每页编码为 PNG。
+func splitTIFF(data []byte) ([]Page, error) {
+	offsets, err := tiffPageOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]Page, len(offsets))
+	for i, offset := range offsets {
+		pages[i].Number = i + 1
+		pages[i].Data, pages[i].Err = tiffPage(data, offset)
+		if pages[i].Err == nil {
+			pages[i].Format = "png"
+		}
+	}
+	return pages, nil
+}
+
+// tiffPageOffsets 沿 IFD 链返回每一页的 IFD 偏移。
+func tiffPageOffsets(data []byte) ([]uint32, error) {
+	if len(data) < 8 {
+		return nil, errors.New("document: truncated TIFF header")
+	}
+	order := tiffByteOrder(data)
+
+	var offsets []uint32
+	seen := make(map[uint32]bool)
+	for offset := order.Uint32(data[4:8]); offset != 0; {
+		if seen[offset] || len(offsets) >= maxPages {
+			return nil, errors.New("document: invalid TIFF IFD chain")
+		}
+		seen[offset] = true
+		if int64(offset)+2 > int64(len(data)) {
+			return nil, fmt.Errorf("document: TIFF IFD offset %d out of range", offset)
+		}
+		entries := int64(order.Uint16(data[offset:]))
+		next := int64(offset) + 2 + entries*12
+		if next+4 > int64(len(data)) {
+			return nil, fmt.Errorf("document: truncated TIFF IFD at offset %d", offset)
+		}
+		offsets = append(offsets, offset)
+		offset = order.Uint32(data[next:])
+	}
+	if len(offsets) == 0 {
+		return nil, errors.New("document: TIFF has no pages")
+	}
+	return offsets, nil
+}
+
+// tiffPage 解码 IFD 位于 offset 的一页。tiff.Decode 只读取第一个 IFD，
+// 这里让它看到一份首个 IFD 偏移被替换为 offset 的文件头，其余内容与原文件共享。
+func tiffPage(data []byte, offset uint32) ([]byte, error) {
+	header := make([]byte, 8)
+	copy(header, data[:8])
+	if data[0] == 'M' {
+		binary.BigEndian.PutUint32(header[4:], offset)
+	} else {
+		binary.LittleEndian.PutUint32(header[4:], offset)
+	}
+
+	// tiff.Decode 按 IFD 中声明的长度与尺寸分配内存，先拒绝超出文件或尺寸过大的损坏页
+	if err := checkTIFFIFD(data, offset); err != nil {
+		return nil, err
+	}
+	cfg, err := tiff.DecodeConfig(&patchedReader{data: data, header: header})
+	if err != nil {
+		return nil, fmt.Errorf("document: decode TIFF page: %w", err)
+	}
+	if err := checkSize(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+	if bytesPerPixel(cfg.ColorModel)*cfg.Width*cfg.Height > maxDecodedSize {
+		return nil, fmt.Errorf("document: TIFF page %dx%d too large", cfg.Width, cfg.Height)
+	}
+	img, err := tiff.Decode(&patchedReader{data: data, header: header})
+	if err != nil {
+		return nil, fmt.Errorf("document: decode TIFF page: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tiffTypeSizes 是 TIFF 各数据类型的字节数，下标为类型编号。
+var tiffTypeSizes = [...]int64{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// checkTIFFIFD 校验 offset 处的 IFD：每个条目的值以及各条带（分块）的字节数都不能超出文件。
+func checkTIFFIFD(data []byte, offset uint32) error {
+	order := tiffByteOrder(data)
+	size := int64(len(data))
+	n := int64(order.Uint16(data[offset:]))
+	for i := int64(0); i < n; i++ {
+		entry := data[int64(offset)+2+12*i:]
+		tag, typ, count := order.Uint16(entry), order.Uint16(entry[2:]), int64(order.Uint32(entry[4:]))
+		if int(typ) >= len(tiffTypeSizes) {
+			continue // tiff.Decode 会拒绝未知的类型
+		}
+		length := tiffTypeSizes[typ] * count
+		if length > 4 && int64(order.Uint32(entry[8:]))+length > size {
+			return fmt.Errorf("document: TIFF tag %d out of range", tag)
+		}
+		// StripByteCounts 与 TileByteCounts：未压缩时按声明的字节数分配缓冲区
+		if tag != 279 && tag != 325 || (typ != 3 && typ != 4) {
+			continue
+		}
+		values := entry[8:]
+		if length > 4 {
+			values = data[order.Uint32(entry[8:]):]
+		}
+		for k := int64(0); k < count; k++ {
+			v := int64(order.Uint16(values[2*k:]))
+			if typ == 4 {
+				v = int64(order.Uint32(values[4*k:]))
+			}
+			if v > size {
+				return fmt.Errorf("document: TIFF strip of %d bytes exceeds the file", v)
+			}
+		}
+	}
+	return nil
+}
+
+func tiffByteOrder(data []byte) binary.ByteOrder {
+	if data[0] == 'M' {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// bytesPerPixel 返回 tiff.Decode 为该颜色模型分配的每像素字节数。
+func bytesPerPixel(m color.Model) int {
+	switch m {
+	case color.GrayModel:
+		return 1
+	case color.Gray16Model:
+		return 2
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	}
+	if _, ok := m.(color.Palette); ok {
+		return 1
+	}
+	return 4
+}
+
+// patchedReader 是开头 len(header) 个字节被 header 替换后的 data，同时实现 io.Reader 与 io.ReaderAt，
+// tiff.Decode 会直接使用 ReadAt 而不缓冲整个输入。
+type patchedReader struct {
+	data   []byte
+	header []byte
+	pos    int64
+}
+
+func (r *patchedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	return n, err
+}
+
+func (r *patchedReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := bytes.NewReader(r.data).ReadAt(p, off)
+	if off < int64(len(r.header)) {
+		copy(p, r.header[off:min(int64(len(r.header)), off+int64(n))])
+	}
+	return n, err
+}
@@ -0,0 +1,303 @@
+// Package emulator 实现各讯飞服务的线上协议（见 docs/*_api_protocol.md），可作为本地服务运行
+// （cmd/xfyun-emulator），也可在测试中配合 httptest 使用，让所有客户端通过 WithHost 指向本地：
+//
+//	srv := httptest.NewServer(emulator.New(emulator.WithResponse(emulator.ServiceTranslate, emulator.Response{Result: "Hello"})))
+//	defer srv.Close()
+//	client := translate.NewClient(appID, apiKey, apiSecret, translate.WithHost(emulator.Endpoint(srv.URL, emulator.ServiceTranslate)))
+//
+// 每个服务返回固定的（WithResponse）、按调用顺序排列的（WithSequence）或由函数生成的（WithScript）结果，
+// 结果中可以注入延迟、业务错误码与网关 HTTP 错误。配置了 WithCredentials 时会像真实网关一样校验签名，
+// 否则接受任意凭证。
+package emulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+// 模拟的服务，取值与 xfyunerr.APIError.Service 相同。
+const (
+	ServiceOCR            = "ocr"
+	ServiceLLMOCR         = "llmocr"
+	ServiceIOCRLD         = "iocrld"
+	ServiceTranslate      = "translate"
+	ServiceDetectLanguage = "detectlanguage"
+	ServiceIST            = "ist"
+	ServiceTTS            = "tts"
+)
+
+// Services 是模拟器支持的全部服务。
+var Services = []string{ServiceOCR, ServiceLLMOCR, ServiceIOCRLD, ServiceTranslate, ServiceDetectLanguage, ServiceIST, ServiceTTS}
+
+// 各服务的路径，与线上地址的路径相同。ist 的 upload 与 getResult 位于 paths[ServiceIST] 之下。
+var paths = map[string]string{
+	ServiceOCR:            "/v1/ocr",
+	ServiceLLMOCR:         "/v1/private/se75ocrbm",
+	ServiceIOCRLD:         "/v1/private/s15fc3900",
+	ServiceTranslate:      "/v1/its",
+	ServiceDetectLanguage: "/v1/private/s0ed5898e",
+	ServiceIST:            "/v2/api",
+	ServiceTTS:            "/v2/tts",
+}
+
+// Endpoint 返回 service 在以 baseURL（例如 httptest.Server.URL）为根的模拟器上的地址，
+// 可直接传给对应客户端的 WithHost；tts 返回 ws:// 或 wss:// 地址。
+func Endpoint(baseURL, service string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if service == ServiceTTS {
+		baseURL = "ws" + strings.TrimPrefix(baseURL, "http")
+	}
+	return baseURL + paths[service]
+}
+
+// Request 是模拟器收到的一次调用，传给 WithScript 的函数。
+type Request struct {
+	Service string
+	Call    int               // 该服务的第几次调用，从 1 开始
+	AppID   string            // 请求中的 AppID
+	Input   []byte            // 解码后的输入：图片、文本或音频
+	Params  map[string]string // 服务参数，例如 translate 的 from/to、tts 的 vcn/aue、ist 的查询参数
+}
+
+// Response 是模拟器对一次调用的回复。
+type Response struct {
+	// Result 是解码后的结果：ocr/llmocr/iocrld 为 payload 中 text 解码后的内容，translate 为译文，
+	// detectlanguage 为 lan_probs，ist 为 orderResult，tts 为音频数据。为空时使用服务的默认结果。
+	Result string
+
+	// Code 非 0 时返回该业务错误码（ist 为 code 字段，其余为 header.code），Message 为空时使用错误码目录中的描述。
+	Code    int
+	Message string
+
+	// HTTPStatus 非 0 时网关直接以该状态码和 {"message": Message} 拒绝请求，对 tts 无效。
+	HTTPStatus int
+
+	// Latency 是回复前的延迟，为 0 时使用 WithLatency 设置的默认值。
+	Latency time.Duration
+
+	// Pending 是 ist 任务在完成前 getResult 返回处理中（status 3）的次数。
+	Pending int
+
+	// FailType 非 0 时 ist 任务以 status -1 与该 failType 失败。
+	FailType int
+}
+
+// Script 根据请求生成回复。
+type Script func(Request) Response
+
+// Emulator 是模拟的讯飞网关，实现了 http.Handler，并发安全。
+type Emulator struct {
+	creds   []auth.Credentials
+	latency time.Duration
+	scripts map[string]Script
+	logger  *slog.Logger
+
+	verifier *auth.Verifier
+	mux      *http.ServeMux
+
+	mu     sync.Mutex
+	calls  map[string]int
+	orders map[string]*order
+	sid    int
+}
+
+// Option is a function that configures an Emulator.
+type Option func(*Emulator)
+
+// WithCredentials makes the emulator verify request signatures against creds, like the real gateway.
+// Without it any credentials are accepted.
+func WithCredentials(creds ...auth.Credentials) Option {
+	return func(e *Emulator) {
+		e.creds = append(e.creds, creds...)
+	}
+}
+
+// WithLatency sets the default delay before every response.
+func WithLatency(d time.Duration) Option {
+	return func(e *Emulator) {
+		e.latency = d
+	}
+}
+
+// WithResponse makes service return resp for every call.
+func WithResponse(service string, resp Response) Option {
+	return WithScript(service, func(Request) Response { return resp })
+}
+
+// WithSequence makes the n-th call to service return resps[n-1]; the last response repeats.
+func WithSequence(service string, resps ...Response) Option {
+	return WithScript(service, func(req Request) Response {
+		if len(resps) == 0 {
+			return Response{}
+		}
+		return resps[min(req.Call, len(resps))-1]
+	})
+}
+
+// WithScript makes service reply with the result of script.
+func WithScript(service string, script Script) Option {
+	return func(e *Emulator) {
+		if script != nil {
+			e.scripts[service] = script
+		}
+	}
+}
+
+// WithLogger sets the logger used to log every call.
+func WithLogger(logger *slog.Logger) Option {
+	return func(e *Emulator) {
+		if logger != nil {
+			e.logger = logger
+		}
+	}
+}
+
+// New 创建模拟器。未配置的服务返回默认结果。
+func New(opts ...Option) *Emulator {
+	e := &Emulator{
+		scripts: make(map[string]Script),
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		calls:   make(map[string]int),
+		orders:  make(map[string]*order),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if len(e.creds) > 0 {
+		e.verifier = auth.NewVerifier(e.creds)
+	}
+
+	e.mux = http.NewServeMux()
+	e.mux.HandleFunc("POST "+paths[ServiceOCR], e.serveOCR)
+	e.mux.HandleFunc("POST "+paths[ServiceLLMOCR], e.serveLLMOCR)
+	e.mux.HandleFunc("POST "+paths[ServiceIOCRLD], e.serveIOCRLD)
+	e.mux.HandleFunc("POST "+paths[ServiceTranslate], e.serveTranslate)
+	e.mux.HandleFunc("POST "+paths[ServiceDetectLanguage], e.serveDetectLanguage)
+	e.mux.HandleFunc("POST "+paths[ServiceIST]+"/upload", e.serveISTUpload)
+	e.mux.HandleFunc("POST "+paths[ServiceIST]+"/getResult", e.serveISTGetResult)
+	e.mux.HandleFunc("GET "+paths[ServiceTTS], e.serveTTS)
+	return e
+}
+
+// ServeHTTP 实现 http.Handler。
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// Calls 返回 service 已收到的调用次数（ist 只统计上传）。
+func (e *Emulator) Calls(service string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls[service]
+}
+
+// respond 记录一次调用并返回脚本生成的回复，已应用默认延迟。
+func (e *Emulator) respond(req Request) Response {
+	e.mu.Lock()
+	e.calls[req.Service]++
+	req.Call = e.calls[req.Service]
+	script := e.scripts[req.Service]
+	e.mu.Unlock()
+
+	var resp Response
+	if script != nil {
+		resp = script(req)
+	}
+	if resp.Latency == 0 {
+		resp.Latency = e.latency
+	}
+	if resp.Code != 0 && resp.Message == "" {
+		resp.Message = "emulated error"
+		if entry, ok := xfyunerr.Lookup(req.Service, resp.Code); ok {
+			resp.Message = entry.Description
+		}
+	}
+	if resp.HTTPStatus != 0 && resp.Message == "" {
+		resp.Message = http.StatusText(resp.HTTPStatus)
+	}
+	e.logger.Info("emulator call", "service", req.Service, "call", req.Call, "app_id", req.AppID,
+		"input_size", len(req.Input), "code", resp.Code, "http_status", resp.HTTPStatus, "latency", resp.Latency)
+	return resp
+}
+
+// sleep 等待 d，请求被取消时提前返回 false。
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// verifyHMAC 校验 host/date/authorization 签名，失败时按网关的格式写入错误并返回 false。
+// 未配置凭证时只返回 true。
+func (e *Emulator) verifyHMAC(w http.ResponseWriter, r *http.Request) (auth.Credentials, bool) {
+	if e.verifier == nil {
+		return auth.Credentials{}, true
+	}
+	creds, err := e.verifier.Verify(r)
+	if err != nil {
+		status, message := http.StatusUnauthorized, "HMAC signature cannot be verified"
+		var verr *auth.VerifyError
+		if errors.As(err, &verr) {
+			status, message = verr.StatusCode, verr.Message
+		}
+		writeJSON(w, status, map[string]string{"message": message})
+		return auth.Credentials{}, false
+	}
+	return creds, true
+}
+
+// verifySigna 校验 ist 的 appId/ts/signa，失败时以 LFASR 的业务错误码回复并返回 false。
+func (e *Emulator) verifySigna(w http.ResponseWriter, r *http.Request) bool {
+	if e.verifier == nil {
+		return true
+	}
+	if _, err := e.verifier.VerifySigna(r); err != nil {
+		code, desc := "26601", "非法应用信息"
+		if errors.Is(err, auth.ErrMissingAuthorization) {
+			code, desc = "26610", "请求参数错误"
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"code": code, "descInfo": desc, "content": nil})
+		return false
+	}
+	return true
+}
+
+// appIDMismatch 在签名凭证与请求中的 AppID 不属于同一应用时返回 true。
+func appIDMismatch(creds auth.Credentials, appID string) bool {
+	return creds.AppID != "" && appID != creds.AppID
+}
+
+// codeInvalidAppID 是请求中的 AppID 与签名凭证不匹配时的错误码。
+const codeInvalidAppID = 10313
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (e *Emulator) nextSID(service string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sid++
+	return fmt.Sprintf("emu-%s-%06d", service, e.sid)
+}
@@ -0,0 +1,263 @@
+package emulator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/fakegateway"
+	"github.com/fruitbars/goxfyunclient/pkg/service/detectlanguage"
+	"github.com/fruitbars/goxfyunclient/pkg/service/iocrld"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ist"
+	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/translate"
+	"github.com/fruitbars/goxfyunclient/pkg/service/tts"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+func newServer(t *testing.T, opts ...Option) (*Emulator, string) {
+	t.Helper()
+	e := New(append([]Option{WithCredentials(fakegateway.DefaultCredentials)}, opts...)...)
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return e, srv.URL
+}
+
+func TestEmulator_DefaultResults(t *testing.T) {
+	_, base := newServer(t)
+	ctx := context.Background()
+	c := fakegateway.DefaultCredentials
+
+	t.Run("ocr", func(t *testing.T) {
+		client := ocr.NewClient(c.AppID, c.APIKey, c.APISecret, ocr.WithHost(Endpoint(base, ServiceOCR)))
+		resp, err := client.RecognizeBytes(ctx, []byte("image"), "jpg", "ch_en")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if text, _ := resp.RecognizedText(); !strings.Contains(text, "pages") {
+			t.Errorf("Expected OCR pages, got '%s'", text)
+		}
+	})
+
+	t.Run("llmocr", func(t *testing.T) {
+		client := llmocr.NewClient(c.AppID, c.APIKey, c.APISecret, llmocr.WithHost(Endpoint(base, ServiceLLMOCR)))
+		text, err := client.RecognizeBytes(ctx, []byte("image"), "jpg", "uid")
+		if err != nil || !strings.Contains(text, "document") {
+			t.Errorf("Expected a document result, got '%s' (err=%v)", text, err)
+		}
+	})
+
+	t.Run("iocrld", func(t *testing.T) {
+		client := iocrld.NewClient(c.AppID, c.APIKey, c.APISecret, iocrld.WithHost(Endpoint(base, ServiceIOCRLD)))
+		resp, err := client.Process(ctx, "track", base64.StdEncoding.EncodeToString([]byte("image")), nil)
+		if err != nil || resp.Payload.JSON.Text == "" {
+			t.Errorf("Expected a JSON result, got %+v (err=%v)", resp, err)
+		}
+	})
+
+	t.Run("translate", func(t *testing.T) {
+		client := translate.NewClient(c.AppID, c.APIKey, c.APISecret, translate.WithHost(Endpoint(base, ServiceTranslate)))
+		dst, err := client.Translate(ctx, "你好", "cn", "en")
+		if err != nil || dst != "[cn->en] 你好" {
+			t.Errorf("Expected '[cn->en] 你好', got '%s' (err=%v)", dst, err)
+		}
+	})
+
+	t.Run("detectlanguage", func(t *testing.T) {
+		client := detectlanguage.NewClient(c.AppID, c.APIKey, c.APISecret, detectlanguage.WithHost(Endpoint(base, ServiceDetectLanguage)))
+		probs, err := client.DetectContext(ctx, "你好")
+		if err != nil || probs != `{"cn": 1}` {
+			t.Errorf("Expected '{\"cn\": 1}', got '%s' (err=%v)", probs, err)
+		}
+	})
+
+	t.Run("ist", func(t *testing.T) {
+		audio := filepath.Join(t.TempDir(), "audio.wav")
+		if err := os.WriteFile(audio, []byte("RIFF"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		client := ist.NewClient(c.AppID, c.SecretKey, ist.WithHost(Endpoint(base, ServiceIST)))
+		result, err := client.Process(ctx, audio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Content.OrderInfo.Status != istStatusDone || !strings.Contains(result.Content.OrderResult, "讯飞开放平台") {
+			t.Errorf("Expected a finished order, got %+v", result.Content)
+		}
+	})
+
+	t.Run("tts", func(t *testing.T) {
+		client := tts.NewTTSClient(c.AppID, c.APIKey, c.APISecret, tts.WithHost(Endpoint(base, ServiceTTS)))
+		audio, err := client.TextToSpeech(ctx, "你好世界", "xiaoyan", "raw")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		// 4 个字符 × 3200 字节，分 2 帧返回
+		if len(audio) != 4*ttsBytesPerRune {
+			t.Errorf("Expected %d bytes of audio, got %d", 4*ttsBytesPerRune, len(audio))
+		}
+	})
+}
+
+func TestEmulator_Script(t *testing.T) {
+	var got Request
+	e, base := newServer(t,
+		WithScript(ServiceTranslate, func(req Request) Response {
+			got = req
+			return Response{Result: strings.ToUpper(string(req.Input))}
+		}),
+		WithResponse(ServiceTTS, Response{Result: "pcm-data"}),
+	)
+	c := fakegateway.DefaultCredentials
+
+	client := translate.NewClient(c.AppID, c.APIKey, c.APISecret, translate.WithHost(Endpoint(base, ServiceTranslate)))
+	dst, err := client.Translate(context.Background(), "hello", "en", "cn")
+	if err != nil || dst != "HELLO" {
+		t.Errorf("Expected 'HELLO', got '%s' (err=%v)", dst, err)
+	}
+	if got.AppID != c.AppID || got.Call != 1 || got.Params["from"] != "en" || got.Params["to"] != "cn" {
+		t.Errorf("Unexpected request passed to the script: %+v", got)
+	}
+	if e.Calls(ServiceTranslate) != 1 {
+		t.Errorf("Expected 1 call, got %d", e.Calls(ServiceTranslate))
+	}
+
+	ttsClient := tts.NewTTSClient(c.AppID, c.APIKey, c.APISecret, tts.WithHost(Endpoint(base, ServiceTTS)))
+	audio, err := ttsClient.TextToSpeech(context.Background(), "hello", "xiaoyan", "raw")
+	if err != nil || string(audio) != "pcm-data" {
+		t.Errorf("Expected 'pcm-data', got '%s' (err=%v)", audio, err)
+	}
+}
+
+func TestEmulator_InjectedErrors(t *testing.T) {
+	_, base := newServer(t,
+		WithSequence(ServiceOCR, Response{Code: 10163}, Response{HTTPStatus: http.StatusTooManyRequests, Message: "API rate limit exceeded"}),
+		WithResponse(ServiceTTS, Response{Code: 10109}),
+		WithResponse(ServiceIST, Response{FailType: 1}),
+		WithResponse(ServiceDetectLanguage, Response{Code: 11201}),
+	)
+	ctx := context.Background()
+	c := fakegateway.DefaultCredentials
+
+	client := ocr.NewClient(c.AppID, c.APIKey, c.APISecret, ocr.WithHost(Endpoint(base, ServiceOCR)))
+	_, err := client.RecognizeBytes(ctx, []byte("image"), "jpg", "ch_en")
+	var apiErr *xfyunerr.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 10163 || !errors.Is(err, xfyunerr.ErrInvalidInput) {
+		t.Errorf("Expected API error 10163, got %v", err)
+	}
+	_, err = client.RecognizeBytes(ctx, []byte("image"), "jpg", "ch_en")
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusTooManyRequests || !errors.Is(err, xfyunerr.ErrQuota) {
+		t.Errorf("Expected HTTP 429, got %v", err)
+	}
+
+	detect := detectlanguage.NewClient(c.AppID, c.APIKey, c.APISecret, detectlanguage.WithHost(Endpoint(base, ServiceDetectLanguage)))
+	if _, err := detect.DetectContext(ctx, "hi"); !errors.Is(err, xfyunerr.ErrQuota) {
+		t.Errorf("Expected a quota error, got %v", err)
+	}
+
+	ttsClient := tts.NewTTSClient(c.AppID, c.APIKey, c.APISecret, tts.WithHost(Endpoint(base, ServiceTTS)))
+	if _, err := ttsClient.TextToSpeech(ctx, "hello", "xiaoyan", "raw"); err == nil || !strings.Contains(err.Error(), "code=10109") {
+		t.Errorf("Expected error code 10109, got %v", err)
+	}
+
+	audio := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(audio, []byte("RIFF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	istClient := ist.NewClient(c.AppID, c.SecretKey, ist.WithHost(Endpoint(base, ServiceIST)))
+	var taskErr *xfyunerr.TaskError
+	if _, err := istClient.Process(ctx, audio); !errors.As(err, &taskErr) || taskErr.FailType != 1 {
+		t.Errorf("Expected a task error with failType 1, got %v", err)
+	}
+}
+
+func TestEmulator_Auth(t *testing.T) {
+	_, base := newServer(t)
+	ctx := context.Background()
+
+	wrong := fakegateway.DefaultCredentials
+	wrong.APISecret = "wrong"
+	client := translate.NewClient(wrong.AppID, wrong.APIKey, wrong.APISecret, translate.WithHost(Endpoint(base, ServiceTranslate)))
+	if _, err := client.Translate(ctx, "hi", "en", "cn"); !errors.Is(err, xfyunerr.ErrAuth) {
+		t.Errorf("Expected an auth error, got %v", err)
+	}
+
+	ttsClient := tts.NewTTSClient(wrong.AppID, wrong.APIKey, wrong.APISecret, tts.WithHost(Endpoint(base, ServiceTTS)))
+	if _, err := ttsClient.TextToSpeech(ctx, "hi", "xiaoyan", "raw"); !errors.Is(err, xfyunerr.ErrAuth) {
+		t.Errorf("Expected the handshake to be rejected, got %v", err)
+	}
+
+	otherApp := fakegateway.DefaultCredentials
+	otherApp.AppID = "other-app"
+	client = translate.NewClient(otherApp.AppID, otherApp.APIKey, otherApp.APISecret, translate.WithHost(Endpoint(base, ServiceTranslate)))
+	if _, err := client.Translate(ctx, "hi", "en", "cn"); err == nil || !strings.Contains(err.Error(), "code=10313") {
+		t.Errorf("Expected error code 10313, got %v", err)
+	}
+
+	// 未配置凭证时接受任意凭证
+	srv := httptest.NewServer(New())
+	defer srv.Close()
+	client = translate.NewClient("any", "any", "any", translate.WithHost(Endpoint(srv.URL, ServiceTranslate)))
+	if _, err := client.Translate(ctx, "hi", "en", "cn"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestEmulator_Latency(t *testing.T) {
+	_, base := newServer(t, WithLatency(50*time.Millisecond))
+	c := fakegateway.DefaultCredentials
+	client := translate.NewClient(c.AppID, c.APIKey, c.APISecret, translate.WithHost(Endpoint(base, ServiceTranslate)))
+
+	start := time.Now()
+	if _, err := client.Translate(context.Background(), "hi", "en", "cn"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected at least 50ms latency, got %v", elapsed)
+	}
+}
+
+func TestEmulator_ISTPending(t *testing.T) {
+	srv := httptest.NewServer(New(WithResponse(ServiceIST, Response{Pending: 2})))
+	defer srv.Close()
+	endpoint := Endpoint(srv.URL, ServiceIST)
+
+	post := func(path string) map[string]any {
+		t.Helper()
+		resp, err := http.Post(endpoint+path, "application/octet-stream", strings.NewReader("RIFF"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Expected JSON, got %v", err)
+		}
+		return body
+	}
+
+	upload := post("/upload?fileName=a.wav")
+	orderID := upload["content"].(map[string]any)["orderId"].(string)
+	var statuses []float64
+	for i := 0; i < 3; i++ {
+		result := post("/getResult?orderId=" + url.QueryEscape(orderID))
+		statuses = append(statuses, result["content"].(map[string]any)["orderInfo"].(map[string]any)["status"].(float64))
+	}
+	if statuses[0] != istStatusProcessing || statuses[1] != istStatusProcessing || statuses[2] != istStatusDone {
+		t.Errorf("Expected statuses [3 3 4], got %v", statuses)
+	}
+
+	if code := post("/getResult?orderId=unknown")["code"]; code != "26602" {
+		t.Errorf("Expected code 26602 for an unknown order, got %v", code)
+	}
+}
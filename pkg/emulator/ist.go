@@ -0,0 +1,134 @@
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ist 返回的状态与错误码。
+const (
+	istCodeSuccess      = "000000"
+	istCodeEmptyAudio   = 26606
+	istCodeOrderUnknown = 26602
+
+	istStatusProcessing = 3
+	istStatusDone       = 4
+	istStatusFailed     = -1
+)
+
+// order 是一个 ist 转写任务。
+type order struct {
+	id     string
+	resp   Response
+	result string
+	polls  int
+}
+
+func (e *Emulator) serveISTUpload(w http.ResponseWriter, r *http.Request) {
+	if !e.verifySigna(w, r) {
+		return
+	}
+	audio, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "read body failed"})
+		return
+	}
+
+	q := r.URL.Query()
+	req := Request{Service: ServiceIST, AppID: q.Get("appId"), Input: audio, Params: make(map[string]string)}
+	for key := range q {
+		if key != "signa" && key != "ts" && key != "appId" {
+			req.Params[key] = q.Get(key)
+		}
+	}
+	if len(audio) == 0 && req.Params["audioUrl"] == "" {
+		writeISTError(w, istCodeEmptyAudio, "空音频")
+		return
+	}
+
+	resp := e.respond(req)
+	if !sleep(r.Context(), resp.Latency) {
+		return
+	}
+	switch {
+	case resp.HTTPStatus != 0:
+		writeJSON(w, resp.HTTPStatus, map[string]string{"message": resp.Message})
+		return
+	case resp.Code != 0:
+		writeISTError(w, resp.Code, resp.Message)
+		return
+	}
+
+	result := resp.Result
+	if result == "" {
+		result = defaultOrderResult
+	}
+	e.mu.Lock()
+	o := &order{id: fmt.Sprintf("EMU%06d", len(e.orders)+1), resp: resp, result: result}
+	e.orders[o.id] = o
+	e.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"code": istCodeSuccess, "descInfo": "success",
+		"content": map[string]any{"orderId": o.id, "taskEstimateTime": resp.Pending * 5000},
+	})
+}
+
+func (e *Emulator) serveISTGetResult(w http.ResponseWriter, r *http.Request) {
+	if !e.verifySigna(w, r) {
+		return
+	}
+	id := r.URL.Query().Get("orderId")
+
+	e.mu.Lock()
+	o, ok := e.orders[id]
+	var status int
+	if ok {
+		switch {
+		case o.polls < o.resp.Pending:
+			status = istStatusProcessing
+		case o.resp.FailType != 0:
+			status = istStatusFailed
+		default:
+			status = istStatusDone
+		}
+		o.polls++
+	}
+	e.mu.Unlock()
+	if !ok {
+		writeISTError(w, istCodeOrderUnknown, "任务ID不存在")
+		return
+	}
+
+	orderInfo := map[string]any{"orderId": o.id, "failType": 0, "status": status, "originalDuration": 1000, "realDuration": 1000}
+	content := map[string]any{"orderInfo": orderInfo, "orderResult": "", "taskEstimateTime": 0}
+	switch status {
+	case istStatusFailed:
+		orderInfo["failType"] = o.resp.FailType
+	case istStatusDone:
+		content["orderResult"] = o.result
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"code": istCodeSuccess, "descInfo": "success", "content": content})
+}
+
+// writeISTError 以 LFASR 的格式回复业务错误：HTTP 200，code 为字符串。
+func writeISTError(w http.ResponseWriter, code int, desc string) {
+	writeJSON(w, http.StatusOK, map[string]any{"code": strconv.Itoa(code), "descInfo": desc, "content": nil})
+}
+
+// defaultOrderResult 是未配置结果时的转写结果，格式与 resultType=transfer 的 orderResult 相同。
+var defaultOrderResult = func() string {
+	best, _ := json.Marshal(map[string]any{
+		"st": map[string]any{
+			"bg": "0", "ed": "1000", "rl": "0",
+			"rt": []any{map[string]any{
+				"ws": []any{map[string]any{"wb": 1, "we": 100, "cw": []any{map[string]any{"w": "讯飞开放平台", "wp": "n", "wc": "1.0000"}}}},
+			}},
+		},
+	})
+	result, _ := json.Marshal(map[string]any{"lattice": []any{map[string]any{"json_1best": string(best)}}})
+	return string(result)
+}()
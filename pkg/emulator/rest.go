@@ -0,0 +1,219 @@
+package emulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 请求格式错误时返回的业务错误码。
+const (
+	codeInvalidJSON   = 10160
+	codeInvalidBase64 = 10161
+	codeInvalidParams = 10163
+)
+
+// restRequest 是 header/parameter/payload 结构的请求体，覆盖 ocr、llmocr、iocrld、translate、detectlanguage。
+type restRequest struct {
+	Header struct {
+		AppID string `json:"app_id"`
+	} `json:"header"`
+	Parameter map[string]map[string]any `json:"parameter"`
+	Payload   map[string]struct {
+		Image string `json:"image"`
+		Text  string `json:"text"`
+	} `json:"payload"`
+}
+
+// restService 描述一个 REST 服务的请求与响应格式。
+type restService struct {
+	name string
+
+	// input 是 payload 中承载输入数据的字段，其中的 image 或 text 经 base64 解码后作为 Request.Input。
+	input string
+
+	// payload 根据结果生成响应的 payload。
+	payload func(req Request, result string) (any, error)
+
+	// defaultResult 是未配置结果时返回的内容。
+	defaultResult func(req Request) string
+}
+
+func (e *Emulator) serveOCR(w http.ResponseWriter, r *http.Request) {
+	e.serveREST(w, r, restService{
+		name:  ServiceOCR,
+		input: "image",
+		payload: func(_ Request, result string) (any, error) {
+			return map[string]any{"ocr_output_text": encodedText(result)}, nil
+		},
+		defaultResult: func(Request) string {
+			return `{"pages":[{"lines":[{"words":[{"content":"讯飞开放平台"}]}]}]}`
+		},
+	})
+}
+
+func (e *Emulator) serveLLMOCR(w http.ResponseWriter, r *http.Request) {
+	e.serveREST(w, r, restService{
+		name:  ServiceLLMOCR,
+		input: "image",
+		payload: func(_ Request, result string) (any, error) {
+			return map[string]any{"result": encodedText(result)}, nil
+		},
+		defaultResult: func(Request) string {
+			return `{"document":[{"name":"title","value":"讯飞开放平台"}],"image":[],"version":"emulator"}`
+		},
+	})
+}
+
+func (e *Emulator) serveIOCRLD(w http.ResponseWriter, r *http.Request) {
+	e.serveREST(w, r, restService{
+		name:  ServiceIOCRLD,
+		input: "image",
+		payload: func(_ Request, result string) (any, error) {
+			return map[string]any{"json": encodedText(result)}, nil
+		},
+		defaultResult: func(Request) string {
+			return `{"pages":[{"lines":[{"words":[{"content":"讯飞开放平台"}]}]}]}`
+		},
+	})
+}
+
+func (e *Emulator) serveTranslate(w http.ResponseWriter, r *http.Request) {
+	e.serveREST(w, r, restService{
+		name:  ServiceTranslate,
+		input: "input_data",
+		payload: func(req Request, result string) (any, error) {
+			inner, err := json.Marshal(map[string]any{
+				"from": req.Params["from"], "to": req.Params["to"],
+				"trans_result": map[string]string{"src": string(req.Input), "dst": result},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"result": encodedText(string(inner))}, nil
+		},
+		defaultResult: func(req Request) string {
+			return fmt.Sprintf("[%s->%s] %s", req.Params["from"], req.Params["to"], req.Input)
+		},
+	})
+}
+
+func (e *Emulator) serveDetectLanguage(w http.ResponseWriter, r *http.Request) {
+	e.serveREST(w, r, restService{
+		name:  ServiceDetectLanguage,
+		input: "request",
+		payload: func(req Request, result string) (any, error) {
+			inner, err := json.Marshal(map[string]any{
+				"trans_result": []map[string]string{{"src": string(req.Input), "lan_probs": result}},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"result": encodedText(string(inner))}, nil
+		},
+		defaultResult: func(Request) string {
+			return `{"cn": 1}`
+		},
+	})
+}
+
+// serveREST 校验签名与请求体，按脚本的回复写入 {"header": ..., "payload": ...}。
+func (e *Emulator) serveREST(w http.ResponseWriter, r *http.Request, svc restService) {
+	creds, ok := e.verifyHMAC(w, r)
+	if !ok {
+		return
+	}
+	sid := e.nextSID(svc.name)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "read body failed"})
+		return
+	}
+	var rr restRequest
+	if err := json.Unmarshal(body, &rr); err != nil {
+		writeHeader(w, codeInvalidJSON, "invalid json", sid)
+		return
+	}
+	if appIDMismatch(creds, rr.Header.AppID) {
+		writeHeader(w, codeInvalidAppID, "invalid appid", sid)
+		return
+	}
+	data, ok := rr.Payload[svc.input]
+	if !ok {
+		writeHeader(w, codeInvalidParams, fmt.Sprintf("payload.%s is required", svc.input), sid)
+		return
+	}
+	encoded := data.Image
+	if encoded == "" {
+		encoded = data.Text
+	}
+	input, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		writeHeader(w, codeInvalidBase64, "invalid base64", sid)
+		return
+	}
+
+	req := Request{Service: svc.name, AppID: rr.Header.AppID, Input: input, Params: make(map[string]string)}
+	for _, section := range rr.Parameter {
+		for key, value := range section {
+			if s, ok := value.(string); ok {
+				req.Params[key] = s
+			}
+		}
+	}
+	for key, item := range rr.Payload {
+		if key != svc.input && item.Text != "" {
+			if decoded, err := base64.StdEncoding.DecodeString(item.Text); err == nil {
+				req.Params[key] = string(decoded)
+			}
+		}
+	}
+
+	resp := e.respond(req)
+	if !sleep(r.Context(), resp.Latency) {
+		return
+	}
+	switch {
+	case resp.HTTPStatus != 0:
+		writeJSON(w, resp.HTTPStatus, map[string]string{"message": resp.Message})
+		return
+	case resp.Code != 0:
+		writeHeader(w, resp.Code, resp.Message, sid)
+		return
+	}
+
+	result := resp.Result
+	if result == "" {
+		result = svc.defaultResult(req)
+	}
+	payload, err := svc.payload(req, result)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"header":  map[string]any{"code": 0, "message": "success", "sid": sid, "status": 3},
+		"payload": payload,
+	})
+}
+
+// writeHeader 以 HTTP 200 与 header.code 回复业务错误，与线上服务一致。
+func writeHeader(w http.ResponseWriter, code int, message, sid string) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"header": map[string]any{"code": code, "message": message, "sid": sid},
+	})
+}
+
+// encodedText 返回 payload 中 base64 编码的 JSON 结果字段。
+func encodedText(text string) map[string]any {
+	return map[string]any{
+		"encoding": "utf8",
+		"compress": "raw",
+		"format":   "json",
+		"status":   3,
+		"text":     base64.StdEncoding.EncodeToString([]byte(text)),
+	}
+}
@@ -0,0 +1,111 @@
+package emulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// ttsChunkSize 是每个音频帧携带的最大字节数。
+const ttsChunkSize = 8192
+
+// ttsBytesPerRune 是默认音频中每个字符对应的字节数：16k 采样、16 位 PCM 下的 100ms 静音。
+const ttsBytesPerRune = 3200
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// ttsFrame 是客户端发送的帧，只有第一帧必须携带 common 与 business。
+type ttsFrame struct {
+	Common struct {
+		AppID string `json:"app_id"`
+	} `json:"common"`
+	Business map[string]any `json:"business"`
+	Data     struct {
+		Status int    `json:"status"`
+		Text   string `json:"text"`
+	} `json:"data"`
+}
+
+// serveTTS 在握手时校验签名，之后读取客户端的文本帧直到 data.status 为 2，再分帧返回音频。
+// 会话在握手之后才读取请求，因此 Response.HTTPStatus 对 tts 无效，握手阶段的拒绝由签名校验触发。
+func (e *Emulator) serveTTS(w http.ResponseWriter, r *http.Request) {
+	creds, ok := e.verifyHMAC(w, r)
+	if !ok {
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	sid := e.nextSID(ServiceTTS)
+
+	req := Request{Service: ServiceTTS, Params: make(map[string]string)}
+	for first := true; ; first = false {
+		var frame ttsFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				writeTTSError(conn, codeInvalidJSON, "invalid json", sid)
+			}
+			return
+		}
+		if first {
+			req.AppID = frame.Common.AppID
+			if appIDMismatch(creds, req.AppID) {
+				writeTTSError(conn, codeInvalidAppID, "invalid appid", sid)
+				return
+			}
+			for key, value := range frame.Business {
+				if s, ok := value.(string); ok {
+					req.Params[key] = s
+				}
+			}
+		}
+		text, err := base64.StdEncoding.DecodeString(frame.Data.Text)
+		if err != nil {
+			writeTTSError(conn, codeInvalidBase64, "invalid base64", sid)
+			return
+		}
+		req.Input = append(req.Input, text...)
+		if frame.Data.Status == 2 {
+			break
+		}
+	}
+
+	resp := e.respond(req)
+	if !sleep(r.Context(), resp.Latency) {
+		return
+	}
+	if resp.Code != 0 {
+		writeTTSError(conn, resp.Code, resp.Message, sid)
+		return
+	}
+
+	audio := []byte(resp.Result)
+	if len(audio) == 0 {
+		audio = make([]byte, ttsBytesPerRune*max(1, utf8.RuneCount(req.Input)))
+	}
+	for len(audio) > 0 {
+		n := min(len(audio), ttsChunkSize)
+		status := 1
+		if n == len(audio) {
+			status = 2
+		}
+		frame := map[string]any{
+			"code": 0, "message": "success", "sid": sid,
+			"data": map[string]any{"audio": base64.StdEncoding.EncodeToString(audio[:n]), "status": status, "ced": ""},
+		}
+		if err := conn.WriteJSON(frame); err != nil {
+			return
+		}
+		audio = audio[n:]
+	}
+}
+
+// writeTTSError 发送携带错误码的帧，与线上服务一致，之后由调用方关闭连接。
+func writeTTSError(conn *websocket.Conn, code int, message, sid string) {
+	_ = conn.WriteJSON(map[string]any{"code": code, "message": message, "sid": sid})
+}
@@ -0,0 +1,170 @@
+// Package endpoint 维护各服务在各区域的接入地址，取代散落在各客户端中的硬编码域名。
+//
+// 每个服务在每个区域可以登记多个地址：第一个为主地址，其余为备用地址。
+// 客户端在 NewClient 时从 Default 读取地址，主地址连接失败（拨号或 DNS 解析失败）时依次改用备用地址，见 Failover。
+//
+//	endpoint.Default.Register(endpoint.ServiceOCR, "backup", "https://ocr-a.example.com/v1/ocr", "https://ocr-b.example.com/v1/ocr")
+//	urls, err := endpoint.Default.Resolve(endpoint.ServiceOCR, "backup")
+//	client := ocr.NewClient(appID, apiKey, apiSecret, ocr.WithEndpoints(urls...))
+//
+// 私有化部署使用 Override 为服务指定地址，它优先于任何区域。
+package endpoint
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// 服务名，取值与 xfyunerr.APIError.Service 相同。
+const (
+	ServiceOCR            = "ocr"
+	ServiceLLMOCR         = "llmocr"
+	ServiceIOCRLD         = "iocrld"
+	ServiceTranslate      = "translate"
+	ServiceDetectLanguage = "detectlanguage"
+	ServiceIST            = "ist"
+	ServiceTTS            = "tts"
+)
+
+// Public 是讯飞公有云区域，客户端默认使用它。
+const Public = "public"
+
+// ErrNotFound 表示服务在所请求的区域没有登记地址。
+var ErrNotFound = errors.New("endpoint not found")
+
+// Registry 按服务与区域保存接入地址，并发安全。
+type Registry struct {
+	mu        sync.RWMutex
+	regions   map[string]map[string][]string // 服务 -> 区域 -> 地址
+	overrides map[string][]string            // 服务 -> 私有化部署地址
+}
+
+// NewRegistry 创建空的 Registry。
+func NewRegistry() *Registry {
+	return &Registry{
+		regions:   make(map[string]map[string][]string),
+		overrides: make(map[string][]string),
+	}
+}
+
+// Default 是客户端默认使用的 Registry，预先登记了各服务的公有云地址。
+var Default = newDefault()
+
+func newDefault() *Registry {
+	r := NewRegistry()
+	r.Register(ServiceOCR, Public, "https://cn-east-1.api.xf-yun.com/v1/ocr")
+	r.Register(ServiceLLMOCR, Public, "https://cbm01.cn-huabei-1.xf-yun.com/v1/private/se75ocrbm")
+	r.Register(ServiceIOCRLD, Public, "https://cn-huabei-1.xf-yun.com/v1/private/s15fc3900")
+	r.Register(ServiceTranslate, Public, "https://itrans.xf-yun.com/v1/its")
+	r.Register(ServiceDetectLanguage, Public, "https://cn-huadong-1.xf-yun.com/v1/private/s0ed5898e")
+	r.Register(ServiceIST, Public, "https://raasr.xfyun.cn/v2/api")
+	r.Register(ServiceTTS, Public, "wss://tts-api.xfyun.cn/v2/tts")
+	return r
+}
+
+// Register 登记 service 在 region 的地址，替换之前登记的地址；urls 为空时删除登记。
+func (r *Registry) Register(service, region string, urls ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(urls) == 0 {
+		delete(r.regions[service], region)
+		return
+	}
+	if r.regions[service] == nil {
+		r.regions[service] = make(map[string][]string)
+	}
+	r.regions[service][region] = slices.Clone(urls)
+}
+
+// Override 为 service 指定私有化部署的地址，它优先于所有区域；urls 为空时取消覆盖。
+func (r *Registry) Override(service string, urls ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(urls) == 0 {
+		delete(r.overrides, service)
+		return
+	}
+	r.overrides[service] = slices.Clone(urls)
+}
+
+// Resolve 返回 service 在 region 的地址，第一个为主地址。
+// 设置了 Override 时返回覆盖的地址；region 为空时使用 Public。
+func (r *Registry) Resolve(service, region string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if urls, ok := r.overrides[service]; ok {
+		return slices.Clone(urls), nil
+	}
+	if region == "" {
+		region = Public
+	}
+	urls, ok := r.regions[service][region]
+	if !ok {
+		return nil, fmt.Errorf("%s in region %q: %w", service, region, ErrNotFound)
+	}
+	return slices.Clone(urls), nil
+}
+
+// Lookup 返回 service 在公有云（或 Override）的主地址与备用地址，未登记时返回空字符串。
+// 客户端在 NewClient 时用它确定默认地址。
+func (r *Registry) Lookup(service string) (primary string, fallbacks []string) {
+	urls, _ := r.Resolve(service, Public)
+	if len(urls) == 0 {
+		return "", nil
+	}
+	return urls[0], urls[1:]
+}
+
+// Regions 返回已登记的区域名，按字母排序。
+func (r *Registry) Regions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var regions []string
+	for _, byRegion := range r.regions {
+		for region := range byRegion {
+			if !slices.Contains(regions, region) {
+				regions = append(regions, region)
+			}
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// Failover 依次以 urls 中的地址调用 fn，直到成功或返回的不是连接错误（见 IsConnectError）。
+// 只有连接错误才改用下一个地址，此时请求尚未送达服务端，重发不会造成重复处理。
+// urls 为空时返回 ErrNotFound。
+func Failover[T any](logger *slog.Logger, urls []string, fn func(url string) (T, error)) (T, error) {
+	var (
+		v   T
+		err error
+	)
+	if len(urls) == 0 {
+		return v, ErrNotFound
+	}
+	for i, url := range urls {
+		if v, err = fn(url); err == nil || !IsConnectError(err) || i == len(urls)-1 {
+			return v, err
+		}
+		if logger != nil {
+			logger.Warn("endpoint unreachable, failing over", "url", url, "next", urls[i+1], "error", err)
+		}
+	}
+	return v, err
+}
+
+// IsConnectError 判断 err 是否是建立连接阶段的错误（拨号失败、连接被拒绝、DNS 解析失败），
+// 此类错误发生时请求尚未发出。
+func IsConnectError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
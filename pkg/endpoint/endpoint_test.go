@@ -0,0 +1,98 @@
+package endpoint
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestRegistry_Resolve(t *testing.T) {
+	r := NewRegistry()
+	r.Register(ServiceOCR, Public, "https://a/v1/ocr")
+	r.Register(ServiceOCR, "backup", "https://b/v1/ocr", "https://c/v1/ocr")
+
+	if urls, err := r.Resolve(ServiceOCR, ""); err != nil || !slices.Equal(urls, []string{"https://a/v1/ocr"}) {
+		t.Errorf("Expected the public endpoint for an empty region, got %v, %v", urls, err)
+	}
+	if primary, fallbacks := r.Lookup(ServiceOCR); primary != "https://a/v1/ocr" || len(fallbacks) != 0 {
+		t.Errorf("Unexpected lookup: %q %v", primary, fallbacks)
+	}
+	if urls, _ := r.Resolve(ServiceOCR, "backup"); len(urls) != 2 {
+		t.Errorf("Expected 2 endpoints, got %v", urls)
+	}
+	if _, err := r.Resolve(ServiceTTS, Public); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if got := r.Regions(); !slices.Equal(got, []string{"backup", Public}) {
+		t.Errorf("Unexpected regions: %v", got)
+	}
+
+	// 私有化部署优先于任何区域
+	r.Override(ServiceOCR, "http://private/v1/ocr", "http://private2/v1/ocr")
+	if primary, fallbacks := r.Lookup(ServiceOCR); primary != "http://private/v1/ocr" || len(fallbacks) != 1 {
+		t.Errorf("Expected the override, got %q %v", primary, fallbacks)
+	}
+	if urls, _ := r.Resolve(ServiceOCR, "backup"); urls[0] != "http://private/v1/ocr" {
+		t.Errorf("Expected the override for every region, got %v", urls)
+	}
+	r.Override(ServiceOCR)
+	if primary, _ := r.Lookup(ServiceOCR); primary != "https://a/v1/ocr" {
+		t.Errorf("Expected the override to be removed, got %q", primary)
+	}
+}
+
+func TestDefault_AllServices(t *testing.T) {
+	for _, service := range []string{ServiceOCR, ServiceLLMOCR, ServiceIOCRLD, ServiceTranslate, ServiceDetectLanguage, ServiceIST, ServiceTTS} {
+		if primary, _ := Default.Lookup(service); primary == "" {
+			t.Errorf("Expected a public endpoint for %s", service)
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	dialErr := fmt.Errorf("send request failed: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+
+	var tried []string
+	got, err := Failover(nil, []string{"a", "b", "c"}, func(url string) (string, error) {
+		tried = append(tried, url)
+		if url == "a" {
+			return "", dialErr
+		}
+		return url, nil
+	})
+	if err != nil || got != "b" || !slices.Equal(tried, []string{"a", "b"}) {
+		t.Errorf("Expected to fail over to b, got %q %v (tried %v)", got, err, tried)
+	}
+
+	// 请求可能已送达时不切换地址
+	tried = nil
+	_, err = Failover(nil, []string{"a", "b"}, func(url string) (string, error) {
+		tried = append(tried, url)
+		return "", readErr
+	})
+	if !errors.Is(err, readErr) || len(tried) != 1 {
+		t.Errorf("Expected no failover on a read error, got %v (tried %v)", err, tried)
+	}
+
+	// 所有地址都不可达时返回最后一个错误
+	_, err = Failover(nil, []string{"a", "b"}, func(url string) (string, error) { return "", dialErr })
+	if !IsConnectError(err) {
+		t.Errorf("Expected the connection error, got %v", err)
+	}
+
+	if _, err := Failover(nil, nil, func(url string) (string, error) { return url, nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for no endpoints, got %v", err)
+	}
+}
+
+func TestIsConnectError(t *testing.T) {
+	if !IsConnectError(&net.DNSError{Err: "no such host", Name: "x"}) {
+		t.Error("Expected a DNS error to be a connection error")
+	}
+	if IsConnectError(errors.New("timeout")) || IsConnectError(nil) {
+		t.Error("Expected other errors not to be connection errors")
+	}
+}
@@ -0,0 +1,229 @@
+// Package fakegateway 提供一个基于 httptest 的本地讯飞网关，用于离线测试客户端。
+//
+// 网关会像真实服务一样校验请求签名：HMAC 签名错误时返回与讯飞网关相同的 HTTP 状态码和
+// {"message": ...}，ist 的 signa 错误时返回 LFASR 的业务错误码。校验通过的请求才会交给
+// 注册的 handler 处理。
+package fakegateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+)
+
+// DefaultCredentials 是未指定 WithCredentials 时网关认可的凭证。
+var DefaultCredentials = auth.Credentials{
+	AppID:     "test-app-id",
+	APIKey:    "test-api-key",
+	APISecret: "test-api-secret",
+	SecretKey: "test-secret-key",
+}
+
+// 网关返回的业务错误码。
+const (
+	// CodeInvalidAppID 是 HMAC 类接口中 header.app_id 与签名所用 APIKey 不属于同一应用时的错误码。
+	CodeInvalidAppID = 10313
+
+	// CodeIllegalApp 是 ist 接口 signa 校验失败（appId 不存在、ts 过期、签名错误）时的错误码。
+	CodeIllegalApp = "26601"
+
+	// CodeInvalidParams 是 ist 接口缺少 appId/ts/signa 时的错误码。
+	CodeInvalidParams = "26610"
+)
+
+// Scheme 是路由使用的鉴权方式。
+type Scheme int
+
+const (
+	// SchemeHMAC 校验 host/date/authorization 签名（URL 查询参数或请求头）。
+	SchemeHMAC Scheme = iota
+
+	// SchemeSigna 校验 ist 的 appId/ts/signa 查询参数。
+	SchemeSigna
+)
+
+type route struct {
+	scheme  Scheme
+	handler http.Handler
+}
+
+// Gateway 是本地假网关，内嵌的 *httptest.Server 提供 URL、Client、Close 等方法。
+type Gateway struct {
+	*httptest.Server
+
+	creds        []auth.Credentials
+	verifierOpts []auth.VerifierOption
+	tls          bool
+	verifier     *auth.Verifier
+
+	mu     sync.RWMutex
+	routes map[string]route
+}
+
+// Option is a function that configures a Gateway.
+type Option func(*Gateway)
+
+// WithCredentials sets the credentials accepted by the gateway, replacing DefaultCredentials.
+func WithCredentials(creds ...auth.Credentials) Option {
+	return func(g *Gateway) {
+		g.creds = append(g.creds, creds...)
+	}
+}
+
+// WithVerifierOptions passes options such as auth.WithVerifierClock to the signature verifier.
+func WithVerifierOptions(opts ...auth.VerifierOption) Option {
+	return func(g *Gateway) {
+		g.verifierOpts = append(g.verifierOpts, opts...)
+	}
+}
+
+// WithTLS starts the gateway with a self-signed certificate; use Gateway.Client() to talk to it.
+func WithTLS() Option {
+	return func(g *Gateway) {
+		g.tls = true
+	}
+}
+
+// New 创建并启动网关，使用完毕后需调用 Close。
+func New(opts ...Option) *Gateway {
+	g := &Gateway{routes: make(map[string]route)}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if len(g.creds) == 0 {
+		g.creds = []auth.Credentials{DefaultCredentials}
+	}
+	g.verifier = auth.NewVerifier(g.creds, g.verifierOpts...)
+
+	if g.tls {
+		g.Server = httptest.NewTLSServer(g)
+	} else {
+		g.Server = httptest.NewServer(g)
+	}
+	return g
+}
+
+// Handle 在 path 上注册 handler，请求需先通过 scheme 对应的签名校验。
+func (g *Gateway) Handle(path string, scheme Scheme, handler http.Handler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.routes[path] = route{scheme: scheme, handler: handler}
+}
+
+// HandleHMAC 在 path 上注册使用 HMAC 签名的 handler。
+func (g *Gateway) HandleHMAC(path string, handler http.Handler) {
+	g.Handle(path, SchemeHMAC, handler)
+}
+
+// HandleSigna 在 path 上注册使用 ist signa 签名的 handler。
+func (g *Gateway) HandleSigna(path string, handler http.Handler) {
+	g.Handle(path, SchemeSigna, handler)
+}
+
+// ServeHTTP 实现 http.Handler。
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.RLock()
+	rt, ok := g.routes[r.URL.Path]
+	g.mu.RUnlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "no Route matched with those values"})
+		return
+	}
+
+	var (
+		creds auth.Credentials
+		err   error
+	)
+	switch rt.scheme {
+	case SchemeSigna:
+		creds, err = g.verifier.VerifySigna(r)
+		if err != nil {
+			writeSignaError(w, err)
+			return
+		}
+	default:
+		creds, err = g.verifier.Verify(r)
+		if err != nil {
+			writeHMACError(w, err)
+			return
+		}
+		if !checkAppID(w, r, creds) {
+			return
+		}
+	}
+
+	rt.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), credentialsKey{}, creds)))
+}
+
+type credentialsKey struct{}
+
+// CredentialsFromContext 返回通过签名校验的请求所对应的凭证，供 handler 使用。
+func CredentialsFromContext(ctx context.Context) (auth.Credentials, bool) {
+	creds, ok := ctx.Value(credentialsKey{}).(auth.Credentials)
+	return creds, ok
+}
+
+// JSON 返回一个以 200 状态码输出 v 的 JSON 编码的 handler。
+func JSON(v any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, v)
+	})
+}
+
+func writeHMACError(w http.ResponseWriter, err error) {
+	status, message := http.StatusUnauthorized, "HMAC signature cannot be verified"
+	var verr *auth.VerifyError
+	if errors.As(err, &verr) {
+		status, message = verr.StatusCode, verr.Message
+	}
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeSignaError(w http.ResponseWriter, err error) {
+	code, desc := CodeIllegalApp, "非法应用信息"
+	if errors.Is(err, auth.ErrMissingAuthorization) {
+		code, desc = CodeInvalidParams, "请求参数错误"
+	}
+	// LFASR 以 HTTP 200 + 业务码报告鉴权失败
+	writeJSON(w, http.StatusOK, map[string]any{"code": code, "descInfo": desc, "content": nil})
+}
+
+// checkAppID 校验 JSON 请求体中的 header.app_id 与签名凭证属于同一应用。
+// 请求体会被还原，handler 仍可完整读取。
+func checkAppID(w http.ResponseWriter, r *http.Request, creds auth.Credentials) bool {
+	if r.Body == nil || r.Method != http.MethodPost {
+		return true
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "read body failed"})
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Header struct {
+			AppID *string `json:"app_id"`
+		} `json:"header"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Header.AppID == nil || *req.Header.AppID == creds.AppID {
+		return true
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"header": map[string]any{"code": CodeInvalidAppID, "message": "invalid appid", "sid": "fakegateway"},
+	})
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
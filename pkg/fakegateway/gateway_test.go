@@ -0,0 +1,135 @@
+package fakegateway
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ist"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+const ocrPath = "/v1/ocr"
+
+func ocrSuccess() http.Handler {
+	return JSON(map[string]any{
+		"header":  map[string]any{"code": 0, "message": "success", "sid": "sid-ok"},
+		"payload": map[string]any{"result": map[string]any{"text": base64.StdEncoding.EncodeToString([]byte(`{"pages":[]}`))}},
+	})
+}
+
+func newOCRClient(g *Gateway, creds auth.Credentials, opts ...ocr.Option) *ocr.Client {
+	opts = append([]ocr.Option{ocr.WithHost(g.URL + ocrPath)}, opts...)
+	return ocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+}
+
+func TestGateway_HMAC(t *testing.T) {
+	g := New()
+	defer g.Close()
+	g.HandleHMAC(ocrPath, ocrSuccess())
+
+	wrongSecret := DefaultCredentials
+	wrongSecret.APISecret = "wrong"
+	wrongApp := DefaultCredentials
+	wrongApp.AppID = "other-app"
+
+	tests := []struct {
+		name    string
+		client  *ocr.Client
+		wantErr string
+	}{
+		{name: "query mode", client: newOCRClient(g, DefaultCredentials)},
+		{name: "header mode", client: newOCRClient(g, DefaultCredentials, ocr.WithAuthMode(auth.ModeHeader))},
+		{name: "wrong secret", client: newOCRClient(g, wrongSecret), wantErr: "HMAC signature does not match"},
+		{
+			name:    "stale clock",
+			client:  newOCRClient(g, DefaultCredentials, ocr.WithSigner(auth.NewSigner(auth.WithSkew(-time.Hour)))),
+			wantErr: "a valid date or x-date header is required",
+		},
+		{name: "app id mismatch", client: newOCRClient(g, wrongApp), wantErr: "code=10313"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.client.RecognizeBase64(context.Background(), base64.StdEncoding.EncodeToString([]byte("img")), "jpg", "ch_en")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if resp.Header.Sid != "sid-ok" {
+					t.Errorf("Expected sid 'sid-ok', got '%s'", resp.Header.Sid)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing '%s', got %v", tt.wantErr, err)
+			}
+			if !errors.Is(err, xfyunerr.ErrAuth) {
+				t.Errorf("Expected errors.Is(err, xfyunerr.ErrAuth), got %v", err)
+			}
+		})
+	}
+}
+
+func TestGateway_HMACStatusCodes(t *testing.T) {
+	g := New()
+	defer g.Close()
+	g.HandleHMAC(ocrPath, ocrSuccess())
+
+	resp, err := http.Post(g.URL+ocrPath, "application/json", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for unsigned request, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(g.URL+"/v1/unknown", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown route, got %d", resp.StatusCode)
+	}
+}
+
+func TestGateway_Signa(t *testing.T) {
+	g := New()
+	defer g.Close()
+	g.HandleSigna("/v2/api/upload", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds, _ := CredentialsFromContext(r.Context())
+		writeJSON(w, http.StatusOK, map[string]any{
+			"code": "000000", "descInfo": "success",
+			"content": map[string]any{"orderId": "order-" + creds.AppID},
+		})
+	}))
+
+	audio := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(audio, []byte("RIFF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := ist.NewClient(DefaultCredentials.AppID, DefaultCredentials.SecretKey, ist.WithHost(g.URL+"/v2/api"))
+	orderID, err := client.UploadFile(context.Background(), audio)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if orderID != "order-test-app-id" {
+		t.Errorf("Expected order id 'order-test-app-id', got '%s'", orderID)
+	}
+
+	bad := ist.NewClient(DefaultCredentials.AppID, "wrong", ist.WithHost(g.URL+"/v2/api"))
+	if _, err := bad.UploadFile(context.Background(), audio); err == nil || !strings.Contains(err.Error(), CodeIllegalApp) {
+		t.Fatalf("Expected error containing code %s, got %v", CodeIllegalApp, err)
+	}
+}
@@ -0,0 +1,84 @@
+package imageprep
+
+import (
+	"image"
+	"math"
+)
+
+// Affine 是二维仿射变换 (x, y) → (A·x + B·y + C, D·x + E·y + F)。
+// 坐标以像素边缘为准：(0, 0) 是左上角像素的左上角，(w, h) 是右下角像素的右下角。
+type Affine struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// Identity 是恒等变换。
+var Identity = Affine{A: 1, E: 1}
+
+// Apply 返回 (x, y) 变换后的坐标。
+func (m Affine) Apply(x, y float64) (float64, float64) {
+	return m.A*x + m.B*y + m.C, m.D*x + m.E*y + m.F
+}
+
+// ApplyPoint 返回 p 变换后四舍五入到整数的坐标。
+func (m Affine) ApplyPoint(p image.Point) image.Point {
+	x, y := m.Apply(float64(p.X), float64(p.Y))
+	return image.Pt(int(math.Round(x)), int(math.Round(y)))
+}
+
+// Then 返回先做 m、再做 n 的变换。
+func (m Affine) Then(n Affine) Affine {
+	return Affine{
+		A: n.A*m.A + n.B*m.D, B: n.A*m.B + n.B*m.E, C: n.A*m.C + n.B*m.F + n.C,
+		D: n.D*m.A + n.E*m.D, E: n.D*m.B + n.E*m.E, F: n.D*m.C + n.E*m.F + n.F,
+	}
+}
+
+// Invert 返回逆变换；m 不可逆（例如缩放为 0）时返回 Identity。
+func (m Affine) Invert() Affine {
+	det := m.A*m.E - m.B*m.D
+	if det == 0 {
+		return Identity
+	}
+	a, b, d, e := m.E/det, -m.B/det, -m.D/det, m.A/det
+	return Affine{A: a, B: b, C: -(a*m.C + b*m.F), D: d, E: e, F: -(d*m.C + e*m.F)}
+}
+
+func translate(dx, dy float64) Affine {
+	return Affine{A: 1, C: dx, E: 1, F: dy}
+}
+
+func scale(sx, sy float64) Affine {
+	return Affine{A: sx, E: sy}
+}
+
+// orientationTransform 返回 EXIF 方向 o 的校正（与 imaging 的 fixOrientation 相同）把 w×h 图片上的坐标
+// 映射到校正后图片上的变换。
+func orientationTransform(o, w, h int) Affine {
+	fw, fh := float64(w), float64(h)
+	switch o {
+	case 2: // FlipH
+		return Affine{A: -1, C: fw, E: 1}
+	case 3: // Rotate180
+		return Affine{A: -1, C: fw, E: -1, F: fh}
+	case 4: // FlipV
+		return Affine{A: 1, E: -1, F: fh}
+	case 5: // Transpose
+		return Affine{B: 1, D: 1}
+	case 6: // Rotate270，顺时针 90 度
+		return Affine{B: -1, C: fh, D: 1}
+	case 7: // Transverse
+		return Affine{B: -1, C: fh, D: -1, F: fw}
+	case 8: // Rotate90，逆时针 90 度
+		return Affine{B: 1, D: -1, F: fw}
+	}
+	return Identity
+}
+
+// rotationTransform 返回 imaging.Rotate(img, angle, bg) 把 w×h 图片上的坐标映射到 dw×dh 的结果上的变换：
+// 绕中心逆时针旋转 angle 度，再移到新画布的中心。
+func rotationTransform(angle float64, w, h, dw, dh int) Affine {
+	sin, cos := math.Sincos(math.Pi * angle / 180)
+	rot := Affine{A: cos, B: sin, D: -sin, E: cos}
+	return translate(-float64(w)/2, -float64(h)/2).Then(rot).Then(translate(float64(dw)/2, float64(dh)/2))
+}
@@ -0,0 +1,71 @@
+package imageprep
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// exifOrientation 返回 JPEG 中 EXIF 的方向标签（1~8），没有或无法解析时返回 1。
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // 填充字节
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // 图像数据开始，之后不再有 EXIF
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation 在 EXIF 的 TIFF 结构中读取 IFD0 的方向标签（0x0112）。
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := uint64(order.Uint32(b[4:]))
+	if offset+2 > uint64(len(b)) {
+		return 1
+	}
+	n := int(order.Uint16(b[offset:]))
+	for k := 0; k < n; k++ {
+		entry := int(offset) + 2 + 12*k
+		if entry+12 > len(b) {
+			return 1
+		}
+		if order.Uint16(b[entry:]) != 0x0112 {
+			continue
+		}
+		if o := int(order.Uint16(b[entry+8:])); o >= 1 && o <= 8 {
+			return o
+		}
+		return 1
+	}
+	return 1
+}
@@ -0,0 +1,305 @@
+// Package imageprep 在识别前预处理图片，提高手机拍摄文档的识别准确率。
+//
+// Pipeline 依次执行（每一步都可以关闭）：
+//  1. 按 EXIF 方向标签旋转（手机竖拍的照片通常以横向存储）；
+//  2. 用投影法估计文字行的倾斜角并旋转校正；
+//  3. 裁掉四周的空白与拍照时的桌面等大面积边框；
+//  4. 拉伸对比度；
+//  5. 把长边缩小到 MaxDimension 以内；
+//  6. 可选的局部阈值二值化；
+//  7. 超过 MaxBytes 时降低 JPEG 质量并缩小。
+//
+// 结果中的 Info 记录了每一步以及从原图到处理后图片的坐标变换，识别结果中的坐标可以据此映射回原图：
+//
+//	res, err := imageprep.Default().Process(data)
+//	x, y := res.Info.ToOriginal(float64(p.X), float64(p.Y))
+//
+// ocr.WithPreprocess 与 llmocr.WithPreprocess 在每次识别前自动执行 Pipeline，
+// ocr.OcrResponse.OriginalResult 返回坐标已映射回原图的识别结果。
+package imageprep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// DefaultMaxSkew 是 MaxSkew 小于等于 0 时搜索的最大倾斜角（度）。
+	DefaultMaxSkew = 15
+
+	// MaxSkewLimit 是 MaxSkew 的上限（度），更大的值按上限处理。
+	MaxSkewLimit = 45
+
+	// DefaultMaxDimension 是 Default 的长边上限（像素）。
+	DefaultMaxDimension = 4096
+
+	// DefaultJPEGQuality 是 JPEGQuality 小于等于 0 时的 JPEG 编码质量。
+	DefaultJPEGQuality = 90
+)
+
+// 处理步骤的名称，按执行顺序记录在 Info.Steps 中。
+const (
+	StepOrient    = "orient"
+	StepDeskew    = "deskew"
+	StepCrop      = "crop"
+	StepNormalize = "normalize"
+	StepResize    = "resize"
+	StepBinarize  = "binarize"
+	StepCompress  = "compress"
+)
+
+// ErrTooLarge 表示 MaxBytes 太小，缩小到最小尺寸后仍然超出。
+var ErrTooLarge = errors.New("imageprep: cannot fit image within MaxBytes")
+
+// Pipeline 是预处理的配置，零值不做任何处理（JPEG 与 PNG 原样返回，其他格式转为 JPEG）。可以并发使用。
+type Pipeline struct {
+	// AutoOrient 按 JPEG 的 EXIF 方向标签旋转或翻转图片。
+	AutoOrient bool
+
+	// Deskew 估计文字行的倾斜角并旋转校正，MaxSkew 是搜索的最大角度（度），小于等于 0 时为 DefaultMaxSkew，
+	// 大于 MaxSkewLimit 时为 MaxSkewLimit。
+	Deskew  bool
+	MaxSkew float64
+
+	// CropBorders 裁掉四周的空白与大面积深色边框，保留 1% 边长的留白。
+	CropBorders bool
+
+	// Normalize 把最暗与最亮的 0.5% 像素之外的灰度线性拉伸到满幅。
+	Normalize bool
+
+	// Binarize 以局部均值阈值二值化，输出黑白 PNG。适合光照不均的文档照片，但会丢失颜色与灰度信息，
+	// 大模型识别（llmocr）通常不需要。
+	Binarize bool
+
+	// MaxDimension 是输出的长边上限（像素），小于等于 0 时不限制。
+	MaxDimension int
+
+	// MaxBytes 是输出的字节数上限，超出时以 JPEG 逐步降低质量并缩小，小于等于 0 时不限制。
+	MaxBytes int64
+
+	// JPEGQuality 是输出 JPEG 的质量，小于等于 0 时为 DefaultJPEGQuality。
+	JPEGQuality int
+}
+
+// Default 返回适合手机拍摄文档的配置：方向、倾斜、边框校正与对比度拉伸，长边不超过 DefaultMaxDimension，不二值化。
+func Default() *Pipeline {
+	return &Pipeline{
+		AutoOrient:   true,
+		Deskew:       true,
+		CropBorders:  true,
+		Normalize:    true,
+		MaxDimension: DefaultMaxDimension,
+	}
+}
+
+// Info 记录预处理做了什么，以及如何把处理后图片上的坐标映射回原图。
+type Info struct {
+	OriginalSize image.Point     // 原图（按存储方向）的宽高
+	Size         image.Point     // 处理后图片的宽高
+	Orientation  int             // 应用的 EXIF 方向，1 表示未旋转
+	Skew         float64         // 为校正倾斜逆时针旋转的角度（度），0 表示未旋转
+	Crop         image.Rectangle // 在方向与倾斜校正后的图片上裁剪的矩形，空矩形表示未裁剪
+	Scale        float64         // 缩小比例（处理后 / 裁剪后），1 表示未缩放
+	Steps        []string        // 实际执行的步骤，见 StepOrient 等
+
+	// Transform 把原图上的坐标映射到处理后的图片上，ToOriginal 使用其逆变换。
+	Transform Affine
+}
+
+// ToOriginal 把处理后图片上的坐标映射回原图。
+func (i *Info) ToOriginal(x, y float64) (float64, float64) {
+	return i.Transform.Invert().Apply(x, y)
+}
+
+// ToOriginalPoint 把处理后图片上的点映射回原图，结果四舍五入并限制在原图范围内。
+func (i *Info) ToOriginalPoint(p image.Point) image.Point {
+	q := i.Transform.Invert().ApplyPoint(p)
+	q.X = min(max(q.X, 0), i.OriginalSize.X)
+	q.Y = min(max(q.Y, 0), i.OriginalSize.Y)
+	return q
+}
+
+// Result 是预处理后的图片。
+type Result struct {
+	Data   []byte // 编码后的图片
+	Format string // "jpg" 或 "png"，可直接作为识别服务的图片编码
+	Info   Info
+}
+
+// Process 解码 data（JPEG、PNG、GIF、BMP 或 TIFF）并按配置处理。没有任何步骤生效、且原图是 JPEG 或 PNG
+// 并满足 MaxBytes 时原样返回 data，避免重新编码的损失。
+func (p *Pipeline) Process(data []byte) (*Result, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imageprep: decode image: %w", err)
+	}
+	b := img.Bounds()
+	info := Info{
+		OriginalSize: image.Pt(b.Dx(), b.Dy()),
+		Orientation:  1,
+		Scale:        1,
+		Transform:    translate(-float64(b.Min.X), -float64(b.Min.Y)),
+	}
+
+	if p.AutoOrient {
+		if o := exifOrientation(data); o != 1 {
+			info.Transform = info.Transform.Then(orientationTransform(o, b.Dx(), b.Dy()))
+			img = fixOrientation(img, o)
+			info.Orientation = o
+			info.Steps = append(info.Steps, StepOrient)
+		}
+	}
+
+	if p.Deskew {
+		maxSkew := p.MaxSkew
+		switch {
+		case !(maxSkew > 0): // 包括 NaN
+			maxSkew = DefaultMaxSkew
+		case maxSkew > MaxSkewLimit:
+			maxSkew = MaxSkewLimit
+		}
+		g, _ := analysisGray(img)
+		if angle := estimateSkew(g, maxSkew); angle != 0 {
+			w, h := img.Bounds().Dx(), img.Bounds().Dy()
+			img = imaging.Rotate(img, angle, borderGray(g))
+			info.Transform = info.Transform.Then(rotationTransform(angle, w, h, img.Bounds().Dx(), img.Bounds().Dy()))
+			info.Skew = angle
+			info.Steps = append(info.Steps, StepDeskew)
+		}
+	}
+
+	if p.CropBorders {
+		g, f := analysisGray(img)
+		if crop := cropRect(contentBounds(g), f, img.Bounds()); crop != img.Bounds() {
+			img = imaging.Crop(img, crop)
+			info.Transform = info.Transform.Then(translate(-float64(crop.Min.X), -float64(crop.Min.Y)))
+			info.Crop = crop
+			info.Steps = append(info.Steps, StepCrop)
+		}
+	}
+
+	if p.Normalize {
+		g, _ := analysisGray(img)
+		if lo, hi, ok := stretchLevels(g); ok {
+			img = stretch(img, lo, hi)
+			info.Steps = append(info.Steps, StepNormalize)
+		}
+	}
+
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); p.MaxDimension > 0 && max(w, h) > p.MaxDimension {
+		img = imaging.Fit(img, p.MaxDimension, p.MaxDimension, imaging.Lanczos)
+		info.Transform = info.Transform.Then(scale(float64(img.Bounds().Dx())/float64(w), float64(img.Bounds().Dy())/float64(h)))
+		info.Scale = float64(max(img.Bounds().Dx(), img.Bounds().Dy())) / float64(max(w, h))
+		info.Steps = append(info.Steps, StepResize)
+	}
+
+	if p.Binarize {
+		img = binarize(img)
+		info.Steps = append(info.Steps, StepBinarize)
+	}
+
+	res := &Result{Info: info}
+	res.Info.Size = image.Pt(img.Bounds().Dx(), img.Bounds().Dy())
+	if len(info.Steps) == 0 && (format == "jpeg" || format == "png") {
+		res.Data, res.Format = data, map[string]string{"jpeg": "jpg", "png": "png"}[format]
+	} else if err := p.encode(res, img, format == "png"); err != nil {
+		return nil, err
+	}
+	if p.MaxBytes > 0 && int64(len(res.Data)) > p.MaxBytes {
+		if err := p.compress(res, img); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// encode 以 PNG 编码二值化的图片与原图为 PNG 的图片（截图等），其余以 JPEG 编码。
+func (p *Pipeline) encode(res *Result, img image.Image, lossless bool) error {
+	format, opts := imaging.JPEG, []imaging.EncodeOption{imaging.JPEGQuality(p.quality())}
+	res.Format = "jpg"
+	if p.Binarize || lossless {
+		format, res.Format = imaging.PNG, "png"
+	}
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, opts...); err != nil {
+		return fmt.Errorf("imageprep: encode image: %w", err)
+	}
+	res.Data = buf.Bytes()
+	return nil
+}
+
+// compress 以 JPEG 逐步降低质量，仍然超出 MaxBytes 时每次缩小到 80%，直到长边不足 256 像素。
+func (p *Pipeline) compress(res *Result, img image.Image) error {
+	for {
+		for _, q := range []int{p.quality(), 75, 60, 45} {
+			if q > p.quality() {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := imaging.Encode(&buf, img, imaging.JPEG, imaging.JPEGQuality(q)); err != nil {
+				return fmt.Errorf("imageprep: encode image: %w", err)
+			}
+			if int64(buf.Len()) <= p.MaxBytes {
+				res.Data, res.Format = buf.Bytes(), "jpg"
+				res.Info.Steps = append(res.Info.Steps, StepCompress)
+				res.Info.Size = image.Pt(img.Bounds().Dx(), img.Bounds().Dy())
+				return nil
+			}
+		}
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		if max(w, h) < 256 {
+			return ErrTooLarge
+		}
+		img = imaging.Resize(img, max(1, w*4/5), max(1, h*4/5), imaging.Lanczos)
+		nw, nh := img.Bounds().Dx(), img.Bounds().Dy()
+		res.Info.Transform = res.Info.Transform.Then(scale(float64(nw)/float64(w), float64(nh)/float64(h)))
+		res.Info.Scale *= float64(max(nw, nh)) / float64(max(w, h))
+	}
+}
+
+func (p *Pipeline) quality() int {
+	if p.JPEGQuality <= 0 {
+		return DefaultJPEGQuality
+	}
+	return p.JPEGQuality
+}
+
+// cropRect 把缩小后图片上的内容矩形放大回 bounds 的坐标，四周留出 1% 边长（至少 4 像素）的空白。
+// 内容不足原图面积的 5% 时视为误判，返回 bounds。
+func cropRect(content image.Rectangle, f float64, bounds image.Rectangle) image.Rectangle {
+	r := image.Rect(
+		int(math.Floor(float64(content.Min.X)/f)), int(math.Floor(float64(content.Min.Y)/f)),
+		int(math.Ceil(float64(content.Max.X)/f)), int(math.Ceil(float64(content.Max.Y)/f)),
+	)
+	pad := max(4, max(bounds.Dx(), bounds.Dy())/100)
+	r = image.Rect(r.Min.X-pad, r.Min.Y-pad, r.Max.X+pad, r.Max.Y+pad).Intersect(bounds)
+	if r.Dx()*r.Dy()*20 < bounds.Dx()*bounds.Dy() {
+		return bounds
+	}
+	return r
+}
+
+func fixOrientation(img image.Image, o int) image.Image {
+	switch o {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
@@ -0,0 +1,240 @@
+package imageprep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+var red = color.NRGBA{R: 255, A: 255}
+
+// findRed 返回图片中红色像素的中心（像素边缘坐标），没有时 ok 为 false。
+func findRed(img image.Image) (x, y float64, ok bool) {
+	var n float64
+	b := img.Bounds()
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			r, g, bl, _ := img.At(px, py).RGBA()
+			if r > 0x9000 && g < 0x7000 && bl < 0x7000 {
+				x += float64(px-b.Min.X) + 0.5
+				y += float64(py-b.Min.Y) + 0.5
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+	return x / n, y / n, true
+}
+
+func TestOrientationTransform(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	src.Set(1, 0, red)
+	for o := 1; o <= 8; o++ {
+		x, y, ok := findRed(fixOrientation(src, o))
+		if !ok {
+			t.Fatalf("orientation %d: marker lost", o)
+		}
+		wx, wy := orientationTransform(o, 5, 3).Apply(1.5, 0.5)
+		if x != wx || y != wy {
+			t.Errorf("orientation %d: expected the marker at (%v, %v), got (%v, %v)", o, wx, wy, x, y)
+		}
+	}
+}
+
+func TestRotationTransform(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 80, 60))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(10, 8, 14, 12), image.NewUniform(red), image.Point{}, draw.Src)
+	for _, angle := range []float64{-12, -3.5, 2, 9} {
+		dst := imaging.Rotate(src, angle, color.White)
+		x, y, ok := findRed(dst)
+		if !ok {
+			t.Fatalf("%v: marker lost", angle)
+		}
+		m := rotationTransform(angle, 80, 60, dst.Bounds().Dx(), dst.Bounds().Dy())
+		wx, wy := m.Apply(12, 10)
+		if math.Hypot(x-wx, y-wy) > 0.5 {
+			t.Errorf("%v: expected the marker at (%.1f, %.1f), got (%.1f, %.1f)", angle, wx, wy, x, y)
+		}
+		if bx, by := m.Invert().Apply(wx, wy); math.Hypot(bx-12, by-10) > 1e-9 {
+			t.Errorf("%v: inverse maps back to (%v, %v)", angle, bx, by)
+		}
+	}
+}
+
+// documentPhoto 模拟手机拍摄的文档：白纸上有若干行“文字”与一个红色标记，逆时针倾斜 skew 度后放在深色桌面上。
+func documentPhoto(skew float64) *image.NRGBA {
+	page := image.NewNRGBA(image.Rect(0, 0, 600, 440))
+	draw.Draw(page, page.Bounds(), image.NewUniform(color.Gray{Y: 230}), image.Point{}, draw.Src)
+	ink := image.NewUniform(color.Gray{Y: 40})
+	for y := 50; y < 380; y += 30 {
+		for x := 50; x < 540; x += 45 {
+			draw.Draw(page, image.Rect(x, y, x+36, y+12), ink, image.Point{}, draw.Src)
+		}
+	}
+	draw.Draw(page, image.Rect(300, 395, 316, 411), image.NewUniform(red), image.Point{}, draw.Src)
+
+	rotated := imaging.Rotate(page, skew, color.Gray{Y: 70})
+	photo := imaging.New(900, 700, color.Gray{Y: 70})
+	return imaging.Paste(photo, rotated, image.Pt(150, 120))
+}
+
+// withOrientation 在 JPEG 中插入带方向标签的 EXIF 段。
+func withOrientation(data []byte, o uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x01\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], o)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	out := append([]byte{}, data[:2]...)
+	out = append(append(out, app1...), segment...)
+	return append(out, data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	// 竖拍的照片按横向存储，EXIF 方向 6 表示显示时需要顺时针旋转 90 度
+	stored := imaging.Rotate90(documentPhoto(4))
+	data := withOrientation(encodeJPEG(t, stored), 6)
+	if o := exifOrientation(data); o != 6 {
+		t.Fatalf("Expected orientation 6, got %d", o)
+	}
+
+	res, err := Default().Process(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	info := res.Info
+	want := []string{StepOrient, StepDeskew, StepCrop, StepNormalize}
+	if len(info.Steps) != len(want) {
+		t.Fatalf("Expected steps %v, got %v", want, info.Steps)
+	}
+	for i := range want {
+		if info.Steps[i] != want[i] {
+			t.Fatalf("Expected steps %v, got %v", want, info.Steps)
+		}
+	}
+	if math.Abs(info.Skew+4) > 0.3 {
+		t.Errorf("Expected a skew of about -4 degrees, got %v", info.Skew)
+	}
+	if info.OriginalSize != image.Pt(700, 900) || info.Orientation != 6 {
+		t.Errorf("Unexpected original size %v or orientation %d", info.OriginalSize, info.Orientation)
+	}
+	// 裁掉桌面与纸张四周的空白后，不小于文字区域（约 490×360），不大于纸张
+	if info.Size.X > 620 || info.Size.Y > 460 || info.Size.X < 490 || info.Size.Y < 360 {
+		t.Errorf("Expected the page to be cropped, got %v (crop %v)", info.Size, info.Crop)
+	}
+	if res.Format != "jpg" {
+		t.Errorf("Expected jpg, got %s", res.Format)
+	}
+
+	out, err := jpeg.Decode(bytes.NewReader(res.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds().Size() != info.Size {
+		t.Errorf("Expected size %v, got %v", info.Size, out.Bounds().Size())
+	}
+	// 标记在原图与结果中的位置应当符合 Transform
+	ox, oy, ok1 := findRed(stored)
+	px, py, ok2 := findRed(out)
+	if !ok1 || !ok2 {
+		t.Fatal("marker lost")
+	}
+	if wx, wy := info.Transform.Apply(ox, oy); math.Hypot(wx-px, wy-py) > 3 {
+		t.Errorf("Expected the marker at (%.1f, %.1f), got (%.1f, %.1f)", wx, wy, px, py)
+	}
+	if bx, by := info.ToOriginal(px, py); math.Hypot(bx-ox, by-oy) > 3 {
+		t.Errorf("Expected the marker to map back to (%.1f, %.1f), got (%.1f, %.1f)", ox, oy, bx, by)
+	}
+}
+
+// 超出范围的 MaxSkew 按上限处理，不会越界或无限搜索。
+func TestProcess_MaxSkewLimit(t *testing.T) {
+	data := encodeJPEG(t, documentPhoto(4))
+	for _, maxSkew := range []float64{170, 1e300, math.Inf(1), math.NaN()} {
+		res, err := (&Pipeline{Deskew: true, MaxSkew: maxSkew}).Process(data)
+		if err != nil {
+			t.Fatalf("%v: expected no error, got %v", maxSkew, err)
+		}
+		if math.Abs(res.Info.Skew+4) > 0.3 {
+			t.Errorf("%v: expected a skew of about -4 degrees, got %v", maxSkew, res.Info.Skew)
+		}
+	}
+}
+
+func TestProcess_ResizeBinarizeAndLimit(t *testing.T) {
+	data := encodeJPEG(t, documentPhoto(0))
+	p := &Pipeline{Binarize: true, MaxDimension: 450}
+	res, err := p.Process(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Format != "png" || res.Info.Size != image.Pt(450, 350) || res.Info.Scale != 0.5 {
+		t.Errorf("Unexpected result: %s %v scale %v", res.Format, res.Info.Size, res.Info.Scale)
+	}
+	out, err := png.Decode(bytes.NewReader(res.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, ok := out.(*image.Gray)
+	if !ok {
+		t.Fatalf("Expected a gray image, got %T", out)
+	}
+	for _, v := range gray.Pix {
+		if v != 0 && v != 255 {
+			t.Fatalf("Expected a binary image, got gray level %d", v)
+		}
+	}
+	if x, y := res.Info.ToOriginal(100, 50); x != 200 || y != 100 {
+		t.Errorf("Expected (200, 100), got (%v, %v)", x, y)
+	}
+
+	p = &Pipeline{MaxBytes: 8 << 10}
+	res, err = p.Process(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Data) > 8<<10 || res.Info.Steps[len(res.Info.Steps)-1] != StepCompress {
+		t.Errorf("Expected at most 8KB after compression, got %d bytes, steps %v", len(res.Data), res.Info.Steps)
+	}
+	if x, _ := res.Info.ToOriginal(float64(res.Info.Size.X), 0); math.Abs(x-900) > 1 {
+		t.Errorf("Expected the right edge to map back to 900, got %v", x)
+	}
+	if _, err := (&Pipeline{MaxBytes: 10}).Process(data); err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	// 没有任何步骤生效时原样返回
+	res, err = (&Pipeline{MaxDimension: 2000}).Process(data)
+	if err != nil || !bytes.Equal(res.Data, data) || res.Format != "jpg" || len(res.Info.Steps) != 0 {
+		t.Errorf("Expected the original image back, got %v", err)
+	}
+	if _, err := Default().Process([]byte("not an image")); err == nil {
+		t.Error("Expected a decode error")
+	}
+}
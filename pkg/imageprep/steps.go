@@ -0,0 +1,287 @@
+package imageprep
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// analysisSize 是估计倾斜角、边框与对比度时缩小后图片的最大边长。
+const analysisSize = 1024
+
+// grayImage 是 8 位灰度像素，按行存储。
+type grayImage struct {
+	pix  []uint8
+	w, h int
+}
+
+// analysisGray 返回缩小到 analysisSize 以内的灰度图，以及缩小比例（缩小后 / 原图）。
+func analysisGray(img image.Image) (*grayImage, float64) {
+	b := img.Bounds()
+	f := 1.0
+	if long := max(b.Dx(), b.Dy()); long > analysisSize {
+		f = float64(analysisSize) / float64(long)
+		img = imaging.Resize(img, max(1, int(float64(b.Dx())*f)), max(1, int(float64(b.Dy())*f)), imaging.Box)
+	}
+	return toGray(img), f
+}
+
+func toGray(img image.Image) *grayImage {
+	src := imaging.Grayscale(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	g := &grayImage{pix: make([]uint8, w*h), w: w, h: h}
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x := 0; x < w; x++ {
+			g.pix[y*w+x] = row[x*4]
+		}
+	}
+	return g
+}
+
+func (g *grayImage) histogram() [256]int {
+	var hist [256]int
+	for _, v := range g.pix {
+		hist[v]++
+	}
+	return hist
+}
+
+// otsu 返回使类间方差最大的阈值，小于阈值的像素视为文字（深色）。
+func otsu(hist [256]int) uint8 {
+	var total, sum float64
+	for i, n := range hist {
+		total += float64(n)
+		sum += float64(i * n)
+	}
+	var sumB, wB, best float64
+	threshold := 128
+	for i, n := range hist {
+		wB += float64(n)
+		wF := total - wB
+		if wB == 0 {
+			continue
+		}
+		if wF == 0 {
+			break
+		}
+		sumB += float64(i * n)
+		mB, mF := sumB/wB, (sum-sumB)/wF
+		if between := wB * wF * (mB - mF) * (mB - mF); between > best {
+			best, threshold = between, i+1
+		}
+	}
+	return uint8(min(threshold, 255))
+}
+
+// maxInkPoints 限制估计倾斜角时使用的边缘像素数。
+const maxInkPoints = 200000
+
+// estimateSkew 用投影法估计文字行的倾斜角：对每个候选角度，把笔画的上边缘（上方是浅色的深色像素）投影到
+// 旋转后的纵轴上，文字行对齐时各行的像素数起伏最大。只用边缘而不用全部深色像素，是为了不让拍照时
+// 大面积的深色桌面主导投影。返回 imaging.Rotate 需要逆时针旋转的角度，找不到文字时返回 0。
+func estimateSkew(g *grayImage, maxSkew float64) float64 {
+	t := otsu(g.histogram())
+	var xs, ys []float64
+	for y := 1; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if g.pix[y*g.w+x] < t && g.pix[(y-1)*g.w+x] >= t {
+				xs = append(xs, float64(x))
+				ys = append(ys, float64(y))
+			}
+		}
+	}
+	// 边缘太少（空白页）或太多（纹理丰富的照片）时不做校正
+	if n := len(xs); n < 50 || n > g.w*g.h/4 {
+		return 0
+	}
+	if stride := len(xs)/maxInkPoints + 1; stride > 1 {
+		for i := 0; i*stride < len(xs); i++ {
+			xs[i], ys[i] = xs[i*stride], ys[i*stride]
+		}
+		xs, ys = xs[:len(xs)/stride], ys[:len(ys)/stride]
+	}
+
+	offset := float64(g.w)
+	hist := make([]float64, g.w+g.h+2*g.w+2)
+	score := func(angle float64) float64 {
+		clear(hist)
+		sin, cos := math.Sincos(math.Pi * angle / 180)
+		for i := range xs {
+			hist[int(-xs[i]*sin+ys[i]*cos+offset)]++
+		}
+		var s float64
+		for i := 1; i < len(hist); i++ {
+			d := hist[i] - hist[i-1]
+			s += d * d
+		}
+		return s
+	}
+
+	best, bestScore := 0.0, score(0)
+	search := func(from, to, step float64) {
+		for a := from; a <= to+step/2; a += step {
+			if s := score(a); s > bestScore {
+				best, bestScore = a, s
+			}
+		}
+	}
+	search(-maxSkew, maxSkew, 0.5)
+	search(best-0.5, best+0.5, 0.05)
+	if math.Abs(best) < 0.1 {
+		return 0
+	}
+	return math.Round(best*100) / 100
+}
+
+// borderGray 返回图片最外一圈像素灰度的中位数，作为旋转后四角的填充色，
+// 使填充与纸张四周的空白或桌面一致，不被裁剪误认为内容。
+func borderGray(g *grayImage) color.Gray {
+	var hist [256]int
+	n := 0
+	for x := 0; x < g.w; x++ {
+		hist[g.pix[x]]++
+		hist[g.pix[(g.h-1)*g.w+x]]++
+		n += 2
+	}
+	for y := 0; y < g.h; y++ {
+		hist[g.pix[y*g.w]]++
+		hist[g.pix[y*g.w+g.w-1]]++
+		n += 2
+	}
+	for v, c := range hist {
+		if n -= 2 * c; n <= 0 {
+			return color.Gray{Y: uint8(v)}
+		}
+	}
+	return color.Gray{Y: 255}
+}
+
+// contentBounds 返回去掉四周空白或大面积深色边框（如拍照时的桌面）后内容所在的矩形，
+// 坐标属于 g。行或列中深色像素的比例在 (0.2%, 60%) 之间视为内容。找不到内容时返回整张图。
+func contentBounds(g *grayImage) image.Rectangle {
+	t := otsu(g.histogram())
+	full := image.Rect(0, 0, g.w, g.h)
+	rows := make([]int, g.h)
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if g.pix[y*g.w+x] < t {
+				rows[y]++
+			}
+		}
+	}
+	top, bottom := span(rows, g.w)
+	if top >= bottom {
+		return full
+	}
+	cols := make([]int, g.w)
+	for y := top; y < bottom; y++ {
+		for x := 0; x < g.w; x++ {
+			if g.pix[y*g.w+x] < t {
+				cols[x]++
+			}
+		}
+	}
+	left, right := span(cols, bottom-top)
+	if left >= right {
+		return full
+	}
+	return image.Rect(left, top, right, bottom)
+}
+
+// span 返回 counts 中从两端起第一个和最后一个内容行（列）的范围 [from, to)，n 为每行（列）的像素数。
+func span(counts []int, n int) (int, int) {
+	minInk, maxInk := max(2, n/500), n*6/10
+	content := func(c int) bool { return c >= minInk && c <= maxInk }
+	from, to := 0, len(counts)
+	for from < to && !content(counts[from]) {
+		from++
+	}
+	for to > from && !content(counts[to-1]) {
+		to--
+	}
+	return from, to
+}
+
+// stretchLevels 返回对比度拉伸的输入范围：最暗与最亮的 0.5% 像素之外的灰度。无需拉伸时 ok 为 false。
+func stretchLevels(g *grayImage) (lo, hi uint8, ok bool) {
+	hist := g.histogram()
+	cut := len(g.pix) / 200
+	var n int
+	for lo = 0; lo < 255; lo++ {
+		if n += hist[lo]; n > cut {
+			break
+		}
+	}
+	n = 0
+	for hi = 255; hi > 0; hi-- {
+		if n += hist[hi]; n > cut {
+			break
+		}
+	}
+	// 范围太窄（几乎纯色）或已经接近满幅时不拉伸
+	if int(hi)-int(lo) < 16 || (lo <= 4 && hi >= 251) {
+		return 0, 0, false
+	}
+	return lo, hi, true
+}
+
+// stretch 将 [lo, hi] 线性拉伸到 [0, 255]，各通道使用相同的映射，保持色相。
+func stretch(img image.Image, lo, hi uint8) *image.NRGBA {
+	var lut [256]uint8
+	for i := range lut {
+		v := (float64(i) - float64(lo)) * 255 / (float64(hi) - float64(lo))
+		lut[i] = uint8(math.Round(math.Min(255, math.Max(0, v))))
+	}
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{R: lut[c.R], G: lut[c.G], B: lut[c.B], A: c.A}
+	})
+}
+
+// binarize 用局部均值阈值（Bradley 方法）二值化：像素比周围 1/8 边长窗口的均值暗 15% 以上时为黑色，
+// 能处理手机拍照时的光照不均。
+func binarize(img image.Image) *image.Gray {
+	g := toGray(img)
+	w, h := g.w, g.h
+	r := max(4, max(w, h)/16) // 窗口半径
+	// 先按行求窗口内的和，再按列累加，窗口和不超过 255·(2r+1)²
+	rowSums := make([]uint32, w*h)
+	for y := 0; y < h; y++ {
+		row := g.pix[y*w : (y+1)*w]
+		prefix := make([]uint32, w+1)
+		for x, v := range row {
+			prefix[x+1] = prefix[x] + uint32(v)
+		}
+		for x := 0; x < w; x++ {
+			rowSums[y*w+x] = prefix[min(w, x+r+1)] - prefix[max(0, x-r)]
+		}
+	}
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	colSums := make([]uint64, w)
+	for y := -r; y < h; y++ {
+		// 窗口下边进入、上边离开
+		if in := y + r; in < h {
+			for x := 0; x < w; x++ {
+				colSums[x] += uint64(rowSums[in*w+x])
+			}
+		}
+		if out := y - r - 1; out >= 0 {
+			for x := 0; x < w; x++ {
+				colSums[x] -= uint64(rowSums[out*w+x])
+			}
+		}
+		if y < 0 {
+			continue
+		}
+		rowsIn := uint64(min(h, y+r+1) - max(0, y-r))
+		for x := 0; x < w; x++ {
+			count := rowsIn * uint64(min(w, x+r+1)-max(0, x-r))
+			if uint64(g.pix[y*w+x])*count*100 > colSums[x]*85 {
+				dst.Pix[y*dst.Stride+x] = 255
+			}
+		}
+	}
+	return dst
+}
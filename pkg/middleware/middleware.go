@@ -0,0 +1,87 @@
+// Package middleware 提供包装 http.RoundTripper 的中间件机制，
+// 用于为所有 REST 客户端统一注入代理、mTLS、请求头、审计日志等横切逻辑。
+//
+// 中间件通过各客户端的 WithMiddleware 选项挂载，作用于客户端发出的每个 HTTP 请求（包括重试）。
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+)
+
+// Middleware 包装一个 http.RoundTripper 并返回新的 RoundTripper。
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc 让普通函数实现 http.RoundTripper。
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip 调用 f(req)。
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain 用 mws 依次包装 base，第一个中间件位于最外层，最先看到请求。
+// base 为 nil 时使用 http.DefaultTransport。
+func Chain(base http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] != nil {
+			base = mws[i](base)
+		}
+	}
+	return base
+}
+
+// WrapClient 返回 client 的浅拷贝，其 Transport 经过 mws 包装；client 本身不会被修改。
+// client 为 nil 时基于 http.DefaultClient，没有中间件时原样返回 client。
+func WrapClient(client *http.Client, mws ...Middleware) *http.Client {
+	if len(mws) == 0 {
+		return client
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	wrapped := *client
+	wrapped.Transport = Chain(client.Transport, mws...)
+	return &wrapped
+}
+
+// SetHeader 返回为每个请求设置请求头 key 的中间件，已有的同名请求头会被覆盖。
+func SetHeader(key, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// RoundTripper 不应修改调用方的请求，先克隆再设置
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Log 返回在 Debug 级别记录每个请求的方法、URL、状态码与耗时的中间件。
+// URL 与请求头中的签名会被脱敏（见 auth.RedactURL、auth.RedactHeader）。
+func Log(logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			attrs := []any{
+				"method", req.Method,
+				"url", auth.RedactURL(req.URL.String()),
+				"headers", auth.RedactHeader(req.Header),
+				"elapsed", time.Since(start),
+			}
+			if err != nil {
+				logger.Debug("http request failed", append(attrs, "error", err)...)
+				return nil, err
+			}
+			logger.Debug("http request", append(attrs, "status", resp.StatusCode)...)
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "base")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	rt := Chain(base, tag("outer"), nil, tag("inner"))
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := strings.Join(order, ","); got != "outer,inner,base" {
+		t.Errorf("Expected 'outer,inner,base', got '%s'", got)
+	}
+}
+
+func TestWrapClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Tenant")))
+	}))
+	defer server.Close()
+
+	orig := &http.Client{}
+	client := WrapClient(orig, SetHeader("X-Tenant", "acme"))
+	if orig.Transport != nil {
+		t.Error("Expected the original client not to be modified")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	if body.String() != "acme" {
+		t.Errorf("Expected header 'acme' to reach the server, got '%s'", body.String())
+	}
+	if req.Header.Get("X-Tenant") != "" {
+		t.Error("Expected the caller's request not to be modified")
+	}
+
+	if WrapClient(orig) != orig {
+		t.Error("Expected WrapClient without middlewares to return the client as is")
+	}
+}
+
+func TestLog_RedactsSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := WrapClient(&http.Client{}, Log(logger))
+
+	resp, err := client.Get(server.URL + "?authorization=secret-signature&date=now")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	out := buf.String()
+	if strings.Contains(out, "secret-signature") {
+		t.Errorf("Expected signature to be redacted, got '%s'", out)
+	}
+	if !strings.Contains(out, "status=200") {
+		t.Errorf("Expected status in log, got '%s'", out)
+	}
+}
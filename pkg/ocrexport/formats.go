@@ -0,0 +1,268 @@
+package ocrexport
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// HOCR 以 hOCR 1.2（XHTML）格式写出 d：每页一个 ocr_page，区域为 ocr_carea，行为 ocr_line，词为 ocrx_word。
+// title 属性中的 bbox 为外接矩形，x_wconf 为 0~100 的置信度（没有置信度时省略）。
+func (d *Document) HOCR(w io.Writer) error {
+	// html/template 会转义模板开头的 <?xml，声明单独写出
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return hocrTemplate.Execute(w, d)
+}
+
+var hocrTemplate = template.Must(template.New("hocr").Funcs(template.FuncMap{
+	"title": hocrTitle,
+	"id": func(prefix string, ids ...int) string {
+		parts := []string{prefix}
+		for _, id := range ids {
+			parts = append(parts, strconv.Itoa(id+1))
+		}
+		return strings.Join(parts, "_")
+	},
+	"pageTitle": func(i int, p Page) string {
+		return fmt.Sprintf("image %d; bbox 0 0 %d %d; ppageno %d", i+1, p.Width, p.Height, i)
+	},
+}).Parse(`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="zh" lang="zh">
+<head>
+<title></title>
+<meta http-equiv="Content-Type" content="text/html;charset=utf-8" />
+<meta name="ocr-system" content="goxfyunclient" />
+<meta name="ocr-capabilities" content="ocr_page ocr_carea ocr_line ocrx_word" />
+</head>
+<body>
+{{- range $p, $page := .Pages}}
+<div class="ocr_page" id="{{id "page" $p}}" title="{{pageTitle $p $page}}">
+{{- range $b, $block := $page.Blocks}}
+<div class="ocr_carea" id="{{id "block" $p $b}}" title="{{title $block.Bounds 0}}">
+{{- range $l, $line := $block.Lines}}
+<span class="ocr_line" id="{{id "line" $p $b $l}}" title="{{title $line.Bounds $line.Conf}}">
+{{- if $line.Words}}
+{{- range $w, $word := $line.Words}}
+<span class="ocrx_word" id="{{id "word" $p $b $l $w}}" title="{{title $word.Bounds $word.Conf}}">{{$word.Text}}</span>
+{{- end}}
+</span>
+{{- else}}{{$line.Text}}</span>
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+func hocrTitle(r image.Rectangle, conf float64) string {
+	title := fmt.Sprintf("bbox %d %d %d %d", r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+	if conf > 0 {
+		title += fmt.Sprintf("; x_wconf %d", int(math.Round(conf*100)))
+	}
+	return title
+}
+
+// ALTO 以 ALTO XML v4 格式写出 d：每页一个 Page/PrintSpace，区域为 TextBlock，行为 TextLine，
+// 词为 String（行没有词时整行为一个 String）。HPOS/VPOS/WIDTH/HEIGHT 为外接矩形，
+// 多边形坐标写入 Shape/Polygon，WC 为 0~1 的置信度（没有置信度时省略）。
+func (d *Document) ALTO(w io.Writer) error {
+	doc := altoDocument{
+		Xmlns: "http://www.loc.gov/standards/alto/ns-v4#",
+		Description: altoDescription{
+			MeasurementUnit: "pixel",
+			Software:        "goxfyunclient",
+		},
+	}
+	for p, page := range d.Pages {
+		ap := altoPage{
+			ID:     fmt.Sprintf("page_%d", p+1),
+			Width:  page.Width,
+			Height: page.Height,
+			Number: p + 1,
+		}
+		ap.PrintSpace.Width, ap.PrintSpace.Height = page.Width, page.Height
+		for b, block := range page.Blocks {
+			ab := altoBlock{ID: fmt.Sprintf("block_%d_%d", p+1, b+1), altoBox: altoBoxOf(block.Bounds()), Shape: altoShapeOf(block.Polygon)}
+			for l, line := range block.Lines {
+				al := altoLine{ID: fmt.Sprintf("line_%d_%d_%d", p+1, b+1, l+1), altoBox: altoBoxOf(line.Bounds()), Shape: altoShapeOf(line.Polygon)}
+				words := line.Words
+				if len(words) == 0 {
+					words = []Word{{Text: line.Text, Conf: line.Conf, Polygon: line.Polygon}}
+				}
+				for i, word := range words {
+					if i > 0 {
+						al.Items = append(al.Items, altoSpace{XMLName: xml.Name{Local: "SP"}})
+					}
+					al.Items = append(al.Items, altoString{
+						Content: word.Text,
+						WC:      altoConf(word.Conf),
+						altoBox: altoBoxOf(word.Bounds()),
+						Shape:   altoShapeOf(word.Polygon),
+					})
+				}
+				ab.Lines = append(ab.Lines, al)
+			}
+			ap.PrintSpace.Blocks = append(ap.PrintSpace.Blocks, ab)
+		}
+		doc.Layout.Pages = append(doc.Layout.Pages, ap)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type altoDocument struct {
+	XMLName     xml.Name        `xml:"alto"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	Description altoDescription `xml:"Description"`
+	Layout      struct {
+		Pages []altoPage `xml:"Page"`
+	} `xml:"Layout"`
+}
+
+type altoDescription struct {
+	MeasurementUnit string `xml:"MeasurementUnit"`
+	Software        string `xml:"OCRProcessing>ocrProcessingStep>processingSoftware>softwareName"`
+}
+
+type altoPage struct {
+	ID         string `xml:"ID,attr"`
+	Width      int    `xml:"WIDTH,attr"`
+	Height     int    `xml:"HEIGHT,attr"`
+	Number     int    `xml:"PHYSICAL_IMG_NR,attr"`
+	PrintSpace struct {
+		Width  int         `xml:"WIDTH,attr"`
+		Height int         `xml:"HEIGHT,attr"`
+		HPos   int         `xml:"HPOS,attr"`
+		VPos   int         `xml:"VPOS,attr"`
+		Blocks []altoBlock `xml:"TextBlock"`
+	} `xml:"PrintSpace"`
+}
+
+type altoBox struct {
+	HPos   int `xml:"HPOS,attr"`
+	VPos   int `xml:"VPOS,attr"`
+	Width  int `xml:"WIDTH,attr"`
+	Height int `xml:"HEIGHT,attr"`
+}
+
+type altoBlock struct {
+	ID string `xml:"ID,attr"`
+	altoBox
+	Shape *altoShape `xml:"Shape"`
+	Lines []altoLine `xml:"TextLine"`
+}
+
+type altoLine struct {
+	ID string `xml:"ID,attr"`
+	altoBox
+	Shape *altoShape `xml:"Shape"`
+	Items []any      `xml:",any"`
+}
+
+type altoString struct {
+	XMLName xml.Name `xml:"String"`
+	Content string   `xml:"CONTENT,attr"`
+	WC      string   `xml:"WC,attr,omitempty"`
+	altoBox
+	Shape *altoShape `xml:"Shape"`
+}
+
+type altoSpace struct {
+	XMLName xml.Name
+}
+
+type altoShape struct {
+	Polygon struct {
+		Points string `xml:"POINTS,attr"`
+	} `xml:"Polygon"`
+}
+
+func altoBoxOf(r image.Rectangle) altoBox {
+	return altoBox{HPos: r.Min.X, VPos: r.Min.Y, Width: r.Dx(), Height: r.Dy()}
+}
+
+func altoShapeOf(polygon []image.Point) *altoShape {
+	if len(polygon) == 0 {
+		return nil
+	}
+	points := make([]string, len(polygon))
+	for i, p := range polygon {
+		points[i] = fmt.Sprintf("%d,%d", p.X, p.Y)
+	}
+	shape := &altoShape{}
+	shape.Polygon.Points = strings.Join(points, " ")
+	return shape
+}
+
+func altoConf(conf float64) string {
+	if conf <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(conf, 'f', -1, 64)
+}
+
+// LineBoxes 以 JSON 写出每一行的文字、置信度、外接矩形与多边形，适合用作可搜索 PDF 的文字层：
+//
+//	{"pages": [{"page": 1, "width": 800, "height": 600, "lines": [
+//	  {"text": "讯飞开放平台", "conf": 0.99, "bbox": [10, 20, 400, 50], "polygon": [[10, 20], [400, 20], ...]}]}]}
+//
+// bbox 为 [x0, y0, x1, y1]；没有置信度或多边形时省略对应字段。
+func (d *Document) LineBoxes(w io.Writer) error {
+	out := lineBoxDocument{Pages: []lineBoxPage{}}
+	for p, page := range d.Pages {
+		lp := lineBoxPage{Page: p + 1, Width: page.Width, Height: page.Height, Lines: []lineBox{}}
+		for _, block := range page.Blocks {
+			for _, line := range block.Lines {
+				r := line.Bounds()
+				lb := lineBox{Text: line.Text, Conf: line.Conf, BBox: [4]int{r.Min.X, r.Min.Y, r.Max.X, r.Max.Y}}
+				for _, pt := range line.Polygon {
+					lb.Polygon = append(lb.Polygon, [2]int{pt.X, pt.Y})
+				}
+				lp.Lines = append(lp.Lines, lb)
+			}
+		}
+		out.Pages = append(out.Pages, lp)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+type lineBoxDocument struct {
+	Pages []lineBoxPage `json:"pages"`
+}
+
+type lineBoxPage struct {
+	Page   int       `json:"page"` // 从 1 开始
+	Width  int       `json:"width"`
+	Height int       `json:"height"`
+	Lines  []lineBox `json:"lines"`
+}
+
+type lineBox struct {
+	Text    string   `json:"text"`
+	Conf    float64  `json:"conf,omitempty"`
+	BBox    [4]int   `json:"bbox"`
+	Polygon [][2]int `json:"polygon,omitempty"`
+}
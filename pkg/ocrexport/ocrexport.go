@@ -0,0 +1,191 @@
+// Package ocrexport 将 ocr 与 llmocr 的识别结果转换为通用的交换格式，用于文档归档：
+// hOCR（HOCR）、ALTO XML v4（ALTO）与简单的行框 JSON（LineBoxes）。
+//
+// 两种结果先转换为统一的 Document（FromOCR、FromLLMOCR），保留每行与每个词的多边形坐标和置信度：
+//
+//	doc := ocrexport.FromOCR(result)
+//	err := doc.HOCR(w)
+//
+// 坐标单位为像素，原点在图片左上角；置信度为 0~1，0 表示服务没有返回置信度。
+package ocrexport
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	llmocrmodels "github.com/fruitbars/goxfyunclient/pkg/service/llmocr/models"
+	ocrmodels "github.com/fruitbars/goxfyunclient/pkg/service/ocr/models"
+)
+
+// Document 是与服务无关的识别结果。
+type Document struct {
+	Pages []Page
+}
+
+// Page 是一页图片，Width 与 Height 未知时为 0。
+type Page struct {
+	Width, Height int
+	Blocks        []Block
+}
+
+// Block 是一个文字区域（段落、表格单元等），ocr 的结果每页只有一个区域。
+type Block struct {
+	Polygon []image.Point
+	Lines   []Line
+}
+
+// Line 是一行文字。
+type Line struct {
+	Text    string
+	Conf    float64
+	Polygon []image.Point
+	Words   []Word
+}
+
+// Word 是行中的一个词。
+type Word struct {
+	Text    string
+	Conf    float64
+	Polygon []image.Point
+}
+
+// Bounds 返回区域的外接矩形；没有坐标时返回各行外接矩形的并集。
+func (b Block) Bounds() image.Rectangle {
+	if len(b.Polygon) > 0 {
+		return bounds(b.Polygon)
+	}
+	var r image.Rectangle
+	for _, l := range b.Lines {
+		r = r.Union(l.Bounds())
+	}
+	return r
+}
+
+// Bounds 返回行的外接矩形；没有坐标时返回各词外接矩形的并集。
+func (l Line) Bounds() image.Rectangle {
+	if len(l.Polygon) > 0 {
+		return bounds(l.Polygon)
+	}
+	var r image.Rectangle
+	for _, w := range l.Words {
+		r = r.Union(w.Bounds())
+	}
+	return r
+}
+
+// Bounds 返回词的外接矩形，没有坐标时返回空矩形。
+func (w Word) Bounds() image.Rectangle {
+	return bounds(w.Polygon)
+}
+
+// FromOCR 转换通用文字识别（ocr）的结果，每页一个区域；行内的词保留各自的坐标与置信度。
+func FromOCR(r *ocrmodels.Result) *Document {
+	doc := &Document{}
+	for _, p := range r.Pages {
+		page := Page{Width: p.Width, Height: p.Height}
+		var block Block
+		for _, l := range p.Lines {
+			line := Line{Text: l.Text(), Conf: l.Conf, Polygon: ocrPolygon(l.Coord), Words: ocrWords(l)}
+			block.Lines = append(block.Lines, line)
+		}
+		if len(block.Lines) > 0 {
+			page.Blocks = append(page.Blocks, block)
+		}
+		doc.Pages = append(doc.Pages, page)
+	}
+	return doc
+}
+
+// ocrWords 转换行中的词。没有坐标的词无法定位：只有一个词时使用行的坐标，否则不保留词，整行作为一个单元。
+func ocrWords(l ocrmodels.Line) []Word {
+	words := make([]Word, 0, len(l.Words))
+	for _, w := range l.Words {
+		polygon := ocrPolygon(w.Coord)
+		if polygon == nil {
+			if len(l.Words) > 1 {
+				return nil
+			}
+			polygon = ocrPolygon(l.Coord)
+		}
+		words = append(words, Word{Text: w.Content, Conf: w.Conf, Polygon: polygon})
+	}
+	return words
+}
+
+// FromLLMOCR 转换大模型文字识别（llmocr）的结果。每个 image 为一页，顶层的内容节点为一个区域，
+// 区域中最内层带有文字的节点为一行；坐标优先取 Contour，其次取 Coord，置信度取 Score。
+func FromLLMOCR(r *llmocrmodels.EngineResult) *Document {
+	doc := &Document{}
+	for _, img := range r.Image {
+		page := Page{Width: img.Width, Height: img.Height}
+		for _, row := range img.Content {
+			for _, node := range row {
+				block := Block{Polygon: llmocrPolygon(node), Lines: llmocrLines(node)}
+				if len(block.Lines) > 0 {
+					page.Blocks = append(page.Blocks, block)
+				}
+			}
+		}
+		doc.Pages = append(doc.Pages, page)
+	}
+	return doc
+}
+
+// llmocrLines 返回 node 之下最内层带有文字的节点：子节点中有文字时使用子节点，否则使用 node 本身。
+func llmocrLines(node llmocrmodels.ContentNode) []Line {
+	var lines []Line
+	for _, row := range node.Content {
+		for _, child := range row {
+			lines = append(lines, llmocrLines(child)...)
+		}
+	}
+	if len(lines) > 0 {
+		return lines
+	}
+	text := strings.Join(node.Text, "")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return []Line{{Text: text, Conf: node.Score, Polygon: llmocrPolygon(node)}}
+}
+
+func llmocrPolygon(node llmocrmodels.ContentNode) []image.Point {
+	points := node.Contour
+	if len(points) == 0 {
+		points = node.Coord
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	polygon := make([]image.Point, len(points))
+	for i, p := range points {
+		polygon[i] = image.Pt(int(math.Round(p.X)), int(math.Round(p.Y)))
+	}
+	return polygon
+}
+
+func ocrPolygon(points []ocrmodels.Point) []image.Point {
+	if len(points) == 0 {
+		return nil
+	}
+	polygon := make([]image.Point, len(points))
+	for i, p := range points {
+		polygon[i] = image.Pt(p.X, p.Y)
+	}
+	return polygon
+}
+
+func bounds(points []image.Point) image.Rectangle {
+	if len(points) == 0 {
+		return image.Rectangle{}
+	}
+	r := image.Rectangle{Min: points[0], Max: points[0]}
+	for _, p := range points[1:] {
+		r.Min.X = min(r.Min.X, p.X)
+		r.Min.Y = min(r.Min.Y, p.Y)
+		r.Max.X = max(r.Max.X, p.X)
+		r.Max.Y = max(r.Max.Y, p.Y)
+	}
+	return r
+}
@@ -0,0 +1,153 @@
+package ocrexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	llmocrmodels "github.com/fruitbars/goxfyunclient/pkg/service/llmocr/models"
+	ocrmodels "github.com/fruitbars/goxfyunclient/pkg/service/ocr/models"
+)
+
+// go test ./pkg/ocrexport -update 重新生成 testdata 中的期望输出。
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func loadOCR(t *testing.T) *Document {
+	t.Helper()
+	data, err := os.ReadFile("testdata/ocr.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ocrmodels.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return FromOCR(r)
+}
+
+func loadLLMOCR(t *testing.T) *Document {
+	t.Helper()
+	data, err := os.ReadFile("testdata/llmocr.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r llmocrmodels.EngineResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	return FromLLMOCR(&r)
+}
+
+func TestFromOCR(t *testing.T) {
+	doc := loadOCR(t)
+	if len(doc.Pages) != 1 || len(doc.Pages[0].Blocks) != 1 {
+		t.Fatalf("Expected one page with one block, got %+v", doc)
+	}
+	lines := doc.Pages[0].Blocks[0].Lines
+	if len(lines) != 2 || lines[0].Text != "讯飞开放平台" || lines[0].Conf != 0.99 || len(lines[0].Words) != 2 {
+		t.Fatalf("Unexpected lines: %+v", lines)
+	}
+	if got, want := lines[1].Bounds(), image.Rect(11, 70, 300, 101); got != want {
+		t.Errorf("Expected line bounds %v, got %v", want, got)
+	}
+	if got, want := doc.Pages[0].Blocks[0].Bounds(), image.Rect(10, 20, 400, 101); got != want {
+		t.Errorf("Expected block bounds %v, got %v", want, got)
+	}
+}
+
+func TestFromLLMOCR(t *testing.T) {
+	doc := loadLLMOCR(t)
+	page := doc.Pages[0]
+	if page.Width != 1024 || len(page.Blocks) != 2 {
+		t.Fatalf("Expected a paragraph and a table block, got %+v", page)
+	}
+	// 段落的子节点为行，段落本身的文字不重复
+	para := page.Blocks[0].Lines
+	if len(para) != 2 || para[0].Text != "讯飞开放平台" || para[1].Text != "语音与文字识别" {
+		t.Fatalf("Unexpected paragraph lines: %+v", para)
+	}
+	// Contour 优先于 Coord，坐标四舍五入
+	if got, want := para[0].Polygon, []image.Point{{40, 30}, {421, 31}, {420, 71}, {40, 70}}; !equalPoints(got, want) {
+		t.Errorf("Expected contour %v, got %v", want, got)
+	}
+	if para[0].Conf != 0.97 || para[1].Conf != 0 {
+		t.Errorf("Unexpected confidence: %v, %v", para[0].Conf, para[1].Conf)
+	}
+	// 表格中只有空白文字的单元格被忽略
+	if cells := page.Blocks[1].Lines; len(cells) != 1 || cells[0].Text != "单元格" {
+		t.Errorf("Unexpected table lines: %+v", cells)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	formats := []struct {
+		ext   string
+		write func(*Document, io.Writer) error
+	}{
+		{".hocr", (*Document).HOCR},
+		{".alto.xml", (*Document).ALTO},
+		{".lines.json", (*Document).LineBoxes},
+	}
+	sources := map[string]*Document{"ocr": loadOCR(t), "llmocr": loadLLMOCR(t)}
+
+	for name, doc := range sources {
+		for _, f := range formats {
+			t.Run(name+f.ext, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := f.write(doc, &buf); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				// hOCR 与 ALTO 都必须是格式良好的 XML
+				if f.ext != ".lines.json" {
+					if err := wellFormed(buf.Bytes()); err != nil {
+						t.Fatalf("Expected well-formed XML, got %v", err)
+					}
+				}
+
+				golden := filepath.Join("testdata", name+f.ext)
+				if *update {
+					if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("Missing golden file (run with -update): %v", err)
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("Output differs from %s:\n%s", golden, buf.String())
+				}
+			})
+		}
+	}
+}
+
+func wellFormed(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func equalPoints(a, b []image.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
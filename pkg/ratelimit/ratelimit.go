@@ -0,0 +1,155 @@
+// Package ratelimit 提供客户端侧的 QPS 限流（令牌桶）与并发限制（信号量），
+// 用于在批量任务中遵守讯飞按 AppID 分配的 QPS 与并发配额。
+//
+// 同一个 *Limiter 可以挂到多个客户端上；使用同一 AppID 的客户端可通过 Registry 共享限流器。
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Config 描述限流配置，零值字段表示不限制对应维度。
+type Config struct {
+	// QPS 是每秒允许发出的请求数。
+	QPS float64
+
+	// Burst 是令牌桶容量，即允许的瞬时突发请求数，小于 1 时取 1。
+	Burst int
+
+	// MaxInFlight 是同时进行中的请求数上限。
+	MaxInFlight int
+}
+
+// Limiter 组合了令牌桶与并发信号量，并发安全。nil 的 *Limiter 不做任何限制。
+type Limiter struct {
+	cfg Config
+	sem chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New 根据 cfg 创建限流器。
+func New(cfg Config) *Limiter {
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	l := &Limiter{cfg: cfg, tokens: float64(cfg.Burst), last: time.Now()}
+	if cfg.MaxInFlight > 0 {
+		l.sem = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return l
+}
+
+// Config 返回限流器的配置。
+func (l *Limiter) Config() Config {
+	if l == nil {
+		return Config{}
+	}
+	return l.cfg
+}
+
+// Acquire 阻塞直到获得一个并发名额和一个 QPS 令牌，或 ctx 结束。
+// 成功时返回的 release 必须在请求结束后调用以归还并发名额；失败时返回 ctx.Err()。
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = l.releaseFunc()
+
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+func (l *Limiter) releaseFunc() func() {
+	if l.sem == nil {
+		return func() {}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l.sem })
+	}
+}
+
+// wait 从令牌桶中预定一个令牌，令牌不足时等待补充。
+// 预定允许令牌数为负，使等待者按到达顺序依次获得令牌；ctx 结束时归还预定的令牌。
+func (l *Limiter) wait(ctx context.Context) error {
+	if l.cfg.QPS <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.cfg.QPS
+	if burst := float64(l.cfg.Burst); l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.cfg.QPS * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Registry 按 key（通常为 AppID）保存共享的限流器。
+type Registry struct {
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewRegistry 创建空的注册表。
+func NewRegistry() *Registry {
+	return &Registry{limiters: make(map[string]*Limiter)}
+}
+
+// Get 返回 key 对应的限流器；不存在时按 cfg 创建。已存在时忽略 cfg。
+// 配额按服务区分时，可使用 AppID 与服务名拼接的 key，例如 "appid/ocr"。
+func (r *Registry) Get(key string, cfg Config) *Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.limiters[key]; ok {
+		return l
+	}
+	l := New(cfg)
+	r.limiters[key] = l
+	return l
+}
+
+// DefaultRegistry 是 ForAppID 使用的进程级注册表。
+var DefaultRegistry = NewRegistry()
+
+// ForAppID 返回 DefaultRegistry 中 appID 对应的共享限流器。
+func ForAppID(appID string, cfg Config) *Limiter {
+	return DefaultRegistry.Get(appID, cfg)
+}
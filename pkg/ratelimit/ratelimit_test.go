@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_QPS(t *testing.T) {
+	l := New(Config{QPS: 50, Burst: 1})
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		release()
+	}
+	// 首个令牌立即可用，其余 5 个以 20ms 间隔补充
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected at least ~100ms for 6 requests at 50 QPS, got %v", elapsed)
+	}
+}
+
+func TestLimiter_MaxInFlight(t *testing.T) {
+	l := New(Config{MaxInFlight: 2})
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 in flight, got %d", peak.Load())
+	}
+}
+
+func TestLimiter_ContextCancel(t *testing.T) {
+	l := New(Config{MaxInFlight: 1})
+	release, _ := l.Acquire(context.Background())
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded waiting for a slot, got %v", err)
+	}
+
+	q := New(Config{QPS: 0.1, Burst: 1})
+	r, _ := q.Acquire(context.Background())
+	r()
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	if _, err := q.Acquire(ctx2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded waiting for a token, got %v", err)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	release()
+}
+
+func TestRegistry_SharesByKey(t *testing.T) {
+	r := NewRegistry()
+	a := r.Get("app", Config{QPS: 5})
+	b := r.Get("app", Config{QPS: 10})
+	if a != b {
+		t.Error("Expected the same limiter for the same key")
+	}
+	if a.Config().QPS != 5 {
+		t.Errorf("Expected the first config to win, got QPS %v", a.Config().QPS)
+	}
+	if r.Get("other", Config{}) == a {
+		t.Error("Expected a different limiter for a different key")
+	}
+}
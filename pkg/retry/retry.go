@@ -0,0 +1,152 @@
+// Package retry 提供各服务客户端共用的重试策略：指数退避、随机抖动，
+// 以及按网络错误、HTTP 状态码和讯飞业务错误码（xfyunerr.APIError）判断是否重试的分类器。
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+// DefaultRetryableCodes 是通用错误码中视为临时性错误的讯飞 header.code：
+// 10200 读取数据超时、10222 网络异常、10700 引擎错误（引擎忙）、
+// 11202 秒级流控超限、11203 并发流控超限。
+// 默认分类器以错误码目录（xfyunerr.Lookup）为准，目录还包含各服务专有的可重试错误码；
+// 本列表用于在其基础上构造自定义的错误码集合。
+var DefaultRetryableCodes = []int{10200, 10222, 10700, 11202, 11203}
+
+// Classifier 判断一次失败是否值得重试。
+type Classifier func(err error) bool
+
+// DefaultClassifier 返回默认分类器：网络错误、读取响应中断、HTTP 429 与 5xx、
+// 以及可重试的业务错误码视为可重试；ctx 取消或超时不重试。
+// 未指定 codes 时，业务错误码是否可重试由错误码目录决定（xfyunerr.APIError.Retryable）；
+// 指定 codes 时仅 codes 中的错误码可重试。
+func DefaultClassifier(codes ...int) Classifier {
+	retryable := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		retryable[code] = true
+	}
+	return func(err error) bool {
+		if err == nil || errors.Is(err, context.Canceled) {
+			return false
+		}
+		var apiErr *xfyunerr.APIError
+		if errors.As(err, &apiErr) {
+			if apiErr.Code != 0 && len(retryable) > 0 {
+				return retryable[apiErr.Code]
+			}
+			return apiErr.Retryable()
+		}
+		var netErr *xfyunerr.NetworkError
+		var urlErr *url.Error
+		return errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+}
+
+// Policy 描述重试策略。nil 的 *Policy 表示不重试，可直接调用 Do。
+type Policy struct {
+	// MaxAttempts 是包括首次请求在内的最大尝试次数，小于等于 1 时不重试。
+	MaxAttempts int
+
+	// InitialBackoff 是第一次重试前的等待时间，之后每次乘以 Multiplier，不超过 MaxBackoff。
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter 是等待时间的随机抖动比例（0~1），实际等待时间在 [d*(1-Jitter), d*(1+Jitter)] 内均匀分布。
+	Jitter float64
+
+	// Classifier 判断错误是否可重试，为 nil 时使用 DefaultClassifier()。
+	Classifier Classifier
+
+	// OnRetry 在每次重试等待前调用，可用于记录日志。
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// DefaultPolicy 返回默认策略：最多 3 次尝试，退避 500ms 起按 2 倍增长至最多 5s，抖动 20%。
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		Classifier:     DefaultClassifier(),
+	}
+}
+
+// Backoff 返回第 attempt 次失败（从 1 开始）之后、下一次尝试之前的等待时间，已叠加抖动。
+func (p *Policy) Backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 1; i < attempt; i++ {
+		d *= multiplier
+		if p.MaxBackoff > 0 && d >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// Do 调用 fn，失败且可重试时按退避策略等待后再次调用，直到成功、遇到不可重试的错误、
+// 达到 MaxAttempts 或 ctx 结束。fn 每次都应重新构造并签名请求，因为签名中的 date 会过期。
+// fn 收到的 ctx 携带本次调用的序号，可通过 Attempt 读取。
+// 返回最后一次调用的错误；等待期间 ctx 结束时返回包装了 ctx.Err() 与最后一次错误的错误。
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p == nil || p.MaxAttempts <= 1 {
+		return fn(withAttempt(ctx, 1))
+	}
+	classify := p.Classifier
+	if classify == nil {
+		classify = DefaultClassifier()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(withAttempt(ctx, attempt))
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !classify(err) {
+			return err
+		}
+
+		wait := p.Backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+type attemptKey struct{}
+
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// Attempt 返回 Policy.Do 传给 fn 的 ctx 中的调用序号（从 1 开始），不在 Do 中时返回 1。
+func Attempt(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
@@ -0,0 +1,170 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+func fastPolicy(attempts int) *Policy {
+	return &Policy{MaxAttempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Multiplier: 2}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := &Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d): expected %v, got %v", i+1, w, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("Expected jittered backoff within [50ms, 150ms], got %v", got)
+		}
+	}
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	classify := DefaultClassifier(DefaultRetryableCodes...)
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network", err: &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("connection reset")}, want: true},
+		{name: "5xx", err: &xfyunerr.APIError{HTTPStatus: 502}, want: true},
+		{name: "429", err: &xfyunerr.APIError{HTTPStatus: 429}, want: true},
+		{name: "401", err: &xfyunerr.APIError{HTTPStatus: 401}, want: false},
+		{name: "qps limit", err: &xfyunerr.APIError{Code: 11202}, want: true},
+		{name: "invalid param", err: &xfyunerr.APIError{Code: 10106}, want: false},
+		{name: "canceled", err: &url.Error{Op: "Post", URL: "https://example.com", Err: context.Canceled}, want: false},
+		{name: "send failed", err: &xfyunerr.NetworkError{Service: "ocr", Err: errors.New("connection refused")}, want: true},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultClassifier_Catalog(t *testing.T) {
+	classify := DefaultClassifier()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "engine busy", err: &xfyunerr.APIError{Service: "ocr", Code: 10700}, want: true},
+		{name: "schema invalid", err: &xfyunerr.APIError{Service: "ocr", Code: 10163}, want: false},
+		{name: "ist rate limited", err: &xfyunerr.APIError{Service: "ist", Code: 26603}, want: true},
+		{name: "ist out of balance", err: &xfyunerr.APIError{Service: "ist", Code: 26625}, want: false},
+		{name: "unknown code", err: &xfyunerr.APIError{Service: "ocr", Code: 99999}, want: false},
+		{name: "5xx", err: &xfyunerr.APIError{Service: "ocr", HTTPStatus: 503}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// DefaultPolicy 按错误码目录分类，不在 DefaultRetryableCodes 中的服务专有错误码同样会重试。
+func TestDefaultPolicy_RetriesCatalogCodes(t *testing.T) {
+	p := DefaultPolicy()
+	p.InitialBackoff, p.MaxBackoff, p.Jitter = time.Millisecond, time.Millisecond, 0
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &xfyunerr.APIError{Service: "ist", Code: 26603}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("Expected ist 26603 to be retried once, got %d calls and %v", calls, err)
+	}
+}
+
+func TestDefaultRetryableCodes_InCatalog(t *testing.T) {
+	for _, code := range DefaultRetryableCodes {
+		if e, ok := xfyunerr.Lookup(xfyunerr.CatalogCommon, code); !ok || !e.Retryable {
+			t.Errorf("Expected %d to be retryable in the catalogue", code)
+		}
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	t.Run("retries until success", func(t *testing.T) {
+		calls := 0
+		err := fastPolicy(3).Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			if Attempt(ctx) != calls {
+				t.Errorf("Expected attempt %d, got %d", calls, Attempt(ctx))
+			}
+			if calls < 3 {
+				return &xfyunerr.APIError{HTTPStatus: 503}
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Fatalf("Expected success after 3 calls, got err=%v calls=%d", err, calls)
+		}
+	})
+
+	t.Run("stops at max attempts", func(t *testing.T) {
+		calls := 0
+		err := fastPolicy(2).Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return &xfyunerr.APIError{Code: 10700}
+		})
+		var apiErr *xfyunerr.APIError
+		if !errors.As(err, &apiErr) || calls != 2 {
+			t.Fatalf("Expected last APIError after 2 calls, got err=%v calls=%d", err, calls)
+		}
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		calls := 0
+		_ = fastPolicy(5).Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return &xfyunerr.APIError{Code: 10106}
+		})
+		if calls != 1 {
+			t.Fatalf("Expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("nil policy calls once", func(t *testing.T) {
+		calls := 0
+		var p *Policy
+		_ = p.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return &xfyunerr.APIError{HTTPStatus: 503}
+		})
+		if calls != 1 {
+			t.Fatalf("Expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := &Policy{MaxAttempts: 5, InitialBackoff: time.Hour}
+		p.OnRetry = func(int, error, time.Duration) { cancel() }
+		err := p.Do(ctx, func(ctx context.Context) error { return &xfyunerr.APIError{HTTPStatus: 503} })
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
@@ -2,26 +2,35 @@ package detectlanguage
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
 	"github.com/fruitbars/goxfyunclient/pkg/service/detectlanguage/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 )
 
 const (
+	// Deprecated: 默认地址由 endpoint.Default 提供，该常量仅为兼容保留。
 	RequestURL = "https://cn-huadong-1.xf-yun.com/v1/private/s0ed5898e"
+
+	// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+	serviceName = "detectlanguage"
 )
 
 type Client struct {
@@ -31,6 +40,34 @@ type Client struct {
 	Logger     *slog.Logger
 	Host       string
 	HTTPClient *http.Client // <--- 添加此字段
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证，AppID/APIKey/APISecret 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 HMAC 签名，可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Retry 是失败时的重试策略，为 nil 时不重试。每次重试都会重新签名。
+	Retry *retry.Policy
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每次调用记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是允许的最大响应体字节数，超出时返回 *xfyunerr.ResponseTooLargeError；
+	// 小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// Fallbacks 是 Host 连接失败时依次尝试的备用地址，默认取自 endpoint.Default。
+	Fallbacks []string
+
+	// middlewares 在 NewClient 中包装 HTTPClient 的 Transport。
+	middlewares []middleware.Middleware
 }
 
 // Option is a function that configures a Client.
@@ -50,6 +87,89 @@ func WithHost(host string) Option {
 	return func(c *Client) {
 		if host != "" {
 			c.Host = host
+			c.Fallbacks = nil
+		}
+	}
+}
+
+// WithEndpoints sets the primary endpoint and the fallbacks tried in order when it is unreachable,
+// e.g. the result of endpoint.Default.Resolve.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		if len(urls) > 0 {
+			c.Host, c.Fallbacks = urls[0], urls[1:]
+		}
+	}
+}
+
+// WithCredentials sets the credential provider resolved on every request.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
+		}
+	}
+}
+
+// WithAuthMode selects whether the signature is sent in the URL query (auth.ModeQuery)
+// or in the Authorization/Date/Host headers (auth.ModeHeader).
+func WithAuthMode(mode auth.Mode) Option {
+	return func(c *Client) {
+		c.AuthMode = mode
+	}
+}
+
+// WithRetry enables retries with the given policy, e.g. retry.DefaultPolicy().
+func WithRetry(policy *retry.Policy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies; larger responses fail with
+// *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
+	}
+}
+
+// WithMiddleware wraps the transport of the HTTP client with mws, e.g. a proxy, mTLS or audit layer.
+// Middlewares apply to every request including retries, in order: the first one is outermost.
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client, e.g. one with a custom Transport or Timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.HTTPClient = client
 		}
 	}
 }
@@ -59,101 +179,130 @@ func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 		AppID:     appID,
 		APIKey:    apiKey,
 		APISecret: apiSecret,
-		Host:      RequestURL,
 		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer:    auth.DefaultSigner,
 		HTTPClient: &http.Client{ // <--- 在这里初始化
 			Timeout: 10 * time.Second,
 		},
 	}
+	c.Host, c.Fallbacks = endpoint.Default.Lookup(serviceName)
+
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.HTTPClient = middleware.WrapClient(c.HTTPClient, c.middlewares...)
 	return c
 }
 
+// Detect 识别文本的语种，等价于使用 context.Background() 调用 DetectContext。
 func (c *Client) Detect(text string) (string, error) {
-	requestData := c.getRequestData(text)
-	jsonData, err := json.Marshal(requestData)
-	if err != nil {
-		return "", fmt.Errorf("请求数据JSON编码失败: %w", err)
-	}
+	return c.DetectContext(context.Background(), text)
+}
+
+// DetectContext 识别文本的语种。每次尝试都重新解析凭证，轮换后的密钥在重试中立即生效。
+func (c *Client) DetectContext(ctx context.Context, text string) (string, error) {
+	var result string
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		creds, err := c.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		jsonData, err := json.Marshal(c.getRequestData(creds.AppID, text))
+		if err != nil {
+			return fmt.Errorf("请求数据JSON编码失败: %w", err)
+		}
+		ctx, call := c.Telemetry.Start(ctx, serviceName, "detect", c.Host)
+		call.AddBytesSent(len(jsonData))
+		result, err = endpoint.Failover(c.Logger, c.endpoints(), func(host string) (string, error) {
+			return c.send(ctx, host, creds, jsonData)
+		})
+		call.End(err)
+		return err
+	})
+	return result, err
+}
 
-	authURL, err := auth.BuildAuthURL(c.Host, "POST", c.APIKey, c.APISecret, auth.SchemeTypeAPIKey)
+// DetectProbs 识别文本的语种，返回各语种代码（例如 "cn"、"en"、"ar"）的概率。
+func (c *Client) DetectProbs(ctx context.Context, text string) (map[string]float64, error) {
+	lanProbs, err := c.DetectContext(ctx, text)
 	if err != nil {
-		return "", fmt.Errorf("构建认证URL失败: %w", err)
+		return nil, err
 	}
-	if err != nil {
-		return "", fmt.Errorf("构建认证URL失败: %w", err)
+	var probs map[string]float64
+	if err := json.Unmarshal([]byte(lanProbs), &probs); err != nil {
+		return nil, fmt.Errorf("解析语种概率失败: %w. lan_probs: %s", err, lanProbs)
 	}
+	return probs, nil
+}
+
+// credentials 解析本次请求使用的凭证。
+func (c *Client) credentials(ctx context.Context) (auth.Credentials, error) {
+	return auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		APIKey:    c.APIKey,
+		APISecret: c.APISecret,
+	})
+}
+
+// endpoints 返回主地址与备用地址。
+func (c *Client) endpoints() []string {
+	return append([]string{c.Host}, c.Fallbacks...)
+}
 
-	c.Logger.Debug("sending detectlanguage request", "url", authURL)
+// send 构造、签名并向 host 发送一次请求。每次重试都会调用 send 重新签名，因为签名中的 date 会过期。
+func (c *Client) send(ctx context.Context, host string, creds auth.Credentials, jsonData []byte) (string, error) {
+	release, err := c.Limiter.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
 
-	req, err := http.NewRequest("POST", authURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", host, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	if err := c.Signer.Sign(req, c.AuthMode, creds.APIKey, creds.APISecret, auth.SchemeTypeAPIKey); err != nil {
+		return "", fmt.Errorf("构建认证URL失败: %w", err)
+	}
+	redactedURL := auth.RedactURL(req.URL.String())
+
+	c.Logger.Debug("sending detectlanguage request", "url", redactedURL, "auth_mode", c.AuthMode)
+
 	//client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		c.Logger.Error("sending detectlanguage request failed", "url", authURL, "error", err)
+		err = &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)}
+		c.Logger.Error("sending detectlanguage request failed", "url", redactedURL, "error", err)
 		return "", fmt.Errorf("发送HTTP请求失败: %w", err)
 	}
 	defer resp.Body.Close()
+	c.Signer.ObserveResponse(resp)
 
-	return c.dealResponse(resp)
-}
-
-func (c *Client) buildAuthRequestURL(requestURL, method string) (string, error) {
-	parsedURL, err := url.Parse(requestURL)
-	if err != nil {
-		return "", fmt.Errorf("error parsing URL: %w", err)
-	}
-
-	date := time.Now().UTC().Format(http.TimeFormat)
-
-	signatureOrigin := fmt.Sprintf("host: %s\ndate: %s\n%s %s HTTP/1.1",
-		parsedURL.Host,
-		date,
-		strings.ToUpper(method),
-		parsedURL.RequestURI(),
-	)
-
-	mac := hmac.New(sha256.New, []byte(c.APISecret))
-	mac.Write([]byte(signatureOrigin))
-	signatureSha := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	authorizationOrigin := fmt.Sprintf(
-		`api_key="%s", algorithm="%s", headers="%s", signature="%s"`,
-		c.APIKey, "hmac-sha256", "host date request-line", signatureSha,
-	)
-
-	authorization := base64.StdEncoding.EncodeToString([]byte(authorizationOrigin))
-
-	queryParams := url.Values{}
-	queryParams.Add("host", parsedURL.Host)
-	queryParams.Add("date", date)
-	queryParams.Add("authorization", authorization)
-
-	return fmt.Sprintf("%s?%s", requestURL, queryParams.Encode()), nil
+	return c.dealResponse(ctx, resp)
 }
 
-func (c *Client) prepareReqData(text string) (models.RequestData, error) {
-	data := c.getRequestData(text)
-	data.Header.AppID = c.AppID
+func (c *Client) prepareReqData(appID, text string) (models.RequestData, error) {
+	data := c.getRequestData(appID, text)
+	data.Header.AppID = appID
 	data.Payload.Request.Text = base64.StdEncoding.EncodeToString([]byte(text))
 	return data, nil
 }
 
-func (c *Client) dealResponse(resp *http.Response) (string, error) {
-	body, err := io.ReadAll(resp.Body)
+func (c *Client) dealResponse(ctx context.Context, resp *http.Response) (string, error) {
+	body, err := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 	if err != nil {
 		return "", fmt.Errorf("error reading response body: %w", err)
 	}
 
 	c.Logger.Debug("received response", "status_code", resp.StatusCode, "body", string(body))
 
+	// 网关鉴权失败时返回非 200 状态码和 {"message": ...}，不含 header.code
+	if resp.StatusCode != http.StatusOK {
+		return "", xfyunerr.FromStatus(serviceName, resp.StatusCode, body)
+	}
+
 	var responseData models.ASELanguageDetectResponse
 	if err := json.Unmarshal(body, &responseData); err != nil {
 		return "", fmt.Errorf("error parsing response JSON: %w. Raw response: %s", err, string(body))
@@ -165,9 +314,9 @@ func (c *Client) dealResponse(resp *http.Response) (string, error) {
 			"message", responseData.Header.Message,
 			"sid", responseData.Header.Sid,
 		)
-		return "", fmt.Errorf("xfyun API error. Code: %d, Message: %s, sid: %s",
-			responseData.Header.Code, responseData.Header.Message, responseData.Header.Sid)
+		return "", &xfyunerr.APIError{Service: serviceName, Code: responseData.Header.Code, Message: responseData.Header.Message, SID: responseData.Header.Sid, HTTPStatus: http.StatusOK}
 	}
+	telemetry.SetSID(ctx, responseData.Header.Sid)
 
 	if responseData.Payload.Result.Text == "" {
 		return "", fmt.Errorf("empty result from xfyun API. sid: %s", responseData.Header.Sid)
@@ -191,12 +340,12 @@ func (c *Client) dealResponse(resp *http.Response) (string, error) {
 	return ldresult.TransResult[0].LanProbs, nil
 }
 
-func (c *Client) getRequestData(text string) models.RequestData {
+func (c *Client) getRequestData(appID, text string) models.RequestData {
 	b64Text := base64.StdEncoding.EncodeToString([]byte(text))
 	uid := strings.ReplaceAll(uuid.New().String(), "-", "")
 	return models.RequestData{
 		Header: models.RequestHeader{
-			AppID:  c.AppID, // Will be set in main
+			AppID:  appID,
 			UID:    uid,
 			Status: 3,
 		},
@@ -1,13 +1,19 @@
 package iocrld
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
 	"github.com/fruitbars/goxfyunclient/pkg/service/iocrld/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"io"
 	"log/slog"
 	"net/http"
@@ -16,7 +22,8 @@ import (
 )
 
 const (
-	defaultHost = "https://cn-huabei-1.xf-yun.com/v1/private/s15fc3900"
+	// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+	serviceName = "iocrld"
 )
 
 // Client 封装了讯飞私有接口调用（签名、请求、解析）
@@ -27,6 +34,34 @@ type Client struct {
 	Host       string
 	Logger     *slog.Logger
 	HTTPClient *http.Client
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证，AppID/APIKey/APISecret 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 HMAC 签名，可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Retry 是失败时的重试策略，为 nil 时不重试。每次重试都会重新签名。
+	Retry *retry.Policy
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每次调用记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是允许的最大响应体字节数，超出时返回 *xfyunerr.ResponseTooLargeError；
+	// 小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// Fallbacks 是 Host 连接失败时依次尝试的备用地址，默认取自 endpoint.Default。
+	Fallbacks []string
+
+	// middlewares 在 NewClient 中包装 HTTPClient 的 Transport。
+	middlewares []middleware.Middleware
 }
 
 // Option is a function that configures a Client.
@@ -37,6 +72,17 @@ func WithHost(host string) Option {
 	return func(c *Client) {
 		if host != "" {
 			c.Host = host
+			c.Fallbacks = nil
+		}
+	}
+}
+
+// WithEndpoints sets the primary endpoint and the fallbacks tried in order when it is unreachable,
+// e.g. the result of endpoint.Default.Resolve.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		if len(urls) > 0 {
+			c.Host, c.Fallbacks = urls[0], urls[1:]
 		}
 	}
 }
@@ -59,21 +105,86 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithCredentials sets the credential provider resolved on every request.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
+		}
+	}
+}
+
+// WithAuthMode selects whether the signature is sent in the URL query (auth.ModeQuery)
+// or in the Authorization/Date/Host headers (auth.ModeHeader).
+func WithAuthMode(mode auth.Mode) Option {
+	return func(c *Client) {
+		c.AuthMode = mode
+	}
+}
+
+// WithRetry enables retries with the given policy, e.g. retry.DefaultPolicy().
+func WithRetry(policy *retry.Policy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies; larger responses fail with
+// *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
+	}
+}
+
+// WithMiddleware wraps the transport of the HTTP client with mws, e.g. a proxy, mTLS or audit layer.
+// Middlewares apply to every request including retries, in order: the first one is outermost.
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
 func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 	c := &Client{
 		AppID:     appID,
 		APIKey:    apiKey,
 		APISecret: apiSecret,
-		Host:      defaultHost,
 		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer:    auth.DefaultSigner,
 		HTTPClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
+	c.Host, c.Fallbacks = endpoint.Default.Lookup(serviceName)
 
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.HTTPClient = middleware.WrapClient(c.HTTPClient, c.middlewares...)
 	return c
 }
 
@@ -84,28 +195,32 @@ type Result struct {
 	Text        string // 解析后的文本（已尽力从 base64 解码为字符串）
 }
 
-// APIError 代表讯飞返回的业务错误（header.code != 0）
-type APIError struct {
-	Code    int
-	Message string
-	SID     string
-}
+// APIError 代表讯飞返回的业务错误（header.code != 0）。
+//
+// Deprecated: 所有客户端统一返回 *xfyunerr.APIError，该别名仅为兼容保留。
+type APIError = xfyunerr.APIError
 
-func (e *APIError) Error() string {
-	return fmt.Sprintf("iFlytek API error: code=%d, sid=%s, message=%s", e.Code, e.SID, e.Message)
+// Process 识别 base64 编码的图片 picBase64，customParams 为叠加的业务数据。
+func (c *Client) Process(ctx context.Context, trackID string, picBase64 string, customParams map[string]interface{}) (*models.Response, error) {
+	if strings.TrimSpace(picBase64) == "" {
+		return nil, fmt.Errorf("pictureBase64 is empty")
+	}
+	return c.process(ctx, trackID, stream.Encoded(picBase64), customParams)
 }
 
-func (c *Client) Process(ctx context.Context, trackID string, picBase64 string, customParams map[string]interface{}) (*models.Response, error) {
-	if strings.TrimSpace(c.AppID) == "" || strings.TrimSpace(c.APIKey) == "" || strings.TrimSpace(c.APISecret) == "" {
-		return nil, fmt.Errorf("missing credentials: AppID/APIKey/APISecret are required")
+// ProcessBytes 与 Process 相同，但接收原始图片字节，发送时流式编码，不生成完整的 base64 字符串。
+func (c *Client) ProcessBytes(ctx context.Context, trackID string, image []byte, customParams map[string]interface{}) (*models.Response, error) {
+	if len(image) == 0 {
+		return nil, fmt.Errorf("image is empty")
 	}
+	return c.process(ctx, trackID, stream.Bytes(image), customParams)
+}
+
+// process 发送识别请求。每次尝试都重新解析凭证，轮换后的密钥在重试中立即生效。
+func (c *Client) process(ctx context.Context, trackID string, image stream.Source, customParams map[string]interface{}) (*models.Response, error) {
 	if strings.TrimSpace(c.Host) == "" {
 		return nil, fmt.Errorf("missing endpoint")
 	}
-	if strings.TrimSpace(picBase64) == "" {
-		return nil, fmt.Errorf("pictureBase64 is empty")
-	}
-
 	var jsonDataRaw json.RawMessage
 	if customParams != nil {
 		jb, err := json.Marshal(customParams)
@@ -120,7 +235,6 @@ func (c *Client) Process(ctx context.Context, trackID string, picBase64 string,
 	// --- 1) 组装请求 ---
 	reqBody := models.Request{
 		Header: models.Header{
-			AppID:     c.AppID,
 			RequestID: &trackID,
 			Status:    3,
 		},
@@ -147,41 +261,97 @@ func (c *Client) Process(ctx context.Context, trackID string, picBase64 string,
 			Image: models.ImagePayload{
 				Encoding: "jpg",
 				Status:   3,
-				Image:    picBase64,
+				Image:    stream.Placeholder,
 			},
 		},
 	}
 
-	authURL, err := auth.BuildAuthURL(c.Host, "POST", c.APIKey, c.APISecret, auth.SchemeTypeAPIKey)
+	var ifResp *models.Response
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		creds, err := c.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		reqBody.Header.AppID = creds.AppID
+		data, err := stream.JSON(reqBody, image)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		ctx, call := c.Telemetry.Start(ctx, serviceName, "process", c.Host)
+		call.AddBytesSent(int(data.Len()))
+		ifResp, err = endpoint.Failover(c.Logger, c.endpoints(), func(host string) (*models.Response, error) {
+			return c.send(ctx, host, creds, data)
+		})
+		if err == nil {
+			telemetry.SetSID(ctx, ifResp.Header.SID)
+		}
+		call.End(err)
+		return err
+	})
+	return ifResp, err
+}
+
+// credentials 解析本次请求使用的凭证并校验其完整性。
+func (c *Client) credentials(ctx context.Context) (auth.Credentials, error) {
+	creds, err := auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		APIKey:    c.APIKey,
+		APISecret: c.APISecret,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("build auth url failed: %w", err)
+		return auth.Credentials{}, err
 	}
+	if strings.TrimSpace(creds.AppID) == "" || strings.TrimSpace(creds.APIKey) == "" || strings.TrimSpace(creds.APISecret) == "" {
+		return auth.Credentials{}, fmt.Errorf("missing credentials: AppID/APIKey/APISecret are required")
+	}
+	return creds, nil
+}
 
-	data, err := json.Marshal(reqBody)
+// endpoints 返回主地址与备用地址。
+func (c *Client) endpoints() []string {
+	return append([]string{c.Host}, c.Fallbacks...)
+}
+
+// send 构造、签名并向 host 发送一次请求。每次重试都会调用 send 重新签名，因为签名中的 date 会过期。
+func (c *Client) send(ctx context.Context, host string, creds auth.Credentials, data *stream.Body) (*models.Response, error) {
+	release, err := c.Limiter.Acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, err
 	}
+	defer release()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, bytes.NewBuffer(data))
+	req, err := data.NewRequest(ctx, http.MethodPost, host)
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	c.Logger.Debug("sending iocrld request", "url", authURL)
+	if err := c.Signer.Sign(req, c.AuthMode, creds.APIKey, creds.APISecret, auth.SchemeTypeAPIKey); err != nil {
+		return nil, fmt.Errorf("build auth url failed: %w", err)
+	}
+	redactedURL := auth.RedactURL(req.URL.String())
+
+	c.Logger.Debug("sending iocrld request", "url", redactedURL, "auth_mode", c.AuthMode)
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		c.Logger.Error("sending iocrld request failed", "url", authURL, "error", err)
+		err = &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)}
+		c.Logger.Error("sending iocrld request failed", "url", redactedURL, "error", err)
 		return nil, fmt.Errorf("do request failed: %w", err)
 	}
 	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
+	c.Signer.ObserveResponse(resp)
+	b, err := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	c.Logger.Debug("received iocrld response", "status_code", resp.StatusCode, "body_snippet", string(b[:min(512, len(b))]))
 
+	// 网关鉴权失败时返回非 200 状态码和 {"message": ...}，不含 header.code
+	if resp.StatusCode != http.StatusOK {
+		return nil, xfyunerr.FromStatus(serviceName, resp.StatusCode, b)
+	}
+
 	// --- 3) 解析响应 ---
 	var ifResp models.Response
 	if err := json.Unmarshal(b, &ifResp); err != nil {
@@ -194,9 +364,11 @@ func (c *Client) Process(ctx context.Context, trackID string, picBase64 string,
 			"sid", ifResp.Header.SID,
 		)
 		return nil, &APIError{
-			Code:    ifResp.Header.Code,
-			Message: ifResp.Header.Message,
-			SID:     ifResp.Header.SID,
+			Service:    serviceName,
+			Code:       ifResp.Header.Code,
+			Message:    ifResp.Header.Message,
+			SID:        ifResp.Header.SID,
+			HTTPStatus: resp.StatusCode,
 		}
 	}
 
@@ -4,23 +4,22 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"goxfyunclient/internal/service/iocrld/models"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/fruitbars/goxfyunclient/pkg/service/iocrld/models"
 )
 
 func TestClient_Process_Success(t *testing.T) {
+	encodedText := base64.StdEncoding.EncodeToString([]byte(`{"pages": [{"angle": 0}]}`))
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 模拟成功响应
-		responseText := `{"pages": [{"angle": 0}]}`
-		encodedText := base64.StdEncoding.EncodeToString([]byte(responseText))
-
 		resp := models.Response{
-			Header: models.Header{Code: 0, Message: "Success", SID: "test-sid"},
-			Payload: models.Payload{
-				JSON:  models.JSONPayload{Text: encodedText},
-				Image: models.ImagePayload{Image: "dummy-image-base64"},
+			Header: models.ResponseHeader{Code: 0, Message: "Success", SID: "test-sid"},
+			Payload: models.ResponsePayload{
+				JSON:  models.ResponseJSONPayload{Text: encodedText},
+				Image: models.ResponseImagePayload{Image: "dummy-image-base64"},
 			},
 		}
 		json.NewEncoder(w).Encode(resp)
@@ -33,13 +32,13 @@ func TestClient_Process_Success(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
-	if result.SID != "test-sid" {
-		t.Errorf("Expected sid 'test-sid', got '%s'", result.SID)
+	if result.Header.SID != "test-sid" {
+		t.Errorf("Expected sid 'test-sid', got '%s'", result.Header.SID)
 	}
-	if result.Text != `{"pages": [{"angle": 0}]}` {
-		t.Errorf("Unexpected result text: %s", result.Text)
+	if result.Payload.JSON.Text != encodedText {
+		t.Errorf("Unexpected result text: %s", result.Payload.JSON.Text)
 	}
-	if result.ImageBase64 != "dummy-image-base64" {
+	if result.Payload.Image.Image != "dummy-image-base64" {
 		t.Error("ImageBase64 was not correctly extracted from response")
 	}
 }
@@ -47,7 +46,7 @@ func TestClient_Process_Success(t *testing.T) {
 func TestClient_Process_ApiError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := models.Response{
-			Header: models.Header{Code: 10110, Message: "some error", SID: "error-sid"},
+			Header: models.ResponseHeader{Code: 10110, Message: "some error", SID: "error-sid"},
 		}
 		json.NewEncoder(w).Encode(resp)
 	}))
@@ -1,15 +1,17 @@
 package ist
 
 import (
-	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/md5"
-	"crypto/sha1"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
 	"github.com/fruitbars/goxfyunclient/pkg/service/ist/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"io"
 	"log/slog"
 	"net/http"
@@ -23,12 +25,17 @@ import (
 )
 
 const (
-	defaultHost     = "https://raasr.xfyun.cn/v2/api"
-	apiUpload       = "/upload"
-	apiGetResult    = "/getResult"
-	pollingInterval = 5 * time.Second
+	apiUpload    = "/upload"
+	apiGetResult = "/getResult"
+	codeSuccess  = "000000"
+
+	// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+	serviceName = "ist"
 )
 
+// pollingInterval 是查询转写结果的间隔，测试中会调小。
+var pollingInterval = 5 * time.Second
+
 // Client holds the configuration for the iFlytek API client.
 type Client struct {
 	AppID        string
@@ -37,6 +44,25 @@ type Client struct {
 	UploadClient *http.Client
 	Logger       *slog.Logger
 	Host         string
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证（使用 AppID 与 SecretKey），AppID/SecretKey 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 signa 签名（auth.SchemeTypeLFASR），可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每次调用记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是允许的最大响应体字节数，超出时返回 *xfyunerr.ResponseTooLargeError；
+	// 小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// middlewares 在 NewClient 中包装 HTTPClient 与 UploadClient 的 Transport。
+	middlewares []middleware.Middleware
 }
 
 // Option is a function that configures a Client.
@@ -72,12 +98,59 @@ func WithHTTPClients(uploadClient, defaultClient *http.Client) Option {
 	}
 }
 
+// WithCredentials sets the credential provider resolved on every request.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
+		}
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies; larger responses fail with
+// *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
+	}
+}
+
+// WithMiddleware wraps the transports of both HTTP clients (see WithHTTPClients) with mws, e.g. a proxy, mTLS or audit layer.
+// Middlewares apply to every request including retries, in order: the first one is outermost.
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
 // NewClient creates a new iFlytek LFAASR API client.
 func NewClient(appID, secretKey string, opts ...Option) *Client {
 	c := &Client{
 		AppID:     appID,
 		SecretKey: secretKey,
-		Host:      defaultHost,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second, // Set a reasonable timeout for general API calls
 		},
@@ -85,29 +158,49 @@ func NewClient(appID, secretKey string, opts ...Option) *Client {
 			Timeout: 10 * time.Minute, // Set a longer timeout for file uploads
 		},
 		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer: auth.DefaultSigner,
 	}
+	// 订单只存在于接受上传的地址上，因此 ist 不使用备用地址
+	c.Host, _ = endpoint.Default.Lookup(serviceName)
 
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.HTTPClient = middleware.WrapClient(c.HTTPClient, c.middlewares...)
+	c.UploadClient = middleware.WrapClient(c.UploadClient, c.middlewares...)
 	return c
 }
 
-// getSignature generates the required signature for API authentication.
-func (c *Client) getSignature(ts string) string {
-	md5Hash := md5.New()
-	md5Hash.Write([]byte(c.AppID + ts))
-	md5Str := fmt.Sprintf("%x", md5Hash.Sum(nil))
-
-	mac := hmac.New(sha1.New, []byte(c.SecretKey))
-	mac.Write([]byte(md5Str))
-	hmacSha1 := mac.Sum(nil)
+// credentials 解析本次请求使用的凭证。
+func (c *Client) credentials(ctx context.Context) (auth.Credentials, error) {
+	creds, err := auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		SecretKey: c.SecretKey,
+	})
+	if err != nil {
+		return auth.Credentials{}, err
+	}
+	if creds.AppID == "" || creds.SecretKey == "" {
+		return auth.Credentials{}, fmt.Errorf("AppID/SecretKey is not configured")
+	}
+	return creds, nil
+}
 
-	return base64.StdEncoding.EncodeToString(hmacSha1)
+// signRequest 为请求追加 appId、ts、signa 查询参数。每次请求都重新签名，避免长时间轮询时 ts 过期。
+func (c *Client) signRequest(req *http.Request, creds auth.Credentials) error {
+	return c.Signer.Sign(req, auth.ModeQuery, creds.AppID, creds.SecretKey, auth.SchemeTypeLFASR)
 }
 
 // UploadFile uploads the audio file to the LFAASR service.
 func (c *Client) UploadFile(ctx context.Context, filePath string, options ...UploadOption) (string, error) {
+	ctx, call := c.Telemetry.Start(ctx, serviceName, "upload", c.Host+apiUpload)
+	orderID, err := c.uploadFile(ctx, call, filePath, options...)
+	call.End(err)
+	return orderID, err
+}
+
+// uploadFile 执行 UploadFile，call 用于记录上传的字节数。
+func (c *Client) uploadFile(ctx context.Context, call *telemetry.Call, filePath string, options ...UploadOption) (string, error) {
 	// The provided `filePath` is a URI, we need to handle different schemes.
 	// For now, we will only handle local file URIs (file://) for simplicity.
 	// And we'll treat it as a direct file path for now.
@@ -117,9 +210,15 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, options ...Upl
 		opt(opts)
 	}
 
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	c.Logger.Debug("upload options", "audio_mode", opts.AudioMode, "audio_url", opts.AudioURL)
 
 	var body io.Reader
+	var contentLength int64
 	var fileSize, fileName string
 	if opts.AudioMode == "urlLink" {
 		if opts.AudioURL == "" {
@@ -151,12 +250,9 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, options ...Upl
 
 		c.Logger.Debug("file info", "name", fileInfo.Name(), "size", fileInfo.Size())
 
-		fileBytes, err := io.ReadAll(file)
-		if err != nil {
-			c.Logger.Error("failed to read file content", "path", filePath, "error", err)
-			return "", fmt.Errorf("failed to read file content: %w", err)
-		}
-		body = bytes.NewReader(fileBytes)
+		// 直接以文件作为请求体流式上传，不把整个音频读入内存
+		body, contentLength = file, fileInfo.Size()
+		call.AddBytesSent(int(contentLength))
 
 		fileSize = strconv.FormatInt(fileInfo.Size(), 10)
 		fileName = filepath.Base(filePath)
@@ -164,12 +260,7 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, options ...Upl
 
 	c.Logger.Debug("upload parameters", "file_size", fileSize, "file_name", fileName)
 
-	ts := strconv.FormatInt(time.Now().Unix(), 10)
-
 	params := url.Values{}
-	params.Set("appId", c.AppID)
-	params.Set("signa", c.getSignature(ts))
-	params.Set("ts", ts)
 	if fileSize != "" {
 		params.Set("fileSize", fileSize)
 	}
@@ -189,24 +280,42 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, options ...Upl
 		}
 	}
 
-	uploadURL := c.Host + apiUpload + "?" + params.Encode()
-	c.Logger.Info("uploading to url", "url", uploadURL)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Host+apiUpload+"?"+params.Encode(), body)
 	if err != nil {
 		return "", fmt.Errorf("failed to create upload request: %w", err)
 	}
+	req.ContentLength = contentLength
+	if body != nil {
+		// Transport 发送后会关闭文件，重定向或中间件重放请求时重新打开
+		req.GetBody = func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		}
+	}
+
+	// 先等待限流再签名，避免等待期间 ts 过期
+	release, err := c.Limiter.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	if err := c.signRequest(req, creds); err != nil {
+		return "", fmt.Errorf("failed to sign upload request: %w", err)
+	}
+	uploadURL := req.URL.String()
+	c.Logger.Info("uploading to url", "url", auth.RedactURL(uploadURL))
 
 	req.Header.Set("Content-Type", "application/octet-stream")
 
 	resp, err := c.UploadClient.Do(req)
 	if err != nil {
-		c.Logger.Error("failed to execute upload request", "url", uploadURL, "error", err)
+		err = &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)}
+		c.Logger.Error("failed to execute upload request", "url", auth.RedactURL(uploadURL), "error", err)
 		return "", fmt.Errorf("failed to execute upload request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, err := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 	if err != nil {
 		c.Logger.Error("failed to read upload response body", "error", err)
 		return "", fmt.Errorf("failed to read response body: %w", err)
@@ -219,7 +328,7 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, options ...Upl
 			"status", resp.Status,
 			"response_body", respBodyShort,
 		)
-		return "", fmt.Errorf("Status: %s, response body: %s", resp.Status, respBodyShort)
+		return "", xfyunerr.FromStatus(serviceName, resp.StatusCode, bodyBytes)
 	}
 
 	c.Logger.Debug("upload response", "headers", resp.Header, "status", resp.Status, "body_snippet", respBodyShort)
@@ -230,12 +339,12 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, options ...Upl
 		return "", fmt.Errorf("failed to decode upload JSON response: %w", err)
 	}
 
-	if uploadResp.Code != "000000" {
+	if uploadResp.Code != codeSuccess {
 		c.Logger.Error("upload api returned an error",
 			"code", uploadResp.Code,
 			"description", uploadResp.DescInfo,
 		)
-		return "", fmt.Errorf("upload failed with code %s: %s", uploadResp.Code, uploadResp.DescInfo)
+		return "", &xfyunerr.APIError{Service: serviceName, Code: xfyunerr.ParseCode(uploadResp.Code), Message: uploadResp.DescInfo, HTTPStatus: resp.StatusCode}
 	}
 
 	return uploadResp.Content.OrderID, nil
@@ -268,13 +377,7 @@ func (c *Client) Process(ctx context.Context, filePath string, options ...Upload
 
 // GetTranscriptionResult polls the API to get the final transcription result.
 func (c *Client) GetTranscriptionResult(ctx context.Context, orderID string, resultType string) (*models.GetResultResponse, error) {
-	ts := strconv.FormatInt(time.Now().Unix(), 10)
-	sign := c.getSignature(ts)
-
 	params := url.Values{}
-	params.Set("appId", c.AppID)
-	params.Set("signa", sign)
-	params.Set("ts", ts)
 	params.Set("orderId", orderID)
 	params.Set("resultType", resultType)
 
@@ -301,6 +404,9 @@ func (c *Client) GetTranscriptionResult(ctx context.Context, orderID string, res
 			return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
 		case <-ticker.C:
 			result, done, err := c.pollForResult(ctx, resultURL, orderID)
+			if done {
+				return result, err // 返回最终结果（成功、任务失败或不可重试的错误）
+			}
 			if err != nil {
 				consecutiveFailures++
 				c.Logger.Warn("error polling for result, will retry", "order_id", orderID, "failures", consecutiveFailures, "error", err)
@@ -311,9 +417,6 @@ func (c *Client) GetTranscriptionResult(ctx context.Context, orderID string, res
 			}
 
 			consecutiveFailures = 0 // 成功通信后重置计数器
-			if done {
-				return result, err // 返回最终结果（成功或失败）
-			}
 			// 如果没完成 (done == false)，则继续等待下一个 tick
 		}
 	}
@@ -321,28 +424,46 @@ func (c *Client) GetTranscriptionResult(ctx context.Context, orderID string, res
 
 // in client.go
 
-// pollForResult 执行单次的结果轮询。
+// pollForResult 执行单次的结果轮询。每次轮询都重新解析凭证，长时间转写期间轮换的密钥会在下一次轮询生效。
 // 它返回最终结果、一个布尔值表示任务是否已终结（成功或失败），以及本次轮询遇到的任何错误。
 func (c *Client) pollForResult(ctx context.Context, resultURL, orderID string) (result *models.GetResultResponse, done bool, err error) {
+	ctx, call := c.Telemetry.Start(ctx, serviceName, "getResult", resultURL)
+	defer func() { call.End(err) }()
+
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		// 凭证缺失或无法解析时继续轮询也不会成功
+		return nil, true, err
+	}
+
 	// 1. 创建并发送 HTTP 请求
 	req, err := http.NewRequestWithContext(ctx, "POST", resultURL, nil)
 	if err != nil {
 		// 这是一个不可恢复的错误（对于本次尝试而言），应向上层报告
 		return nil, false, fmt.Errorf("failed to create result request: %w", err)
 	}
+	release, err := c.Limiter.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer release()
+
+	if err := c.signRequest(req, creds); err != nil {
+		return nil, false, fmt.Errorf("failed to sign result request: %w", err)
+	}
 	req.Header.Set("Content-Type", "application/json")
 
-	c.Logger.Debug("polling for transcription result", "url", resultURL, "order_id", orderID)
+	c.Logger.Debug("polling for transcription result", "url", auth.RedactURL(req.URL.String()), "order_id", orderID)
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
 		// 网络错误，任务未终结，但本次轮询失败
-		return nil, false, fmt.Errorf("failed to execute polling request: %w", err)
+		return nil, false, fmt.Errorf("failed to execute polling request: %w", &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)})
 	}
 	defer resp.Body.Close()
 
 	// 2. 读取和解析响应体
-	body, err := io.ReadAll(resp.Body)
+	body, err := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 	if err != nil {
 		// 读取响应体失败，任务未终结，但本次轮询失败
 		return nil, false, fmt.Errorf("failed to read polling response body: %w", err)
@@ -357,7 +478,16 @@ func (c *Client) pollForResult(ctx context.Context, resultURL, orderID string) (
 		return nil, false, fmt.Errorf("failed to decode result json: %w (body: %s)", err, bodySnippet)
 	}
 
-	// 3. 根据 API 返回的状态码进行逻辑判断
+	// 3. 业务错误码：错误码目录中标记为不可重试的错误（鉴权、参数等）不会自行恢复，直接结束轮询；
+	// 目录中没有的错误码（包括无法解析的错误码）无法判断，继续轮询直到失败上限
+	if resultResp.Code != codeSuccess {
+		code := xfyunerr.ParseCode(resultResp.Code)
+		entry, known := xfyunerr.Lookup(serviceName, code)
+		apiErr := &xfyunerr.APIError{Service: serviceName, Code: code, Message: resultResp.DescInfo, HTTPStatus: resp.StatusCode}
+		return nil, known && !entry.Retryable, apiErr
+	}
+
+	// 4. 根据 API 返回的任务状态进行逻辑判断
 	status := resultResp.Content.OrderInfo.Status
 	switch status {
 	case 4: // 任务成功完成
@@ -369,8 +499,9 @@ func (c *Client) pollForResult(ctx context.Context, resultURL, orderID string) (
 		return nil, false, nil // 无结果, 标记为未完成, 无错误
 
 	default: // 所有其他状态码均视为最终失败
-		c.Logger.Error("transcription failed", "order_id", orderID, "status", status, "message", resultResp.DescInfo)
-		finalErr := fmt.Errorf("transcription failed with status %d, message: %s", status, resultResp.DescInfo)
+		failType := resultResp.Content.OrderInfo.FailType
+		c.Logger.Error("transcription failed", "order_id", orderID, "status", status, "fail_type", failType, "message", resultResp.DescInfo)
+		finalErr := &xfyunerr.TaskError{Service: serviceName, TaskID: orderID, Status: status, FailType: failType, Message: resultResp.DescInfo}
 		return nil, true, finalErr // 无结果, 标记为完成, 有错误
 	}
 }
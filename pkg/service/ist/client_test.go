@@ -2,64 +2,65 @@ package ist
 
 import (
 	"context"
-	"encoding/json"
-	"goxfyunclient/internal/service/ist/models"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 )
 
+// writeAudio 在临时目录中创建一个假的音频文件。
+func writeAudio(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dummy.mp3")
+	if err := os.WriteFile(path, []byte("fake audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fastPolling 加速测试中的轮询。
+func fastPolling(t *testing.T) {
+	original := pollingInterval
+	pollingInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pollingInterval = original })
+}
+
 func TestClient_Process_Success(t *testing.T) {
+	fastPolling(t)
 	orderID := "test-order-id-success"
 	pollingCount := 0
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(r.URL.Path, apiUpload) {
 			// 模拟上传成功
-			resp := models.UploadResponse{
-				Code: "000000",
-				Content: struct {
-					OrderID string `json:"orderId"`
-				}{OrderID: orderID},
-			}
-			json.NewEncoder(w).Encode(resp)
+			fmt.Fprintf(w, `{"code": "000000", "content": {"orderId": %q}}`, orderID)
 			return
 		}
 
 		if strings.Contains(r.URL.Path, apiGetResult) {
 			pollingCount++
-			var status int
-			var result string
 			// 模拟轮询：前两次返回处理中，第三次返回成功
 			if pollingCount < 3 {
-				status = 3 // Processing
-			} else {
-				status = 4 // Success
-				result = `{"key": "value"}`
+				fmt.Fprint(w, `{"code": "000000", "content": {"orderInfo": {"status": 3}}}`)
+				return
 			}
-
-			resp := models.GetResultResponse{
-				Code: "000000",
-				Content: models.GetResultContent{
-					OrderInfo:   models.OrderInfo{Status: status},
-					OrderResult: result,
-				},
-			}
-			json.NewEncoder(w).Encode(resp)
-			return
+			fmt.Fprint(w, `{"code": "000000", "content": {"orderInfo": {"status": 4}, "orderResult": "{\"key\": \"value\"}"}}`)
 		}
 	}))
 	defer server.Close()
 
-	// 替换全局 Host 变量
-	originalPollingInterval := pollingInterval
-	pollingInterval = 10 * time.Millisecond // 加速测试
-	defer func() { pollingInterval = originalPollingInterval }()
-
 	client := NewClient("app-id", "secret-key", WithHost(server.URL))
-	result, err := client.Process(context.Background(), "dummy.mp3")
+	result, err := client.Process(context.Background(), writeAudio(t))
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -72,22 +73,170 @@ func TestClient_Process_Success(t *testing.T) {
 	}
 }
 
+// 上传失败后重放请求时（重定向或重试中间件）重新读取整个音频文件。
+func TestClient_UploadFile_Replay(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"code": "000000", "content": {"orderId": "order-id"}}`)
+	}))
+	defer server.Close()
+
+	// 收到 503 时通过 GetBody 重放一次请求
+	replay := func(next http.RoundTripper) http.RoundTripper {
+		return middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.StatusCode != http.StatusServiceUnavailable || req.GetBody == nil {
+				return resp, err
+			}
+			resp.Body.Close()
+			retry := req.Clone(req.Context())
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(retry)
+		})
+	}
+	client := NewClient("app-id", "secret-key", WithHost(server.URL), WithMiddleware(replay))
+	orderID, err := client.UploadFile(context.Background(), writeAudio(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if orderID != "order-id" || len(bodies) != 2 || bodies[1] != "fake audio" {
+		t.Errorf("Expected the audio to be uploaded again, got order %q and bodies %q", orderID, bodies)
+	}
+}
+
 func TestClient_Process_UploadError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 模拟上传失败
-		resp := models.UploadResponse{Code: "10001", DescInfo: "upload failed"}
-		json.NewEncoder(w).Encode(resp)
+		fmt.Fprint(w, `{"code": "10001", "descInfo": "upload failed"}`)
 	}))
 	defer server.Close()
 
 	client := NewClient("app-id", "secret-key", WithHost(server.URL))
-	_, err := client.Process(context.Background(), "dummy.mp3")
+	_, err := client.Process(context.Background(), writeAudio(t))
 
 	if err == nil {
 		t.Fatal("Expected an error, got nil")
 	}
-	expectedError := "error during file upload: upload failed with code 10001: upload failed"
-	if err.Error() != expectedError {
-		t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
+	var apiErr *xfyunerr.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 10001 || apiErr.Message != "upload failed" {
+		t.Errorf("Expected an APIError with code 10001, got %v", err)
+	}
+}
+
+// 任务失败与不可重试的错误码应立即结束轮询，而不是重试到失败上限。
+func TestClient_GetTranscriptionResult_TerminalFailure(t *testing.T) {
+	fastPolling(t)
+	tests := []struct {
+		name     string
+		terminal string
+		check    func(error) bool
+	}{
+		{
+			name:     "fail type",
+			terminal: `{"code": "000000", "content": {"orderInfo": {"status": -1, "failType": 1}}}`,
+			check: func(err error) bool {
+				var taskErr *xfyunerr.TaskError
+				return errors.As(err, &taskErr) && taskErr.FailType == 1
+			},
+		},
+		{
+			name:     "non-retryable code",
+			terminal: `{"code": "26601", "descInfo": "illegal app"}`,
+			check: func(err error) bool {
+				var apiErr *xfyunerr.APIError
+				return errors.As(err, &apiErr) && apiErr.Code == 26601
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pollingCount := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pollingCount++
+				// 第一次处理中，之后进入轮询循环并返回终结的失败
+				if pollingCount == 1 {
+					fmt.Fprint(w, `{"code": "000000", "content": {"orderInfo": {"status": 3}}}`)
+					return
+				}
+				fmt.Fprint(w, tt.terminal)
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			client := NewClient("app-id", "secret-key", WithHost(server.URL))
+			_, err := client.GetTranscriptionResult(ctx, "order-id", "transfer")
+			if !tt.check(err) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if pollingCount != 2 {
+				t.Errorf("Expected polling to stop after the terminal response, polled %d times", pollingCount)
+			}
+		})
+	}
+}
+
+// 每次轮询都重新解析凭证，转写期间轮换的密钥在下一次轮询生效。
+func TestClient_GetTranscriptionResult_RotatedCredentials(t *testing.T) {
+	fastPolling(t)
+	var appIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appIDs = append(appIDs, r.URL.Query().Get("appId"))
+		if len(appIDs) < 3 {
+			fmt.Fprint(w, `{"code": "000000", "content": {"orderInfo": {"status": 3}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"code": "000000", "content": {"orderInfo": {"status": 4}}}`)
+	}))
+	defer server.Close()
+
+	resolved := 0
+	provider := auth.CredentialProviderFunc(func(ctx context.Context) (auth.Credentials, error) {
+		resolved++
+		return auth.Credentials{AppID: fmt.Sprintf("app-%d", resolved), SecretKey: "secret-key"}, nil
+	})
+	client := NewClient("", "", WithHost(server.URL), WithCredentials(provider))
+	if _, err := client.GetTranscriptionResult(context.Background(), "order-id", "transfer"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := []string{"app-1", "app-2", "app-3"}; fmt.Sprint(appIDs) != fmt.Sprint(want) {
+		t.Errorf("Expected app IDs %v, got %v", want, appIDs)
+	}
+}
+
+// 错误码目录中没有的错误码（包括无法解析的错误码）继续轮询，而不是当作不可重试的错误结束。
+func TestClient_GetTranscriptionResult_UnknownCode(t *testing.T) {
+	fastPolling(t)
+	for _, code := range []string{"99999", "not-a-code"} {
+		t.Run(code, func(t *testing.T) {
+			pollingCount := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pollingCount++
+				if pollingCount < 3 {
+					fmt.Fprintf(w, `{"code": %q, "descInfo": "unknown"}`, code)
+					return
+				}
+				fmt.Fprint(w, `{"code": "000000", "content": {"orderInfo": {"status": 4}}}`)
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			client := NewClient("app-id", "secret-key", WithHost(server.URL))
+			if _, err := client.GetTranscriptionResult(ctx, "order-id", "transfer"); err != nil {
+				t.Fatalf("Expected polling to continue past the unknown code, got %v", err)
+			}
+			if pollingCount != 3 {
+				t.Errorf("Expected to poll 3 times, polled %d times", pollingCount)
+			}
+		})
 	}
 }
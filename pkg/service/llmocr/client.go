@@ -1,13 +1,19 @@
 package llmocr
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/document"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
 	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"io"
 	"log/slog"
 	"net/http"
@@ -18,7 +24,11 @@ import (
 )
 
 const (
+	// Deprecated: 默认地址由 endpoint.Default 提供，该常量仅为兼容保留。
 	HOST = "https://cbm01.cn-huabei-1.xf-yun.com/v1/private/se75ocrbm"
+
+	// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+	serviceName = "llmocr"
 )
 
 // Client represents the llmocr client
@@ -29,6 +39,38 @@ type Client struct {
 	Host       string
 	Logger     *slog.Logger
 	HTTPClient *http.Client // <--- 添加此字段
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证，AppID/ApiKey/ApiSecret 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 HMAC 签名，可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Retry 是失败时的重试策略，为 nil 时不重试。每次重试都会重新签名。
+	Retry *retry.Policy
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每次调用记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是允许的最大响应体字节数，超出时返回 *xfyunerr.ResponseTooLargeError；
+	// 小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// Fallbacks 是 Host 连接失败时依次尝试的备用地址，默认取自 endpoint.Default。
+	Fallbacks []string
+
+	// Preprocess 在每次识别前预处理图片（方向、倾斜、边框等），为 nil 时原样发送。
+	// 大模型识别只返回文字，预处理的记录只写入 debug 日志；预处理失败时记录警告并发送原图。
+	Preprocess *imageprep.Pipeline
+
+	// middlewares 在 NewClient 中包装 HTTPClient 的 Transport。
+	middlewares []middleware.Middleware
 }
 
 // Option is a function that configures a Client.
@@ -48,6 +90,97 @@ func WithHost(host string) Option {
 	return func(c *Client) {
 		if host != "" {
 			c.Host = host
+			c.Fallbacks = nil
+		}
+	}
+}
+
+// WithEndpoints sets the primary endpoint and the fallbacks tried in order when it is unreachable,
+// e.g. the result of endpoint.Default.Resolve.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		if len(urls) > 0 {
+			c.Host, c.Fallbacks = urls[0], urls[1:]
+		}
+	}
+}
+
+// WithCredentials sets the credential provider resolved on every request.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
+		}
+	}
+}
+
+// WithAuthMode selects whether the signature is sent in the URL query (auth.ModeQuery)
+// or in the Authorization/Date/Host headers (auth.ModeHeader).
+func WithAuthMode(mode auth.Mode) Option {
+	return func(c *Client) {
+		c.AuthMode = mode
+	}
+}
+
+// WithRetry enables retries with the given policy, e.g. retry.DefaultPolicy().
+func WithRetry(policy *retry.Policy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies; larger responses fail with
+// *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
+	}
+}
+
+// WithPreprocess runs p on every image before recognition, e.g. imageprep.Default() for phone
+// photos of documents.
+func WithPreprocess(p *imageprep.Pipeline) Option {
+	return func(c *Client) {
+		c.Preprocess = p
+	}
+}
+
+// WithMiddleware wraps the transport of the HTTP client with mws, e.g. a proxy, mTLS or audit layer.
+// Middlewares apply to every request including retries, in order: the first one is outermost.
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client, e.g. one with a custom Transport or Timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.HTTPClient = client
 		}
 	}
 }
@@ -58,33 +191,50 @@ func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 		AppID:     appID,
 		ApiKey:    apiKey,
 		ApiSecret: apiSecret,
-		Host:      HOST,
 		// Default to a silent logger
 		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer: auth.DefaultSigner,
 		HTTPClient: &http.Client{ // <--- 在这里初始化
 			Timeout: 30 * time.Second,
 		},
 	}
+	c.Host, c.Fallbacks = endpoint.Default.Lookup(serviceName)
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	c.HTTPClient = middleware.WrapClient(c.HTTPClient, c.middlewares...)
 	return c
 }
 
 // RecognizeFile 静态图片识别，从文件路径读取
 func (c *Client) RecognizeFile(ctx context.Context, imagePath, uid string) (string, error) {
-	// 1. 读取并编码图片
-	base64Image, imageType, err := readAndEncodeImage(imagePath)
+	// 1. 读取图片，发送时再流式编码
+	imageData, imageType, err := readImage(imagePath)
 	if err != nil {
 		return "", fmt.Errorf("读取图片文件失败: %w", err)
 	}
-	return c.ocr(ctx, uid, base64Image, imageType)
+	imageData, imageType = c.preprocess(imageData, imageType)
+	return c.ocr(ctx, uid, stream.Bytes(imageData), imageType)
+}
+
+// RecognizeDocument 多页文档识别：将 PDF、多页 TIFF（或单张图片）拆分为逐页图片，
+// 以最多 concurrency 个并发识别（小于等于 0 时为 document.DefaultConcurrency）。
+// 结果按页码排列，无法提取或识别失败的页带有各自的错误。
+func (c *Client) RecognizeDocument(ctx context.Context, path, uid string, concurrency int) ([]document.Result[string], error) {
+	pages, err := document.SplitFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("拆分文档失败: %w", err)
+	}
+	return document.Recognize(ctx, pages, concurrency, func(ctx context.Context, page document.Page) (string, error) {
+		return c.RecognizeBytes(ctx, page.Data, page.Format, uid)
+	}), nil
 }
 
 // RecognizeBytes 静态图片识别，从字节流读取
 func (c *Client) RecognizeBytes(ctx context.Context, imageData []byte, imageType, uid string) (string, error) {
+	imageData, imageType = c.preprocess(imageData, imageType)
 	if imageType == "" {
 		contentType := http.DetectContentType(imageData)
 		switch {
@@ -97,45 +247,80 @@ func (c *Client) RecognizeBytes(ctx context.Context, imageData []byte, imageType
 			imageType = "jpg"
 		}
 	}
-	base64Image := base64.StdEncoding.EncodeToString(imageData)
-	return c.ocr(ctx, uid, base64Image, imageType)
+	return c.ocr(ctx, uid, stream.Bytes(imageData), imageType)
 }
 
-// ocr 是执行OCR的核心私有方法
-func (c *Client) ocr(ctx context.Context, uid, base64Image, imageType string) (string, error) {
-	// 1. 构建请求体
-	requestBody := c.buildRequestBody(uid, base64Image, imageType)
-	requestBytes, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("序列化请求体失败: %w", err)
+// preprocess 按 c.Preprocess 处理图片，未配置或失败时返回原图
+func (c *Client) preprocess(imageData []byte, imageType string) ([]byte, string) {
+	if c.Preprocess == nil {
+		return imageData, imageType
 	}
-
-	// 2. 执行请求
-	responseBytes, err := c.executeOCRRequest(ctx, requestBytes)
+	res, err := c.Preprocess.Process(imageData)
 	if err != nil {
-		return "", fmt.Errorf("执行OCR请求失败: %w", err)
+		c.Logger.Warn("image preprocessing failed, sending the original image", "error", err)
+		return imageData, imageType
 	}
+	c.Logger.Debug("image preprocessed",
+		"steps", res.Info.Steps,
+		"skew", res.Info.Skew,
+		"size", res.Info.Size,
+		"bytes", len(res.Data),
+	)
+	return res.Data, res.Format
+}
 
-	// 3. 解析响应并返回结果
-	return c.parseResponse(responseBytes)
+// ocr 是执行OCR的核心私有方法
+func (c *Client) ocr(ctx context.Context, uid string, image stream.Source, imageType string) (string, error) {
+	// 执行请求并解析响应，失败时按重试策略重新解析凭证、签名后重试
+	var result string
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		creds, err := c.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		requestBody := c.buildRequestBody(creds.AppID, uid, stream.Placeholder, imageType)
+		requestBytes, err := stream.JSON(requestBody, image)
+		if err != nil {
+			return fmt.Errorf("序列化请求体失败: %w", err)
+		}
+		ctx, call := c.Telemetry.Start(ctx, serviceName, "recognize", c.Host)
+		call.AddBytesSent(int(requestBytes.Len()))
+		result, err = endpoint.Failover(c.Logger, c.endpoints(), func(host string) (string, error) {
+			return c.executeOCRRequest(ctx, host, creds, requestBytes)
+		})
+		if err != nil {
+			err = fmt.Errorf("执行OCR请求失败: %w", err)
+		}
+		call.End(err)
+		return err
+	})
+	return result, err
+}
+
+// credentials 解析本次请求使用的凭证。
+func (c *Client) credentials(ctx context.Context) (auth.Credentials, error) {
+	return auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		APIKey:    c.ApiKey,
+		APISecret: c.ApiSecret,
+	})
 }
 
-// readAndEncodeImage 读取图片文件，返回Base64编码字符串和文件类型
-func readAndEncodeImage(path string) (string, string, error) {
+// readImage 读取图片文件，返回图片内容和文件类型
+func readImage(path string) ([]byte, string, error) {
 	imgBytes, err := os.ReadFile(path)
 	if err != nil {
-		return "", "", err
+		return nil, "", err
 	}
-	base64Str := base64.StdEncoding.EncodeToString(imgBytes)
 	fileType := strings.TrimPrefix(filepath.Ext(path), ".")
-	return base64Str, fileType, nil
+	return imgBytes, fileType, nil
 }
 
 // buildRequestBody 使用结构体构建请求体
-func (c *Client) buildRequestBody(uid, imageBase64, fileType string) models.RequestBody {
+func (c *Client) buildRequestBody(appID, uid, imageBase64, fileType string) models.RequestBody {
 	return models.RequestBody{
 		Header: models.Header{
-			AppID:  c.AppID,
+			AppID:  appID,
 			UID:    uid,
 			Status: 0,
 		},
@@ -161,54 +346,79 @@ func (c *Client) buildRequestBody(uid, imageBase64, fileType string) models.Requ
 	}
 }
 
-// executeOCRRequest 负责签名和发送HTTP请求
-func (c *Client) executeOCRRequest(ctx context.Context, payload []byte) ([]byte, error) {
-	// 1. 生成带鉴权的URL
-	//authURL, err := c.assembleRequestUrl("POST")
-	authURL, err := auth.BuildAuthURL(HOST, "POST", c.ApiKey, c.ApiSecret, auth.SchemeTypeHMAC)
+// endpoints 返回主地址与备用地址。
+func (c *Client) endpoints() []string {
+	return append([]string{c.Host}, c.Fallbacks...)
+}
+
+// executeOCRRequest 负责签名、向 host 发送HTTP请求并解析响应
+func (c *Client) executeOCRRequest(ctx context.Context, host string, creds auth.Credentials, payload *stream.Body) (string, error) {
+	release, err := c.Limiter.Acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("生成鉴权URL失败: %w", err)
+		return "", err
 	}
+	defer release()
 
-	// 2. 创建带上下文的HTTP请求
-	req, err := http.NewRequestWithContext(ctx, "POST", authURL, bytes.NewBuffer(payload))
+	// 1. 创建带上下文的HTTP请求
+	req, err := payload.NewRequest(ctx, "POST", host)
 	if err != nil {
-		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+		return "", fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	c.Logger.Debug("sending llmocr request", "url", authURL, "uid", req.Header.Get("uid"))
+	// 2. 按鉴权模式签名（URL 查询参数或请求头）
+	if err := c.Signer.Sign(req, c.AuthMode, creds.APIKey, creds.APISecret, auth.SchemeTypeHMAC); err != nil {
+		return "", fmt.Errorf("生成鉴权URL失败: %w", err)
+	}
+
+	c.Logger.Debug("sending llmocr request", "url", auth.RedactURL(req.URL.String()), "auth_mode", c.AuthMode, "uid", req.Header.Get("uid"))
 
 	// 3. 发送请求
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("发送HTTP请求失败: %w", err)
+		return "", fmt.Errorf("发送HTTP请求失败: %w", &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)})
 	}
 	defer resp.Body.Close()
+	c.Signer.ObserveResponse(resp)
+	body := stream.LimitReader(serviceName, resp.Body, c.MaxResponseSize)
 
-	// 4. 读取响应体
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应体失败: %w", err)
-	}
-
-	// 5. 检查HTTP状态码
+	// 4. 检查HTTP状态码，网关错误的响应体很小，直接读取
 	if resp.StatusCode != http.StatusOK {
+		responseBody, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("读取响应体失败: %w", err)
+		}
 		c.Logger.Error("llmocr request failed",
 			"status_code", resp.StatusCode,
 			"response", string(responseBody),
 		)
-		return nil, fmt.Errorf("请求失败, 状态码: %d, 响应: %s", resp.StatusCode, string(responseBody))
+		return "", xfyunerr.FromStatus(serviceName, resp.StatusCode, responseBody)
 	}
 
 	c.Logger.Debug("llmocr request successful")
-	return responseBody, nil
+	return c.parseResponse(ctx, body, resp.ContentLength)
 }
 
-// parseResponse 解析API返回的JSON数据
-func (c *Client) parseResponse(responseBytes []byte) (string, error) {
+// parseResponse 流式解析API返回的JSON数据，payload.result.text 边读边做Base64解码，
+// 不在内存中保留完整的响应体与Base64字符串。size 为响应体长度（未知时为 -1），用于预分配结果。
+func (c *Client) parseResponse(ctx context.Context, r io.Reader, size int64) (string, error) {
+	limit := c.MaxResponseSize
+	if limit <= 0 {
+		limit = stream.DefaultMaxResponseSize
+	}
+	var text strings.Builder
+	if size > 0 && size <= limit {
+		text.Grow(int(size / 4 * 3))
+	}
+
 	var respData models.ResponseBody
-	if err := json.Unmarshal(responseBytes, &respData); err != nil {
+	err := stream.DecodeJSON(r, &respData, func(name string) io.Writer {
+		if name == "result" {
+			return &text
+		}
+		return nil
+	})
+	if err != nil {
 		return "", fmt.Errorf("解析响应JSON失败: %w", err)
 	}
 
@@ -219,14 +429,9 @@ func (c *Client) parseResponse(responseBytes []byte) (string, error) {
 			"message", respData.Header.Message,
 			"sid", respData.Header.SID,
 		)
-		return "", fmt.Errorf("API返回错误: code=%d, message=%s", respData.Header.Code, respData.Header.Message)
-	}
-
-	// Base64解码最终的文本结果
-	decodedText, err := base64.StdEncoding.DecodeString(respData.Payload.Result.Text)
-	if err != nil {
-		return "", fmt.Errorf("Base64解码结果失败: %w", err)
+		return "", &xfyunerr.APIError{Service: serviceName, Code: respData.Header.Code, Message: respData.Header.Message, SID: respData.Header.SID, HTTPStatus: http.StatusOK}
 	}
+	telemetry.SetSID(ctx, respData.Header.SID)
 
-	return string(decodedText), nil
+	return text.String(), nil
 }
@@ -1,15 +1,38 @@
 package llmocr
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"goxfyunclient/internal/service/llmocr/models"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/fruitbars/goxfyunclient/pkg/document"
+	"github.com/fruitbars/goxfyunclient/pkg/emulator"
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr/models"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 )
 
+// dummyImage 在临时目录中创建一个占位图片文件，服务器返回的是固定响应。
+func dummyImage(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dummy.jpg")
+	if err := os.WriteFile(path, []byte("dummy image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 // mockXFYunServer 创建一个模拟的讯飞 API 服务器
 func mockXFYunServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
 	return httptest.NewServer(handler)
@@ -51,8 +74,7 @@ func TestClient_RecognizeFile_Success(t *testing.T) {
 	client := NewClient("test-app-id", "test-api-key", "test-api-secret", WithHost(server.URL))
 
 	// 3. 执行测试
-	// 此处 imagePath 和 uid 仅为占位符，因为服务器返回的是固定响应
-	result, err := client.RecognizeFile(context.Background(), "dummy-path.jpg", "test-uid")
+	result, err := client.RecognizeFile(context.Background(), dummyImage(t), "test-uid")
 
 	// 4. 断言结果
 	if err != nil {
@@ -85,16 +107,16 @@ func TestClient_RecognizeFile_ApiError(t *testing.T) {
 	client := NewClient("test-app-id", "test-api-key", "test-api-secret", WithHost(server.URL))
 
 	// 3. 执行测试
-	_, err := client.RecognizeFile(context.Background(), "dummy-path.jpg", "test-uid")
+	_, err := client.RecognizeFile(context.Background(), dummyImage(t), "test-uid")
 
 	// 4. 断言错误
 	if err == nil {
 		t.Fatal("Expected an error, but got nil")
 	}
 
-	expectedErrorMsg := "API返回错误: code=10106, message=Invalid authorization"
-	if err.Error() != expectedErrorMsg {
-		t.Errorf("Expected error message '%s', but got '%s'", expectedErrorMsg, err.Error())
+	var apiErr *xfyunerr.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 10106 || apiErr.Message != "Invalid authorization" {
+		t.Errorf("Expected an APIError with code 10106, but got '%v'", err)
 	}
 }
 
@@ -105,19 +127,70 @@ func TestClient_RecognizeFile_HttpError(t *testing.T) {
 	})
 	defer server.Close()
 
-	// 2. 创建客户端
-	client := NewClient("test-app-id", "test-api-key", "test-api-secret", WithHost(server.URL))
+	// 2. 创建客户端，不重试
+	client := NewClient("test-app-id", "test-api-key", "test-api-secret", WithHost(server.URL), WithRetry(nil))
 
 	// 3. 执行测试
-	_, err := client.RecognizeFile(context.Background(), "dummy-path.jpg", "test-uid")
+	_, err := client.RecognizeFile(context.Background(), dummyImage(t), "test-uid")
 
 	// 4. 断言错误
 	if err == nil {
 		t.Fatal("Expected an error, but got nil")
 	}
 
-	expectedErrorMsg := "执行OCR请求失败: 请求失败, 状态码: 500, 响应: Internal Server Error\n"
-	if err.Error() != expectedErrorMsg {
-		t.Errorf("Expected error message '%s', but got '%s'", expectedErrorMsg, err.Error())
+	var apiErr *xfyunerr.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusInternalServerError {
+		t.Errorf("Expected an APIError with status 500, but got '%v'", err)
+	}
+}
+
+func TestClient_RecognizeDocument(t *testing.T) {
+	srv := httptest.NewServer(emulator.New())
+	defer srv.Close()
+	client := NewClient("test-app-id", "test-api-key", "test-api-secret", WithHost(emulator.Endpoint(srv.URL, emulator.ServiceLLMOCR)))
+
+	// 两页 PDF，第 2 页没有图片
+	pdf := "%PDF-1.4\n" +
+		"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+		"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj\n" +
+		"3 0 obj << /Type /Page /Resources << /XObject << /Im0 5 0 R >> >> >> endobj\n" +
+		"4 0 obj << /Type /Page >> endobj\n" +
+		"5 0 obj << /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 1 >>\nstream\n\x80\nendstream endobj\n" +
+		"trailer << /Root 1 0 R >>\n%%EOF\n"
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	if err := os.WriteFile(path, []byte(pdf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	texts, err := client.RecognizeDocument(context.Background(), path, "uid", 0)
+	if err != nil || len(texts) != 2 {
+		t.Fatalf("Expected 2 page results, got %d, %v", len(texts), err)
+	}
+	if texts[0].Err != nil || texts[0].Value == "" || !errors.Is(texts[1].Err, document.ErrNoImage) {
+		t.Errorf("Unexpected results %+v", texts)
+	}
+}
+
+func TestClient_ImagePreprocess(t *testing.T) {
+	// 识别服务返回收到的图片的大小
+	srv := httptest.NewServer(emulator.New(emulator.WithScript(emulator.ServiceLLMOCR, func(req emulator.Request) emulator.Response {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(req.Input))
+		if err != nil {
+			return emulator.Response{Result: "0x0"}
+		}
+		return emulator.Response{Result: fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)}
+	})))
+	defer srv.Close()
+	client := NewClient("test-app-id", "test-api-key", "test-api-secret",
+		WithHost(emulator.Endpoint(srv.URL, emulator.ServiceLLMOCR)),
+		WithPreprocess(&imageprep.Pipeline{MaxDimension: 100}))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 400, 200))); err != nil {
+		t.Fatal(err)
+	}
+	text, err := client.RecognizeBytes(context.Background(), buf.Bytes(), "png", "uid")
+	if err != nil || !strings.Contains(text, "100x50") {
+		t.Errorf("Expected the preprocessed 100x50 image, got %q, %v", text, err)
 	}
 }
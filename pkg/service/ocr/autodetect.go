@@ -0,0 +1,290 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+)
+
+// DefaultAutoDetectConfidence 是 RecognizeAutoDetect 直接接受识别结果的默认最低平均置信度。
+const DefaultAutoDetectConfidence = 0.8
+
+// autoDetectFirstPass 是第一遍识别使用的语种。
+const autoDetectFirstPass = "ch_en"
+
+// maxDetectRunes 限制发送给语种识别服务的文字长度。
+const maxDetectRunes = 1000
+
+// LanguageDetector 返回文本属于各语种的概率，键为语种代码（例如 "cn"、"fr"、"ar"）。
+// *detectlanguage.Client 实现了该接口。
+type LanguageDetector interface {
+	DetectProbs(ctx context.Context, text string) (map[string]float64, error)
+}
+
+// LanguageDetectorFunc 将函数适配为 LanguageDetector。
+type LanguageDetectorFunc func(ctx context.Context, text string) (map[string]float64, error)
+
+// DetectProbs 调用 f(ctx, text)。
+func (f LanguageDetectorFunc) DetectProbs(ctx context.Context, text string) (map[string]float64, error) {
+	return f(ctx, text)
+}
+
+// LanguageSource 说明 RecognizeAutoDetect 的语种是如何得到的。
+type LanguageSource string
+
+const (
+	// LanguageFromFirstPass 表示返回的是第一遍（中英）识别的结果。
+	LanguageFromFirstPass LanguageSource = "first_pass"
+	// LanguageFromScript 表示语种由第一遍结果中文字的 Unicode 字符集判断。
+	LanguageFromScript LanguageSource = "script"
+	// LanguageFromDetector 表示语种由 LanguageDetector 判断。
+	LanguageFromDetector LanguageSource = "detector"
+	// LanguageFromCandidates 表示结果来自 AutoDetectCandidates 中置信度最高的一个。
+	LanguageFromCandidates LanguageSource = "candidates"
+)
+
+// AutoDetectResult 是 RecognizeAutoDetect 的结果。
+type AutoDetectResult struct {
+	*OcrResponse
+
+	Language   string         // 所返回结果使用的 ASE 语种代码，见 GetLanguageByASECode
+	Category   string         // Language 在 Client.Backend 上的分类名
+	Detected   string         // 检测出的 ASE 语种代码，检测不出时为空
+	Source     LanguageSource // Language 的来源
+	Confidence float64        // 所返回结果按文字长度加权的平均置信度
+	Passes     int            // 发送的识别请求数
+}
+
+// RecognizeAutoDetect 在不知道图片语种时识别：先以中英识别一遍，平均置信度不低于
+// AutoDetectConfidence 时直接返回；否则按结果中文字的 Unicode 字符集（阿拉伯文、泰文、西里尔文等）
+// 判断语种，字符集无法区分时（例如拉丁字母）询问 LanguageDetector，再以该语种重新识别。
+// 仍然检测不出或置信度不足时依次尝试 AutoDetectCandidates。返回置信度最高的一遍结果及其语种；
+// 只有第一遍失败时返回错误，之后某一遍失败时跳过该语种。
+// 图片的压缩、编码探测与预处理与 RecognizeAuto 相同，预处理只做一次。
+func (c *Client) RecognizeAutoDetect(ctx context.Context, raw []byte) (*AutoDetectResult, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty image")
+	}
+	// 预处理只做一次，每一遍识别共用同一张图片
+	var img []byte
+	var enc string
+	var info *imageprep.Info
+	if c.Preprocess != nil {
+		img, enc, info = c.preprocess(raw, "")
+	} else {
+		img, enc = prepareImage(raw)
+	}
+	threshold := c.AutoDetectConfidence
+	if threshold <= 0 {
+		threshold = DefaultAutoDetectConfidence
+	}
+
+	best := &AutoDetectResult{Language: autoDetectFirstPass}
+	tried := map[string]bool{}
+	// run 以 lang 识别一遍，置信度更高时替换 best
+	run := func(lang string) (string, error) {
+		tried[lang] = true
+		resp, err := c.recognize(ctx, img, enc, lang)
+		best.Passes++
+		if err != nil {
+			return "", err
+		}
+		resp.Preprocessing = info
+		text, conf := resultConfidence(resp)
+		if best.OcrResponse == nil || conf > best.Confidence {
+			best.OcrResponse, best.Language, best.Confidence = resp, lang, conf
+		}
+		return text, nil
+	}
+
+	text, err := run(autoDetectFirstPass)
+	if err != nil {
+		return nil, err
+	}
+	if best.Confidence >= threshold {
+		best.Source, best.Category = LanguageFromFirstPass, c.Category(best.Language)
+		return best, nil
+	}
+
+	// 第一遍之后的识别失败（例如部署不支持该语种）只记录日志，保留已有的最佳结果
+	retry := func(lang string) {
+		if _, err := run(lang); err != nil {
+			c.Logger.Warn("ocr pass failed, keeping the best result so far", "language", lang, "error", err)
+		}
+	}
+
+	lm := c.languages()
+	var detectedBy LanguageSource
+	// 字符集对应的语种不在当前语言表中时（见 SetLanguageMap），交给 LanguageDetector 判断
+	if lang := aseCodeOf(lm, detectScript(text)); lang != "" {
+		best.Detected, detectedBy = lang, LanguageFromScript
+	} else if c.LanguageDetector != nil && strings.TrimSpace(text) != "" {
+		probs, err := c.LanguageDetector.DetectProbs(ctx, truncateRunes(text, maxDetectRunes))
+		if err != nil {
+			c.Logger.Warn("language detection failed", "error", err)
+		} else if lang := pickDetectedLanguage(lm, probs); lang != "" {
+			best.Detected, detectedBy = lang, LanguageFromDetector
+		}
+	}
+	if best.Detected != "" && !tried[best.Detected] {
+		retry(best.Detected)
+	}
+
+	for _, lang := range c.AutoDetectCandidates {
+		if best.Confidence >= threshold {
+			break
+		}
+		if !tried[lang] {
+			retry(lang)
+		}
+	}
+
+	switch best.Language {
+	case best.Detected:
+		best.Source = detectedBy
+	case autoDetectFirstPass:
+		best.Source = LanguageFromFirstPass
+	default:
+		best.Source = LanguageFromCandidates
+	}
+
+	best.Category = c.Category(best.Language)
+
+	c.Logger.Debug("ocr language selected",
+		"language", best.Language,
+		"category", best.Category,
+		"detected", best.Detected,
+		"source", best.Source,
+		"confidence", best.Confidence,
+		"passes", best.Passes,
+	)
+	return best, nil
+}
+
+// resultConfidence 返回识别出的纯文本与按每行文字长度加权的平均置信度，没有文字时置信度为 0。
+func resultConfidence(resp *OcrResponse) (string, float64) {
+	result, err := resp.Result()
+	if err != nil {
+		return "", 0
+	}
+	var sum, weight float64
+	for _, line := range result.Lines() {
+		n := float64(utf8.RuneCountInString(strings.TrimSpace(line.Text())))
+		sum += line.Conf * n
+		weight += n
+	}
+	if weight == 0 {
+		return "", 0
+	}
+	return result.PlainText(), sum / weight
+}
+
+// scriptLanguages 是可以仅凭字符集确定识别语种的文字。西里尔字母对应多种语言，按俄语识别。
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Lao, "lo"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+	{unicode.Greek, "el"},
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Cyrillic, "ru"},
+}
+
+// detectScript 返回 text 中字母最多的字符集对应的 ASE 语种代码。
+// 以汉字或拉丁字母为主、或者没有字母时返回空字符串；日文中的汉字计入日语。
+func detectScript(text string) string {
+	counts := map[string]int{}
+	var han, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+			continue
+		case unicode.Is(unicode.Latin, r):
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if counts["ja"] > 0 {
+		counts["ja"] += han
+	}
+
+	lang, most := "", max(han, latin)
+	for _, s := range scriptLanguages {
+		if n := counts[s.lang]; n > most {
+			lang, most = s.lang, n
+		}
+	}
+	return lang
+}
+
+// pickDetectedLanguage 返回概率最高、且能对应到 LanguageMap 中识别语种的 ASE 语种代码。
+func pickDetectedLanguage(lm *LanguageMap, probs map[string]float64) string {
+	codes := make([]string, 0, len(probs))
+	for code := range probs {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if probs[codes[i]] != probs[codes[j]] {
+			return probs[codes[i]] > probs[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	for _, code := range codes {
+		if lang := aseCodeOf(lm, code); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+// aseCodeOf 将语种识别服务返回的代码转换为 ASE 语种代码，中文与英文都使用中英识别。
+// 不在 lm 中的语种返回空字符串。
+func aseCodeOf(lm *LanguageMap, code string) string {
+	code = strings.ToLower(code)
+	switch code {
+	case "":
+		return ""
+	case "cn", "zh", "en", "ch_en":
+		return "ch_en"
+	}
+	if lang := lm.FindByASECode(code); lang != nil {
+		return lang.ASECode
+	}
+	if lang := lm.FindByCode(code); lang != nil {
+		return lang.ASECode
+	}
+	return ""
+}
+
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
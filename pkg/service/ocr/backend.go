@@ -0,0 +1,60 @@
+package ocr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Backend 是私有化部署 OCR 引擎所用的硬件，决定语种对应的分类（模型）名称。
+type Backend int
+
+const (
+	// BackendNvidia 是英伟达 GPU，分类名不带前缀，例如 "mix0"。默认值。
+	BackendNvidia Backend = iota
+	// BackendCambricon 是寒武纪 MLU，分类名以 "cam." 开头。
+	BackendCambricon
+	// BackendAtlas 是华为 Atlas，分类名以 "atlas." 开头。
+	BackendAtlas
+)
+
+// Backends 按顺序列出所有支持的硬件。
+var Backends = []Backend{BackendNvidia, BackendCambricon, BackendAtlas}
+
+// String 返回硬件名称："nvidia"、"cambricon" 或 "atlas"。
+func (b Backend) String() string {
+	switch b {
+	case BackendNvidia:
+		return "nvidia"
+	case BackendCambricon:
+		return "cambricon"
+	case BackendAtlas:
+		return "atlas"
+	}
+	return fmt.Sprintf("Backend(%d)", int(b))
+}
+
+// ParseBackend 解析硬件名称，不区分大小写，也接受 "gpu"、"mlu"、"cam"、"huawei" 等别名。
+func ParseBackend(s string) (Backend, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "nvidia", "gpu", "cuda":
+		return BackendNvidia, nil
+	case "cambricon", "cam", "mlu":
+		return BackendCambricon, nil
+	case "atlas", "huawei", "ascend", "npu":
+		return BackendAtlas, nil
+	}
+	return BackendNvidia, fmt.Errorf("unknown ocr backend %q", s)
+}
+
+// Category 返回该语种在硬件 b 上的分类名，b 未知时返回空字符串。
+func (l *Language) Category(b Backend) string {
+	switch b {
+	case BackendNvidia:
+		return l.NvidiaGPU
+	case BackendCambricon:
+		return l.CambriconMLU
+	case BackendAtlas:
+		return l.HuaweiAtlas
+	}
+	return ""
+}
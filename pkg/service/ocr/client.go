@@ -7,14 +7,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/document"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
 	"github.com/fruitbars/goxfyunclient/pkg/utils"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"image"
 	"image/jpeg"
 	"io"
 	"log/slog"
 	"mime"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -22,7 +31,8 @@ import (
 	"unicode"
 )
 
-const apiURL = "https://cn-east-1.api.xf-yun.com/v1/ocr"
+// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+const serviceName = "ocr"
 
 // 压缩策略
 const (
@@ -38,6 +48,57 @@ type Client struct {
 	Host       string
 	Logger     *slog.Logger
 	HTTPClient *http.Client
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证，AppID/APIKey/APISecret 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 HMAC 签名，可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Retry 是失败时的重试策略，为 nil 时不重试。每次重试都会重新签名。
+	Retry *retry.Policy
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每次调用记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是允许的最大响应体字节数，超出时返回 *xfyunerr.ResponseTooLargeError；
+	// 小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// Fallbacks 是 Host 连接失败时依次尝试的备用地址，默认取自 endpoint.Default。
+	Fallbacks []string
+
+	// Backend 是私有化部署的 OCR 引擎所用的硬件，决定 RecognizeAuto 与 Category 使用的分类名，
+	// 默认 BackendNvidia。
+	Backend Backend
+
+	// LanguageMap 是语种与分类的对应表，为 nil 时使用 GetInstance()，
+	// 即 SetLanguageMap 设置的语言表或内置语言表。
+	LanguageMap *LanguageMap
+
+	// Preprocess 在每次识别前预处理图片（方向、倾斜、边框等），为 nil 时原样发送。
+	// 输出不超过 MaxUncompressedBytes；预处理失败（例如无法解码的格式）时记录警告并发送原图。
+	Preprocess *imageprep.Pipeline
+
+	// LanguageDetector 在 RecognizeAutoDetect 中判断拉丁字母等无法按字符集区分的文字的语种，
+	// 例如 *detectlanguage.Client；为 nil 时只按 Unicode 字符集判断。
+	LanguageDetector LanguageDetector
+
+	// AutoDetectConfidence 是 RecognizeAutoDetect 直接接受一遍识别结果的最低平均置信度，
+	// 小于等于 0 时使用 DefaultAutoDetectConfidence。
+	AutoDetectConfidence float64
+
+	// AutoDetectCandidates 是 RecognizeAutoDetect 在检测不出语种且置信度仍然不足时依次尝试的 ASE 语种代码。
+	AutoDetectCandidates []string
+
+	// middlewares 在 NewClient 中包装 HTTPClient 的 Transport。
+	middlewares []middleware.Middleware
 }
 
 // Option is a function that configures a Client.
@@ -52,15 +113,131 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-// WithHost sets the host for the client.
+// WithHost sets the host for the client and drops the default fallback endpoints.
 func WithHost(host string) Option {
 	return func(c *Client) {
 		if host != "" {
 			c.Host = host
+			c.Fallbacks = nil
+		}
+	}
+}
+
+// WithEndpoints sets the primary endpoint and the fallbacks tried in order when it is unreachable,
+// e.g. the result of endpoint.Default.Resolve.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		if len(urls) > 0 {
+			c.Host, c.Fallbacks = urls[0], urls[1:]
+		}
+	}
+}
+
+// WithCredentials sets the credential provider resolved on every request.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
 		}
 	}
 }
 
+// WithAuthMode selects whether the signature is sent in the URL query (auth.ModeQuery)
+// or in the Authorization/Date/Host headers (auth.ModeHeader).
+func WithAuthMode(mode auth.Mode) Option {
+	return func(c *Client) {
+		c.AuthMode = mode
+	}
+}
+
+// WithRetry enables retries with the given policy, e.g. retry.DefaultPolicy().
+func WithRetry(policy *retry.Policy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies; larger responses fail with
+// *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
+	}
+}
+
+// WithBackend sets the hardware of the deployment (NVIDIA, Cambricon or Atlas), which decides
+// the category names used by RecognizeAuto and Category.
+func WithBackend(b Backend) Option {
+	return func(c *Client) {
+		c.Backend = b
+	}
+}
+
+// WithLanguageMap sets the language table used to map languages to categories, e.g. one loaded
+// with LoadLanguageMapFile for a private deployment. nil uses the package-wide table.
+func WithLanguageMap(lm *LanguageMap) Option {
+	return func(c *Client) {
+		c.LanguageMap = lm
+	}
+}
+
+// WithPreprocess runs p on every image before recognition, e.g. imageprep.Default() for phone
+// photos of documents. The pipeline applied is recorded in OcrResponse.Preprocessing.
+func WithPreprocess(p *imageprep.Pipeline) Option {
+	return func(c *Client) {
+		c.Preprocess = p
+	}
+}
+
+// WithLanguageDetector sets the detector RecognizeAutoDetect asks when the script of the first
+// pass is ambiguous, e.g. a *detectlanguage.Client.
+func WithLanguageDetector(d LanguageDetector) Option {
+	return func(c *Client) {
+		c.LanguageDetector = d
+	}
+}
+
+// WithAutoDetect sets the average confidence below which RecognizeAutoDetect re-runs OCR in the
+// detected language, and the ASE language codes it tries in order when detection is inconclusive.
+func WithAutoDetect(minConfidence float64, candidates ...string) Option {
+	return func(c *Client) {
+		c.AutoDetectConfidence = minConfidence
+		c.AutoDetectCandidates = candidates
+	}
+}
+
+// WithMiddleware wraps the transport of the HTTP client with mws, e.g. a proxy, mTLS or audit layer.
+// Middlewares apply to every request including retries, in order: the first one is outermost.
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client, e.g. one with a custom Transport or Timeout.
 func WithHTTPClient(client *http.Client) Option {
 	return func(c *Client) {
 		if client != nil {
@@ -74,13 +251,14 @@ func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 		AppID:     appID,
 		APIKey:    apiKey,
 		APISecret: apiSecret,
-		Host:      apiURL,
 		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer:    auth.DefaultSigner,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second, // 给个总超时更安全
 			// Transport: 自定义的话可在 Option 里扩展
 		},
 	}
+	c.Host, c.Fallbacks = endpoint.Default.Lookup(serviceName)
 
 	for _, opt := range opts {
 		opt(c)
@@ -90,6 +268,7 @@ func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 	if c.HTTPClient == nil {
 		c.HTTPClient = http.DefaultClient
 	}
+	c.HTTPClient = middleware.WrapClient(c.HTTPClient, c.middlewares...)
 	return c
 }
 
@@ -105,33 +284,68 @@ func (r *OcrResponse) RecognizedText() (string, error) {
 	return string(decoded), nil
 }
 
-func (c *Client) validateCredentials() error {
-	if c.AppID == "" || c.APIKey == "" || c.APISecret == "" {
-		return fmt.Errorf("AppID/APIKey/APISecret is not configured")
+// Result decodes the "text" field into the typed recognition result (pages, lines, words).
+func (r *OcrResponse) Result() (*models.Result, error) {
+	text, err := base64.StdEncoding.DecodeString(r.Payload.OcrOutputText.Text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recognized text: %w", err)
 	}
-	return nil
+	if len(text) == 0 {
+		return &models.Result{}, nil
+	}
+	result, err := models.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recognized text: %w", err)
+	}
+	return result, nil
 }
 
-// 重构后的 buildAuthURL
-func (c *Client) buildAuthURL() (string, error) {
-	if c.APIKey == "" || c.APISecret == "" {
-		return "", fmt.Errorf("missing APIKey/APISecret")
-	}
-	// 直接调用通用鉴权函数
-	u, err := url.Parse(c.Host) // 使用 c.Host 而不是硬编码的 apiURL
+// OriginalResult is like Result, but maps the coordinates back to the original image when the
+// image was preprocessed (see WithPreprocess); page sizes become the original size as well.
+func (r *OcrResponse) OriginalResult() (*models.Result, error) {
+	result, err := r.Result()
+	if err != nil || r.Preprocessing == nil {
+		return result, err
+	}
+	info := r.Preprocessing
+	result.MapPoints(func(p models.Point) models.Point {
+		q := info.ToOriginalPoint(image.Pt(p.X, p.Y))
+		return models.Point{X: q.X, Y: q.Y}
+	})
+	for i := range result.Pages {
+		result.Pages[i].Width, result.Pages[i].Height = info.OriginalSize.X, info.OriginalSize.Y
+	}
+	return result, nil
+}
+
+// credentials 解析本次请求使用的凭证并校验其完整性。
+func (c *Client) credentials(ctx context.Context) (auth.Credentials, error) {
+	creds, err := auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		APIKey:    c.APIKey,
+		APISecret: c.APISecret,
+	})
 	if err != nil {
-		return "", fmt.Errorf("invalid host url: %w", err)
+		return auth.Credentials{}, err
 	}
-	return auth.AssembleAuthURLWithHostPath(u.Scheme, u.Host, u.Path, "POST", c.APIKey, c.APISecret)
+	if creds.AppID == "" || creds.APIKey == "" || creds.APISecret == "" {
+		return auth.Credentials{}, fmt.Errorf("AppID/APIKey/APISecret is not configured")
+	}
+	return creds, nil
+}
+
+// signRequest 按客户端的鉴权模式（URL 查询参数或请求头）为请求签名。
+func (c *Client) signRequest(req *http.Request, creds auth.Credentials) error {
+	if creds.APIKey == "" || creds.APISecret == "" {
+		return fmt.Errorf("missing APIKey/APISecret")
+	}
+	return c.Signer.Sign(req, c.AuthMode, creds.APIKey, creds.APISecret, auth.SchemeTypeAPIKey)
 }
 
 // ----------- Public APIs -----------
 
 // RecognizePath reads file then calls RecognizeBytes.
 func (c *Client) RecognizePath(ctx context.Context, imagePath, imgEncoding, language string) (*OcrResponse, error) {
-	if err := c.validateCredentials(); err != nil {
-		return nil, err
-	}
 	data, err := os.ReadFile(imagePath)
 	if err != nil {
 		return nil, fmt.Errorf("read image file '%s' failed: %w", imagePath, err)
@@ -139,11 +353,61 @@ func (c *Client) RecognizePath(ctx context.Context, imagePath, imgEncoding, lang
 	return c.RecognizeBytes(ctx, data, imgEncoding, language)
 }
 
-// RecognizeBytes sends the image bytes to API.
+// RecognizeDocument splits a multi-page PDF or TIFF (or a single image) into pages and recognizes
+// up to concurrency pages at a time (document.DefaultConcurrency when <= 0). Results are ordered by
+// page number; a page that cannot be extracted or recognized carries its own error.
+func (c *Client) RecognizeDocument(ctx context.Context, path, language string, concurrency int) ([]document.Result[*OcrResponse], error) {
+	pages, err := document.SplitFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("split document '%s' failed: %w", path, err)
+	}
+	return document.Recognize(ctx, pages, concurrency, func(ctx context.Context, page document.Page) (*OcrResponse, error) {
+		return c.RecognizeBytes(ctx, page.Data, page.Format, language)
+	}), nil
+}
+
+// RecognizeBytes sends the image bytes to API, after preprocessing them when c.Preprocess is set.
 func (c *Client) RecognizeBytes(ctx context.Context, image []byte, imgEncoding, language string) (*OcrResponse, error) {
-	if err := c.validateCredentials(); err != nil {
+	if len(image) == 0 {
+		return nil, fmt.Errorf("empty image data")
+	}
+	var info *imageprep.Info
+	if c.Preprocess != nil {
+		image, imgEncoding, info = c.preprocess(image, imgEncoding)
+	}
+	resp, err := c.recognize(ctx, image, imgEncoding, language)
+	if err != nil {
 		return nil, err
 	}
+	resp.Preprocessing = info
+	return resp, nil
+}
+
+// preprocess 按 c.Preprocess 处理图片，输出不超过 MaxUncompressedBytes；失败时返回原图与 nil。
+func (c *Client) preprocess(image []byte, imgEncoding string) ([]byte, string, *imageprep.Info) {
+	p := *c.Preprocess
+	if p.MaxBytes <= 0 || p.MaxBytes > MaxUncompressedBytes {
+		p.MaxBytes = MaxUncompressedBytes
+	}
+	res, err := p.Process(image)
+	if err != nil {
+		c.Logger.Warn("image preprocessing failed, sending the original image", "error", err)
+		if imgEncoding == "" {
+			imgEncoding = detectImageEncoding(image)
+		}
+		return image, imgEncoding, nil
+	}
+	c.Logger.Debug("image preprocessed",
+		"steps", res.Info.Steps,
+		"skew", res.Info.Skew,
+		"size", res.Info.Size,
+		"bytes", len(res.Data),
+	)
+	return res.Data, res.Format, &res.Info
+}
+
+// recognize 把图片原样发送给识别服务。每次尝试都重新解析凭证，轮换后的密钥在重试中立即生效。
+func (c *Client) recognize(ctx context.Context, image []byte, imgEncoding, language string) (*OcrResponse, error) {
 	if len(image) == 0 {
 		return nil, fmt.Errorf("empty image data")
 	}
@@ -155,56 +419,81 @@ func (c *Client) RecognizeBytes(ctx context.Context, image []byte, imgEncoding,
 		language = "cn|en"
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(image)
-
 	// Build JSON body
 	var body requestBody
-	body.Header = reqHeader{
-		AppID:  c.AppID,
-		Status: 3,
-	}
+	body.Header.Status = 3
 	body.Parameter.OCR.Language = language
 	body.Parameter.OCR.OcrOutputText.Encoding = "utf8"
 	body.Parameter.OCR.OcrOutputText.Compress = "raw"
 	body.Parameter.OCR.OcrOutputText.Format = "json"
 	body.Payload.Image.Encoding = imgEncoding
-	body.Payload.Image.Image = encoded
+	body.Payload.Image.Image = stream.Placeholder // 发送时流式编码，不生成完整的 base64 字符串
 	body.Payload.Image.Status = 3
 
-	payload, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request body failed: %w", err)
-	}
+	var ocrResp *OcrResponse
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		creds, err := c.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		body.Header.AppID = creds.AppID
+		payload, err := stream.JSON(body, stream.Bytes(image))
+		if err != nil {
+			return fmt.Errorf("marshal request body failed: %w", err)
+		}
+		ctx, call := c.Telemetry.Start(ctx, serviceName, "recognize", c.Host)
+		call.AddBytesSent(int(payload.Len()))
+		ocrResp, err = endpoint.Failover(c.Logger, c.endpoints(), func(host string) (*OcrResponse, error) {
+			return c.send(ctx, host, creds, payload, language)
+		})
+		if err == nil {
+			telemetry.SetSID(ctx, ocrResp.Header.Sid)
+		}
+		call.End(err)
+		return err
+	})
+	return ocrResp, err
+}
 
-	payloadShort := utils.SafeSnippet(payload, 512)
-	c.Logger.Debug("building ocr request body", "payloadShort", payloadShort)
+// endpoints 返回主地址与备用地址。
+func (c *Client) endpoints() []string {
+	return append([]string{c.Host}, c.Fallbacks...)
+}
 
-	authURL, err := c.buildAuthURL()
+// send 构造、签名并向 host 发送一次请求。每次重试都会调用 send 重新签名，因为签名中的 date 会过期。
+func (c *Client) send(ctx context.Context, host string, creds auth.Credentials, payload *stream.Body, language string) (*OcrResponse, error) {
+	release, err := c.Limiter.Acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("build auth url failed: %w", err)
+		return nil, err
 	}
+	defer release()
 
-	c.Logger.Debug("sending ocr request", "url", authURL, "category", language)
+	c.Logger.Debug("building ocr request body", "payloadShort", payload.Snippet(512))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, bytes.NewReader(payload))
+	req, err := payload.NewRequest(ctx, http.MethodPost, host)
 	if err != nil {
 		c.Logger.Error("create request failed", "error", err)
 		return nil, fmt.Errorf("create request failed: %w", err)
 	}
+	if err := c.signRequest(req, creds); err != nil {
+		return nil, fmt.Errorf("build auth url failed: %w", err)
+	}
 
-	u, _ := url.Parse(c.Host)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Host", u.Host)    // keep same as demo
-	req.Header.Set("App_id", c.AppID) // casing per demo
+	req.Header.Set("App_id", creds.AppID) // casing per demo
+
+	c.Logger.Debug("sending ocr request", "url", auth.RedactURL(req.URL.String()), "auth_mode", c.AuthMode, "category", language)
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
+		err = &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)}
 		c.Logger.Error("send request failed", "error", err)
 		return nil, fmt.Errorf("send request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	c.Signer.ObserveResponse(resp)
 
-	respBytes, err := io.ReadAll(resp.Body)
+	respBytes, err := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 	if err != nil {
 		c.Logger.Error("read response failed", "error", err)
 		return nil, fmt.Errorf("read response failed: %w", err)
@@ -212,7 +501,13 @@ func (c *Client) RecognizeBytes(ctx context.Context, image []byte, imgEncoding,
 
 	respBodyShort := utils.SafeSnippet(respBytes, 512)
 
-	c.Logger.Debug("received ocr response", "status_code", resp, "body", respBodyShort)
+	c.Logger.Debug("received ocr response", "status_code", resp.StatusCode, "body", respBodyShort)
+
+	// 网关鉴权失败时返回非 200 状态码和 {"message": ...}，不含 header.code
+	if resp.StatusCode != http.StatusOK {
+		c.Logger.Error("unexpected status", "status_code", resp.StatusCode, "body", respBodyShort)
+		return nil, xfyunerr.FromStatus(serviceName, resp.StatusCode, respBytes)
+	}
 
 	var ocrResp OcrResponse
 	if err := json.Unmarshal(respBytes, &ocrResp); err != nil {
@@ -224,19 +519,36 @@ func (c *Client) RecognizeBytes(ctx context.Context, image []byte, imgEncoding,
 			"code", ocrResp.Header.Code,
 			"message", ocrResp.Header.Message,
 		)
-		return nil, fmt.Errorf("API error: code=%d, message=%s", ocrResp.Header.Code, ocrResp.Header.Message)
+		return nil, &xfyunerr.APIError{Service: serviceName, Code: ocrResp.Header.Code, Message: ocrResp.Header.Message, SID: ocrResp.Header.Sid, HTTPStatus: http.StatusOK}
 	}
 	return &ocrResp, nil
 }
 
-// RecognizeAuto：给我原始图片字节 + GPU 分类（如 "mix0"、"cam.xxx"、"atlas.xxx"）即可。
+// RecognizeAuto：给我原始图片字节 + 分类（如 "mix0"、"cam.xxx"、"atlas.xxx"）即可。
 // 1) 大于 7.5MB 自动压到 ~2MB；2) 自动探测 jpg/png 等编码；3) 自动从分类得到 language(ASECode)。
+// 分类先按 c.Backend 的分类名查找，带其他硬件前缀时按前缀查找。
 func (c *Client) RecognizeAuto(ctx context.Context, raw []byte, category string) (*OcrResponse, error) {
 	if len(raw) == 0 {
 		return nil, fmt.Errorf("empty image")
 	}
 
-	// 1) 压缩（>7.5MB 才压）
+	// 1) 压缩与编码探测；配置了预处理时由 RecognizeBytes 预处理，不再另行压缩
+	img, enc := raw, ""
+	if c.Preprocess == nil {
+		img, enc = prepareImage(raw)
+	}
+
+	// 2) language 自动从分类推导（取第一个 ASECode）
+	lang := pickASELanguageFromCategory(c.languages(), c.Backend, category)
+	if lang == "" {
+		lang = "ch_en" // 兜底中英
+	}
+
+	return c.RecognizeBytes(ctx, img, enc, lang)
+}
+
+// prepareImage 压缩过大的图片（>7.5MB 才压）并探测编码。
+func prepareImage(raw []byte) ([]byte, string) {
 	img := raw
 	if int64(len(raw)) > MaxUncompressedBytes {
 		// 优先走你现有的压缩工具
@@ -250,19 +562,11 @@ func (c *Client) RecognizeAuto(ctx context.Context, raw []byte, category string)
 		}
 	}
 
-	// 2) 编码探测
 	enc := detectImageEncoding(img)
 	if enc == "" {
 		enc = "jpg"
 	}
-
-	// 3) language 自动从 GPU 分类推导（取第一个 ASECode）
-	lang := pickASELanguageFromCategory(category)
-	if lang == "" {
-		lang = "ch_en" // 兜底中英
-	}
-
-	return c.RecognizeBytes(ctx, img, enc, lang)
+	return img, enc
 }
 
 func encodingFromFilename(path string) string {
@@ -341,12 +645,26 @@ func compressJPEGFallback(src []byte, target int64) ([]byte, error) {
 	return out, nil
 }
 
-// 用你的 ocrblock.GPUCategoryToLanguages 来挑第一候选的 ASECode
-func pickASELanguageFromCategory(category string) string {
+// Category 返回 language（ASE 代码、语种代码或 ISO 639 / BCP-47 标签）在 c.Backend 上的分类名，
+// 找不到时返回空字符串。
+func (c *Client) Category(language string) string {
+	return c.languages().CategoryFor(c.Backend, language)
+}
+
+// languages 返回客户端使用的语言表
+func (c *Client) languages() *LanguageMap {
+	if c.LanguageMap != nil {
+		return c.LanguageMap
+	}
+	return GetInstance()
+}
+
+// 用 LanguageMap.FindByCategory 来挑第一候选的 ASECode
+func pickASELanguageFromCategory(lm *LanguageMap, b Backend, category string) string {
 	if strings.TrimSpace(category) == "" {
 		return ""
 	}
-	langs := GPUCategoryToLanguages(category)
+	langs := lm.FindByCategory(b, category)
 	if len(langs) == 0 || langs[0] == nil {
 		return ""
 	}
@@ -356,9 +674,6 @@ func pickASELanguageFromCategory(category string) string {
 // RecognizeBase64 接收 base64（可为 data URL）并调用 OCR API。
 // 优先从 data URL 的 MIME 推断 imgEncoding；否则使用调用方传入的 imgEncoding。
 func (c *Client) RecognizeBase64(ctx context.Context, b64, imgEncoding, language string) (*OcrResponse, error) {
-	if c.AppID == "" || c.APIKey == "" || c.APISecret == "" {
-		return nil, fmt.Errorf("AppID/APIKey/APISecret is not configured")
-	}
 	if strings.TrimSpace(b64) == "" {
 		return nil, fmt.Errorf("empty base64 string")
 	}
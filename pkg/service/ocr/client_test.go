@@ -1,66 +1,442 @@
 package ocr
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
-	"goxfyunclient/internal/service/ocr/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/document"
+	"github.com/fruitbars/goxfyunclient/pkg/emulator"
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 )
 
+// echoLanguage 让模拟器把请求的语种作为识别结果返回。
+var echoLanguage = emulator.WithScript(emulator.ServiceOCR, func(req emulator.Request) emulator.Response {
+	return emulator.Response{Result: fmt.Sprintf(`{"pages":[{"lines":[{"conf":0.99,"words":[{"content":%q}]}]}]}`, req.Params["language"])}
+})
+
 func TestClient_Recognize_Success(t *testing.T) {
+	text := base64.StdEncoding.EncodeToString([]byte(`{"pages": [{"lines": [{"words": [{"content": "Test"}]}]}]}`))
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 模拟成功响应
-		resp := models.Response{
-			Header: models.Header{Code: 0, Message: "Success", Sid: "test-sid-success"},
-			Payload: models.Payload{
-				Result: models.Result{
-					Text: "eyJzdGF0dXMiOiAzLCAicGFnZXMiOiBbeyJhbmdsZSI6IDAsICJoZWlnaHQiOiAxMjAwLCAid2lkdGgiOiA5MDAsICJjb250ZW50IjogW3siY29udGVudCI6ICJUZXN0IiwgInBvc2l0aW9uIjogWzAsIDAsIDkwMCwgMF0gfV0gfV19", // base64 of `{"status": 3, "pages": [{"angle": 0, "height": 1200, "width": 900, "content": [{"content": "Test", "position": [0, 0, 900, 0]}]}]}`
-				},
-			},
-		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(resp)
+		fmt.Fprintf(w, `{"header": {"code": 0, "message": "success", "sid": "test-sid-success"}, "payload": {"ocr_output_text": {"text": %q}}}`, text)
 	}))
 	defer server.Close()
 
 	client := NewClient("app-id", "api-key", "api-secret", WithHost(server.URL))
 
-	result, err := client.Recognize(context.Background(), "dummy-base64-image", General)
+	resp, err := client.RecognizeBase64(context.Background(), "ZHVtbXk=", "jpg", "ch_en")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
-	if result.Header.Sid != "test-sid-success" {
-		t.Errorf("Expected sid 'test-sid-success', got '%s'", result.Header.Sid)
+	if resp.Header.Sid != "test-sid-success" {
+		t.Errorf("Expected sid 'test-sid-success', got '%s'", resp.Header.Sid)
 	}
-	if result.Payload.Result.Text == "" {
-		t.Error("Expected result text to be non-empty")
+	result, err := resp.Result()
+	if err != nil || result.PlainText() != "Test" {
+		t.Errorf("Expected result text 'Test', got %+v, %v", result, err)
 	}
 }
 
 func TestClient_Recognize_ApiError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 模拟 API 错误响应
-		resp := models.Response{
-			Header: models.Header{Code: 10106, Message: "Invalid parameter", Sid: "test-sid-error"},
-		}
+		// 模拟 API 错误响应，讯飞接口即使业务失败，HTTP状态码也可能是200
 		w.Header().Set("Content-Type", "application/json")
-		// 讯飞接口即使业务失败，HTTP状态码也可能是200
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(resp)
+		fmt.Fprint(w, `{"header": {"code": 10106, "message": "Invalid parameter", "sid": "test-sid-error"}}`)
 	}))
 	defer server.Close()
 
 	client := NewClient("app-id", "api-key", "api-secret", WithHost(server.URL))
 
-	_, err := client.Recognize(context.Background(), "dummy-base64-image", General)
-	if err == nil {
-		t.Fatal("Expected an error, but got nil")
+	_, err := client.RecognizeBase64(context.Background(), "ZHVtbXk=", "jpg", "ch_en")
+	var apiErr *xfyunerr.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 10106 || apiErr.SID != "test-sid-error" {
+		t.Errorf("Expected an APIError with code 10106, got %v", err)
+	}
+}
+
+// flakyServer 返回一个识别服务：前 failures 次请求返回业务错误码 code，之后返回成功；每次请求先交给 record。
+func flakyServer(t *testing.T, failures, code int, record func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls <= failures {
+			fmt.Fprintf(w, `{"header": {"code": %d, "message": "busy", "sid": "sid-busy"}}`, code)
+			return
+		}
+		fmt.Fprint(w, `{"header": {"code": 0, "message": "success", "sid": "sid-ok"}, "payload": {"ocr_output_text": {"text": "eyJwYWdlcyI6W119"}}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_RetryResigns(t *testing.T) {
+	var dates []string
+	srv := flakyServer(t, 2, 11202, func(r *http.Request) {
+		dates = append(dates, r.URL.Query().Get("date"))
+	})
+
+	// 每次签名时钟前进 1 秒，验证每次重试都重新签名
+	now := time.Now()
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	policy := &retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+	client := NewClient("app-id", "api-key", "api-secret", WithHost(srv.URL),
+		WithRetry(policy), WithSigner(auth.NewSigner(auth.WithClock(clock))))
+
+	if _, err := client.RecognizeBase64(context.Background(), "ZHVtbXk=", "jpg", "ch_en"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(dates) != 3 || dates[0] == dates[1] || dates[1] == dates[2] {
+		t.Errorf("Expected 3 attempts with distinct dates, got %v", dates)
+	}
+}
+
+func TestClient_Middleware(t *testing.T) {
+	var tenants []string
+	srv := flakyServer(t, 1, 10700, func(r *http.Request) {
+		tenants = append(tenants, r.Header.Get("X-Tenant"))
+	})
+
+	var attempts int
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return next.RoundTrip(req)
+		})
+	}
+	policy := &retry.Policy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Multiplier: 2}
+	client := NewClient("app-id", "api-key", "api-secret", WithHost(srv.URL), WithRetry(policy),
+		WithHTTPClient(&http.Client{Timeout: 5 * time.Second}),
+		WithMiddleware(count, middleware.SetHeader("X-Tenant", "acme")))
+
+	if _, err := client.RecognizeBase64(context.Background(), "ZHVtbXk=", "jpg", "ch_en"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 2 || strings.Join(tenants, ",") != "acme,acme" {
+		t.Errorf("Expected the middlewares to see both attempts, got attempts=%d tenants=%v", attempts, tenants)
+	}
+}
+
+func TestClient_Telemetry(t *testing.T) {
+	srv := flakyServer(t, 1, 11202, func(*http.Request) {})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tel, err := telemetry.New(telemetry.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	policy := &retry.Policy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Multiplier: 2}
+	client := NewClient("app-id", "api-key", "api-secret", WithHost(srv.URL), WithRetry(policy), WithTelemetry(tel))
+
+	if _, err := client.RecognizeBase64(context.Background(), "ZHVtbXk=", "jpg", "ch_en"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected one span per attempt, got %d", len(spans))
+	}
+	wantSIDs := []string{"sid-busy", "sid-ok"}
+	for i, span := range spans {
+		var sid string
+		var attempt int64
+		for _, kv := range span.Attributes {
+			switch kv.Key {
+			case telemetry.AttrSID:
+				sid = kv.Value.AsString()
+			case telemetry.AttrAttempt:
+				attempt = kv.Value.AsInt64()
+			}
+		}
+		if span.Name != "ocr.recognize" || sid != wantSIDs[i] || attempt != int64(i+1) {
+			t.Errorf("Unexpected span %d: name=%s sid=%s attempt=%d", i+1, span.Name, sid, attempt)
+		}
+	}
+}
+
+// 每次重试都重新解析凭证，轮换后的密钥在重试中立即生效。
+func TestClient_Recognize_RotatedCredentials(t *testing.T) {
+	var appIDs []string
+	srv := httptest.NewServer(emulator.New(emulator.WithScript(emulator.ServiceOCR, func(req emulator.Request) emulator.Response {
+		appIDs = append(appIDs, req.AppID)
+		if len(appIDs) == 1 {
+			return emulator.Response{Code: 10200} // 可重试的读取数据超时
+		}
+		return emulator.Response{Result: `{"pages":[]}`}
+	})))
+	defer srv.Close()
+
+	resolved := 0
+	provider := auth.CredentialProviderFunc(func(ctx context.Context) (auth.Credentials, error) {
+		resolved++
+		return auth.Credentials{AppID: fmt.Sprintf("app-%d", resolved), APIKey: "api-key", APISecret: "api-secret"}, nil
+	})
+	client := NewClient("", "", "",
+		WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)),
+		WithCredentials(provider),
+		WithRetry(&retry.Policy{MaxAttempts: 2}))
+	if _, err := client.RecognizeBytes(context.Background(), []byte("image"), "jpg", "ch_en"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := []string{"app-1", "app-2"}; fmt.Sprint(appIDs) != fmt.Sprint(want) {
+		t.Errorf("Expected app IDs %v, got %v", want, appIDs)
+	}
+}
+
+func TestClient_RecognizeDocument(t *testing.T) {
+	srv := httptest.NewServer(emulator.New())
+	defer srv.Close()
+	client := NewClient("app-id", "api-key", "api-secret", WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)))
+
+	// 三页 PDF，第 2 页没有图片
+	pdf := "%PDF-1.4\n" +
+		"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+		"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 >> endobj\n" +
+		"3 0 obj << /Type /Page /Resources << /XObject << /Im0 6 0 R >> >> >> endobj\n" +
+		"4 0 obj << /Type /Page >> endobj\n" +
+		"5 0 obj << /Type /Page /Resources << /XObject << /Im0 6 0 R >> >> >> endobj\n" +
+		"6 0 obj << /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 1 >>\nstream\n\x80\nendstream endobj\n" +
+		"trailer << /Root 1 0 R >>\n%%EOF\n"
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	if err := os.WriteFile(path, []byte(pdf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := client.RecognizeDocument(context.Background(), path, "ch_en", 2)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Expected 3 page results, got %d, %v", len(results), err)
+	}
+	for i, r := range results {
+		if r.Page != i+1 {
+			t.Errorf("Expected page %d, got %d", i+1, r.Page)
+		}
+	}
+	if results[0].Err != nil || results[2].Err != nil || !errors.Is(results[1].Err, document.ErrNoImage) {
+		t.Errorf("Expected only page 2 to fail, got %v, %v, %v", results[0].Err, results[1].Err, results[2].Err)
+	}
+	if result, err := results[2].Value.Result(); err != nil || result.PlainText() != "讯飞开放平台" {
+		t.Errorf("Expected the typed result for page 3, got %+v, %v", result, err)
+	}
+}
+
+func TestClient_RecognizeAutoDetect(t *testing.T) {
+	line := func(text string, conf float64) emulator.Response {
+		return emulator.Response{Result: fmt.Sprintf(`{"pages":[{"lines":[{"conf":%v,"words":[{"content":%q}]}]}]}`, conf, text)}
+	}
+	// 图片内容即图片的真实语种；以其他语种识别时得到低置信度的乱码
+	srv := httptest.NewServer(emulator.New(
+		emulator.WithScript(emulator.ServiceOCR, func(req emulator.Request) emulator.Response {
+			actual, lang := string(req.Input), req.Params["language"]
+			switch {
+			case actual == lang:
+				return line("识别正确", 0.95)
+			case actual == "ch_en":
+				return line("讯飞开放平台", 0.99)
+			case actual == "th":
+				return line("สวัสดีครับ", 0.4)
+			case actual == "ar":
+				return line("lvwlo ilc", 0.3)
+			}
+			return line("zzz", 0.2)
+		}),
+	))
+	defer srv.Close()
+	detector := LanguageDetectorFunc(func(ctx context.Context, text string) (map[string]float64, error) {
+		if text == "lvwlo ilc" {
+			return map[string]float64{"ar": 0.7, "en": 0.3}, nil
+		}
+		return map[string]float64{"xx": 1}, nil
+	})
+	client := NewClient("app-id", "api-key", "api-secret",
+		WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)), WithLanguageDetector(detector))
+	client.AutoDetectCandidates = []string{"ja", "ko", "ru"}
+	ctx := context.Background()
+
+	tests := []struct {
+		image  string
+		want   string
+		source LanguageSource
+		passes int
+	}{
+		{"ch_en", "ch_en", LanguageFromFirstPass, 1},
+		{"th", "th", LanguageFromScript, 2},
+		{"ar", "ar", LanguageFromDetector, 2},
+		{"ko", "ko", LanguageFromCandidates, 3},
+		// 所有语种的置信度都不足时返回置信度最高的一遍，并列时保留先识别的
+		{"he", "ch_en", LanguageFromFirstPass, 4},
+	}
+	for _, tt := range tests {
+		got, err := client.RecognizeAutoDetect(ctx, []byte(tt.image))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.image, err)
+			continue
+		}
+		if got.Language != tt.want || got.Source != tt.source || got.Passes != tt.passes {
+			t.Errorf("%s: expected %s from %s in %d passes, got %s from %s in %d passes",
+				tt.image, tt.want, tt.source, tt.passes, got.Language, got.Source, got.Passes)
+		}
+		if tt.image != "he" && got.Confidence < DefaultAutoDetectConfidence {
+			t.Errorf("%s: expected a confident result, got %v", tt.image, got.Confidence)
+		}
+	}
+}
+
+// 第一遍之后的识别失败不影响已有结果；不在语言表中的语种不会被发送。
+func TestClient_RecognizeAutoDetect_PartialFailure(t *testing.T) {
+	table := DefaultLanguageTable()
+	languages := table.Languages[:0]
+	for _, l := range table.Languages {
+		if l.ASECode != "th" {
+			languages = append(languages, l)
+		}
+	}
+	table.Languages = languages
+	lm, err := NewLanguageMap(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []string
+	srv := httptest.NewServer(emulator.New(emulator.WithScript(emulator.ServiceOCR, func(req emulator.Request) emulator.Response {
+		lang := req.Params["language"]
+		sent = append(sent, lang)
+		if lang == "ru" {
+			return emulator.Response{Code: 10106} // 部署不支持该语种
+		}
+		return emulator.Response{Result: `{"pages":[{"lines":[{"conf":0.4,"words":[{"content":"สวัสดีครับ"}]}]}]}`}
+	})))
+	defer srv.Close()
+	client := NewClient("app-id", "api-key", "api-secret",
+		WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)), WithLanguageMap(lm))
+	client.AutoDetectCandidates = []string{"ru", "ko"}
+
+	got, err := client.RecognizeAutoDetect(context.Background(), []byte("th"))
+	if err != nil {
+		t.Fatalf("Expected the first pass to be kept, got %v", err)
+	}
+	if got.Language != "ch_en" || got.Detected != "" || got.Passes != 3 || got.Confidence != 0.4 {
+		t.Errorf("Unexpected result: %s (detected %q) in %d passes, confidence %v", got.Language, got.Detected, got.Passes, got.Confidence)
+	}
+	if want := []string{"ch_en", "ru", "ko"}; fmt.Sprint(sent) != fmt.Sprint(want) {
+		t.Errorf("Expected passes %v, got %v", want, sent)
+	}
+}
+
+func TestClient_OCRBackend(t *testing.T) {
+	srv := httptest.NewServer(emulator.New(echoLanguage))
+	defer srv.Close()
+	ocrClient := NewClient("app-id", "api-key", "api-secret",
+		WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)), WithBackend(BackendCambricon))
+	ctx := context.Background()
+
+	if got := ocrClient.Category("pt-BR"); got != "cam.mix0" {
+		t.Errorf("Expected cam.mix0 for pt-BR, got %q", got)
 	}
-	expectedError := "API返回错误: code=10106, message=Invalid parameter, sid=test-sid-error"
-	if err.Error() != expectedError {
-		t.Errorf("Expected error message '%s', got '%s'", expectedError, err.Error())
+	// 分类名按 Backend 解析，带其他硬件前缀时按前缀解析
+	for category, want := range map[string]string{"cam.mix1": "ja", "atlas.thai": "th", "mix8": "fa", "cam.unknown": "ch_en"} {
+		resp, err := ocrClient.RecognizeAuto(ctx, []byte("image"), category)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", category, err)
+		}
+		result, err := resp.Result()
+		if err != nil {
+			t.Fatalf("%s: expected a result, got %v", category, err)
+		}
+		if got := result.PlainText(); got != want {
+			t.Errorf("%s: expected language %s, got %s", category, want, got)
+		}
+	}
+
+	got, err := ocrClient.RecognizeAutoDetect(ctx, []byte("image"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Category != "cam.ch_en" {
+		t.Errorf("Expected category cam.ch_en, got %q", got.Category)
+	}
+}
+
+// pngImage 返回指定大小的空白 PNG 图片。
+func pngImage(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestClient_ImagePreprocess(t *testing.T) {
+	// 识别服务返回收到的图片的大小，行坐标覆盖整张图片
+	srv := httptest.NewServer(emulator.New(emulator.WithScript(emulator.ServiceOCR, func(req emulator.Request) emulator.Response {
+		var s image.Point
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(req.Input)); err == nil {
+			s = image.Pt(cfg.Width, cfg.Height)
+		}
+		return emulator.Response{Result: fmt.Sprintf(`{"pages":[{"width":%d,"height":%d,"lines":[{"conf":0.99,"coord":[{"x":0,"y":0},{"x":%d,"y":%d}],"words":[{"content":"%dx%d"}]}]}]}`,
+			s.X, s.Y, s.X, s.Y, s.X, s.Y)}
+	})))
+	defer srv.Close()
+	client := NewClient("app-id", "api-key", "api-secret",
+		WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)),
+		WithPreprocess(&imageprep.Pipeline{MaxDimension: 100}))
+	ctx := context.Background()
+
+	resp, err := client.RecognizeBytes(ctx, pngImage(t, 400, 200), "png", "ch_en")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if info := resp.Preprocessing; info == nil || info.Size != image.Pt(100, 50) || info.Scale != 0.25 {
+		t.Fatalf("Unexpected preprocessing %+v", resp.Preprocessing)
+	}
+	result, _ := resp.Result()
+	if b := result.Pages[0].Lines[0].Bounds(); b != image.Rect(0, 0, 100, 50) {
+		t.Errorf("Expected coordinates on the 100x50 image, got %v", b)
+	}
+	original, err := resp.OriginalResult()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	page := original.Pages[0]
+	if b := page.Lines[0].Bounds(); b != image.Rect(0, 0, 400, 200) || page.Width != 400 || page.Height != 200 {
+		t.Errorf("Expected coordinates on the 400x200 original, got %v in %dx%d", b, page.Width, page.Height)
+	}
+
+	// 无法解码的图片原样发送
+	resp, err = client.RecognizeBytes(ctx, []byte("raw bytes"), "jpg", "ch_en")
+	if err != nil || resp.Preprocessing != nil {
+		t.Errorf("Expected the original image to be sent, got %+v, %v", resp, err)
+	}
+	if original, err := resp.OriginalResult(); err != nil || original.PlainText() != "0x0" {
+		t.Errorf("Expected the unmapped result, got %+v, %v", original, err)
+
 	}
 }
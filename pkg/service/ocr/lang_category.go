@@ -1,22 +1,26 @@
 package ocr
 
 import (
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Language 结构体用于存储语言信息
 type Language struct {
-	ID           int    // 编号
-	Name         string // 语种名称
-	Code         string // 语种代码
-	NvidiaGPU    string // 英伟达 GPU
-	CambriconMLU string // 寒武纪 MLU
-	HuaweiAtlas  string // 华为 Atlas
-	ASECode      string // ASE的语种代码
+	ID           int    `json:"id" yaml:"id"`                       // 编号
+	Name         string `json:"name" yaml:"name"`                   // 语种名称
+	Code         string `json:"code" yaml:"code"`                   // 语种代码
+	NvidiaGPU    string `json:"nvidia_gpu" yaml:"nvidia_gpu"`       // 英伟达 GPU
+	CambriconMLU string `json:"cambricon_mlu" yaml:"cambricon_mlu"` // 寒武纪 MLU
+	HuaweiAtlas  string `json:"huawei_atlas" yaml:"huawei_atlas"`   // 华为 Atlas
+	ASECode      string `json:"ase_code" yaml:"ase_code"`           // ASE的语种代码
+	ISO6391      string `json:"iso639_1" yaml:"iso639_1"`           // ISO 639-1 代码，也是 BCP-47 标签的主语言子标签
+	ISO6393      string `json:"iso639_3" yaml:"iso639_3"`           // ISO 639-3 代码
 }
 
-// LanguageMap 用于存储和查询语言数据
+// LanguageMap 用于存储和查询语言数据，创建后只读，可以并发使用
 type LanguageMap struct {
 	byID           map[int]*Language
 	byName         map[string]*Language
@@ -25,175 +29,40 @@ type LanguageMap struct {
 	byCambriconMLU map[string][]*Language
 	byHuaweiAtlas  map[string][]*Language
 	byASECode      map[string]*Language
+	byISO          map[string]*Language // ISO 639-1 与 ISO 639-3 代码及别名
+	all            []*Language          // 按 ID 排列
 }
 
 var (
-	instance *LanguageMap
-	once     sync.Once
+	current     atomic.Pointer[LanguageMap]
+	defaultMap  *LanguageMap
+	defaultOnce sync.Once
 )
 
-// GetInstance 返回LanguageMap的单例实例
+// GetInstance 返回当前使用的 LanguageMap：SetLanguageMap 设置的语言表，未设置时为内置语言表
 func GetInstance() *LanguageMap {
-	once.Do(func() {
-		instance = &LanguageMap{
-			byID:           make(map[int]*Language),
-			byName:         make(map[string]*Language),
-			byCode:         make(map[string]*Language),
-			byNvidiaGPU:    make(map[string][]*Language),
-			byCambriconMLU: make(map[string][]*Language),
-			byHuaweiAtlas:  make(map[string][]*Language),
-			byASECode:      make(map[string]*Language),
-		}
-		instance.initData()
-	})
-	return instance
-}
-
-// initData 初始化语言数据
-func (lm *LanguageMap) initData() {
-	// ASE代码映射
-	aseCodeMap := map[string]string{
-		"af":    "荷兰",
-		"az":    "阿塞拜疆",
-		"bg":    "保加利亚",
-		"bn":    "孟加拉",
-		"ch_en": "中英",
-		"cs":    "捷克",
-		"da":    "丹麦",
-		"de":    "德语",
-		"el":    "希腊",
-		"es":    "西班牙语",
-		"fa":    "波斯",
-		"fi":    "芬兰",
-		"fr":    "法语",
-		"ha":    "豪撒",
-		"he":    "希伯来",
-		"hr":    "克罗地亚",
-		"hu":    "匈牙利",
-		"hy":    "亚美尼亚",
-		"id":    "印尼语",
-		"it":    "意大利语",
-		"ja":    "日语",
-		"ka":    "格鲁吉亚",
-		"kka":   "哈萨克语",
-		"ko":    "韩语",
-		"lo":    "老挝",
-		"lt":    "立陶宛",
-		"lv":    "拉脱维亚",
-		"mn":    "内蒙语",
-		"ms":    "马来语",
-		"nb":    "挪威",
-		"pl":    "波兰",
-		"ps":    "普什图",
-		"pt":    "葡萄牙语",
-		"ro":    "罗马尼亚",
-		"ru":    "俄语",
-		"sk":    "斯洛伐克",
-		"sl":    "斯洛文尼亚",
-		"sr":    "塞尔维亚",
-		"sv":    "瑞典",
-		"sw":    "斯瓦西里",
-		"ta":    "泰米尔",
-		"te":    "泰卢固",
-		"tg":    "塔吉克",
-		"tk":    "土库曼",
-		"tl":    "菲律宾",
-		"tr":    "土耳其",
-		"uk":    "乌克兰",
-		"ur":    "乌尔都",
-		"uz":    "乌兹别克",
-		"vi":    "越南语",
-		"hi":    "印地语",
-		"th":    "泰语",
-		"ar":    "阿拉伯语",
-	}
-
-	// 定义语言数据
-	languages := []Language{
-		{1, "中英", "ch_en", "ch_en", "cam.ch_en", "atlas.ch_en", "ch_en"},
-		{2, "印地语", "hindi", "hindi", "cam.hindi", "atlas.hindi", "hi"},
-		{3, "阿拉伯语", "ar", "arabic", "cam.arabic", "atlas.arabic", "ar"},
-		{4, "泰语", "thai", "thai", "cam.thai", "atlas.thai", "th"},
-		{5, "越南语", "viet", "viet", "cam.viet", "atlas.viet", "vi"},
-		{6, "匈牙利语", "hu", "hu", "cam.hu", "atlas.hu", "hu"},
-		{7, "法语", "fr", "mix0", "cam.mix0", "atlas.mix0", "fr"},
-		{8, "西班牙语", "es", "mix0", "cam.mix0", "atlas.mix0", "es"},
-		{9, "德语", "de", "mix0", "cam.mix0", "atlas.mix0", "de"},
-		{10, "意大利语", "it", "mix0", "cam.mix0", "atlas.mix0", "it"},
-		{11, "葡萄牙语", "pt", "mix0", "cam.mix0", "atlas.mix0", "pt"},
-		{12, "马来语", "ms", "mix0", "cam.mix0", "atlas.mix0", "ms"},
-		{13, "印尼语", "id", "mix0", "cam.mix0", "atlas.mix0", "id"},
-		{14, "日语", "ja", "mix1", "cam.mix1", "atlas.mix1", "ja"},
-		{15, "韩语", "ko", "mix1", "cam.mix1", "atlas.mix1", "ko"},
-		{16, "俄语", "ru", "mix1", "cam.mix1", "atlas.mix1", "ru"},
-		{17, "哈萨克语", "kka", "mix1", "cam.mix1", "atlas.mix1", "kka"},
-		{18, "希腊语", "el", "mix3", "cam.mix3", "atlas.mix3", "el"},
-		{19, "老挝语", "lo", "mix3", "cam.mix3", "atlas.mix3", "lo"},
-		{20, "泰米尔语", "ta", "mix3", "cam.mix3", "atlas.mix3", "ta"},
-		{21, "泰卢固语", "te", "mix3", "cam.mix3", "atlas.mix3", "te"},
-		{22, "亚美尼亚语", "hy", "mix3", "cam.mix3", "atlas.mix3", "hy"},
-		{23, "格鲁吉亚语", "ka", "mix4", "cam.mix4", "atlas.mix4", "ka"},
-		{24, "拉脱维亚语", "lv", "mix4", "cam.mix4", "atlas.mix4", "lv"},
-		{25, "阿塞拜疆语", "az", "mix4", "cam.mix4", "atlas.mix4", "az"},
-		{26, "丹麦语", "da", "mix4", "cam.mix4", "atlas.mix4", "da"},
-		{27, "芬兰语", "fi", "mix4", "cam.mix4", "atlas.mix4", "fi"},
-		{28, "斯瓦西里语", "sw", "mix5", "cam.mix5", "atlas.mix5", "sw"},
-		{29, "罗马尼亚语", "ro", "mix5", "cam.mix5", "atlas.mix5", "ro"},
-		{30, "豪撒语", "ha", "mix5", "cam.mix5", "atlas.mix5", "ha"},
-		{31, "瑞典语", "sv", "mix5", "cam.mix5", "atlas.mix5", "sv"},
-		{32, "土耳其语", "tr", "mix5", "cam.mix5", "atlas.mix5", "tr"},
-		{33, "乌兹别克语", "uz", "mix5", "cam.mix5", "atlas.mix5", "uz"},
-		{34, "克罗地亚语", "hr", "mix6", "cam.mix6", "atlas.mix6", "hr"},
-		{35, "孟加拉语", "bn", "mix6", "cam.mix6", "atlas.mix6", "bn"},
-		{36, "波兰语", "pl", "mix6", "cam.mix6", "atlas.mix6", "pl"},
-		{37, "捷克语", "cs", "mix6", "cam.mix6", "atlas.mix6", "cs"},
-		{38, "菲律宾语", "tl", "mix6", "cam.mix6", "atlas.mix6", "tl"},
-		{39, "荷兰语", "af", "mix6", "cam.mix6", "atlas.mix6", "af"},
-		{40, "斯洛伐克语", "sk", "mix7", "cam.mix7", "atlas.mix7", "sk"},
-		{41, "立陶宛语", "lt", "mix7", "cam.mix7", "atlas.mix7", "lt"},
-		{42, "斯洛文尼亚语", "sl", "mix7", "cam.mix7", "atlas.mix7", "sl"},
-		{43, "挪威语", "nb", "mix7", "cam.mix7", "atlas.mix7", "nb"},
-		{44, "塔吉克语", "tg", "mix7", "cam.mix7", "atlas.mix7", "tg"},
-		{45, "土库曼语", "tk", "mix7", "cam.mix7", "atlas.mix7", "tk"},
-		{46, "波斯语", "fa", "mix8", "cam.mix8", "atlas.mix8", "fa"},
-		{47, "乌尔都语", "ur", "mix8", "cam.mix8", "atlas.mix8", "ur"},
-		{48, "希伯来语", "he", "mix8", "cam.mix8", "atlas.mix8", "he"},
-		{49, "普什图语", "ps", "mix8", "cam.mix8", "atlas.mix8", "ps"},
-		{50, "保加利亚语", "bg", "mix9", "cam.mix9", "atlas.mix9", "bg"},
-		{51, "乌克兰语", "uk", "mix9", "cam.mix9", "atlas.mix9", "uk"},
-		{52, "塞尔维亚语", "sr", "mix9", "cam.mix9", "atlas.mix9", "sr"},
-		{53, "蒙语", "mn", "mn", "cam.mn", "atlas.mn", "mn"},
-		{54, "维语", "uyg", "wei", "cam.uyg", "atlas.uyg", "uyg"},
+	if lm := current.Load(); lm != nil {
+		return lm
 	}
+	return DefaultLanguageMap()
+}
 
-	// 添加所有语言到映射中
-	for i := range languages {
-		lang := &languages[i]
-
-		// 检查ASE代码是否存在，如果不存在，尝试通过名称查找
-		if lang.ASECode == "" {
-			for code, name := range aseCodeMap {
-				if strings.Contains(lang.Name, name) || strings.Contains(name, strings.TrimSuffix(lang.Name, "语")) {
-					lang.ASECode = code
-					break
-				}
-			}
+// DefaultLanguageMap 返回内置语言表（languages.json）构建的 LanguageMap
+func DefaultLanguageMap() *LanguageMap {
+	defaultOnce.Do(func() {
+		lm, err := NewLanguageMap(DefaultLanguageTable())
+		if err != nil {
+			panic("ocr: invalid embedded language table: " + err.Error())
 		}
+		defaultMap = lm
+	})
+	return defaultMap
+}
 
-		lm.byID[lang.ID] = lang
-		lm.byName[lang.Name] = lang
-		lm.byCode[lang.Code] = lang
-
-		// 添加到GPU映射
-		lm.byNvidiaGPU[lang.NvidiaGPU] = append(lm.byNvidiaGPU[lang.NvidiaGPU], lang)
-		lm.byCambriconMLU[lang.CambriconMLU] = append(lm.byCambriconMLU[lang.CambriconMLU], lang)
-		lm.byHuaweiAtlas[lang.HuaweiAtlas] = append(lm.byHuaweiAtlas[lang.HuaweiAtlas], lang)
-
-		// 如果有ASE代码，添加到ASE映射
-		if lang.ASECode != "" {
-			lm.byASECode[lang.ASECode] = lang
-		}
-	}
+// SetLanguageMap 替换 GetInstance 及包级便捷函数使用的语言表，并发安全，已开始的查询不受影响。
+// lm 为 nil 时恢复内置语言表。
+func SetLanguageMap(lm *LanguageMap) {
+	current.Store(lm)
 }
 
 // FindByID 通过ID查找语言
@@ -351,8 +220,10 @@ func GetLanguageByASECode(aseCode string) *Language {
 
 // GPUToLanguages 根据任何GPU代码(NVIDIA、寒武纪或华为Atlas)查找支持的语言列表
 func GPUCategoryToLanguages(code string) []*Language {
-	lm := GetInstance()
+	return GetInstance().findByGPUCategory(code)
+}
 
+func (lm *LanguageMap) findByGPUCategory(code string) []*Language {
 	// 首先检查是否符合各平台代码格式，然后在相应的映射中查找
 	if strings.HasPrefix(code, "cam.") {
 		// 寒武纪MLU代码格式
@@ -366,3 +237,89 @@ func GPUCategoryToLanguages(code string) []*Language {
 		return lm.FindByNvidiaGPU(code)
 	}
 }
+
+// Languages 按 ID 顺序返回所有支持的语言
+func (lm *LanguageMap) Languages() []*Language {
+	return append([]*Language(nil), lm.all...)
+}
+
+// FindByTag 通过 ISO 639-1、ISO 639-3 代码或 BCP-47 标签（如 "zh-Hans-CN"、"pt_BR"）查找语言，
+// 也接受语种代码与 ASE 代码。不区分大小写，只按主语言子标签匹配。
+func (lm *LanguageMap) FindByTag(tag string) *Language {
+	tag = strings.TrimSpace(tag)
+	if lang := lm.byCode[tag]; lang != nil {
+		return lang
+	}
+	if lang := lm.byASECode[tag]; lang != nil {
+		return lang
+	}
+	tag = strings.ToLower(tag)
+	if lang := lm.byCode[tag]; lang != nil {
+		return lang
+	}
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if primary == "" {
+		return nil
+	}
+	return lm.byISO[primary]
+}
+
+// Categories 返回硬件 b 上所有的分类名，按字典序排列
+func (lm *LanguageMap) Categories(b Backend) []string {
+	var categories []string
+	for category := range lm.backendIndex(b) {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// FindByCategory 返回硬件 b 上分类 category 支持的语言。category 带有其他硬件的前缀
+// （或不带前缀）时按前缀查找，见 GPUCategoryToLanguages。
+func (lm *LanguageMap) FindByCategory(b Backend, category string) []*Language {
+	if langs, ok := lm.backendIndex(b)[category]; ok {
+		return langs
+	}
+	return lm.findByGPUCategory(category)
+}
+
+// CategoryFor 返回 tag（见 FindByTag）对应的语言在硬件 b 上的分类名，找不到时返回空字符串
+func (lm *LanguageMap) CategoryFor(b Backend, tag string) string {
+	lang := lm.FindByTag(tag)
+	if lang == nil {
+		return ""
+	}
+	return lang.Category(b)
+}
+
+func (lm *LanguageMap) backendIndex(b Backend) map[string][]*Language {
+	switch b {
+	case BackendCambricon:
+		return lm.byCambriconMLU
+	case BackendAtlas:
+		return lm.byHuaweiAtlas
+	case BackendNvidia:
+		return lm.byNvidiaGPU
+	}
+	return nil
+}
+
+// Languages 按 ID 顺序返回所有支持的语言（便捷函数）
+func Languages() []*Language {
+	return GetInstance().Languages()
+}
+
+// GetLanguageByTag 通过 ISO 639 代码或 BCP-47 标签查找语言（便捷函数）
+func GetLanguageByTag(tag string) *Language {
+	return GetInstance().FindByTag(tag)
+}
+
+// Categories 返回硬件 b 上所有的分类名（便捷函数）
+func Categories(b Backend) []string {
+	return GetInstance().Categories(b)
+}
+
+// CategoryFor 返回 tag 对应的语言在硬件 b 上的分类名（便捷函数）
+func CategoryFor(b Backend, tag string) string {
+	return GetInstance().CategoryFor(b, tag)
+}
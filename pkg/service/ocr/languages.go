@@ -0,0 +1,267 @@
+package ocr
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed languages.json
+var defaultLanguageTable []byte
+
+// LanguageTable 是可以从 JSON 或 YAML 加载的语言表：
+//
+//	ase_codes:            # ASE 支持的语种代码 → 名称
+//	  uyg: 维语
+//	aliases:              # 额外的 ISO 639 代码 → 语种代码，供 FindByTag 使用
+//	  "no": nb
+//	languages:
+//	  - {id: 54, name: 维语, code: uyg, nvidia_gpu: wei, cambricon_mlu: cam.uyg,
+//	     huawei_atlas: atlas.uyg, ase_code: uyg, iso639_1: ug, iso639_3: uig}
+type LanguageTable struct {
+	ASECodes  map[string]string `json:"ase_codes" yaml:"ase_codes"`
+	Aliases   map[string]string `json:"aliases" yaml:"aliases"`
+	Languages []Language        `json:"languages" yaml:"languages"`
+}
+
+// DefaultLanguageTable 返回内置语言表的副本。
+func DefaultLanguageTable() *LanguageTable {
+	t, err := ParseLanguageTable(bytes.NewReader(defaultLanguageTable), "json")
+	if err != nil {
+		panic("ocr: invalid embedded language table: " + err.Error())
+	}
+	return t
+}
+
+// ParseLanguageTable 解析 format（"json" 或 "yaml"）格式的语言表，不做校验，见 NewLanguageMap。
+func ParseLanguageTable(r io.Reader, format string) (*LanguageTable, error) {
+	var t LanguageTable
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&t)
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err = dec.Decode(&t); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("language table: %w", err)
+	}
+	return &t, nil
+}
+
+// LoadLanguageTable 读取 path（.json、.yaml 或 .yml）中的语言表。
+func LoadLanguageTable(path string) (*LanguageTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("language table: %w", err)
+	}
+	defer f.Close()
+	t, err := ParseLanguageTable(f, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Merge 将 o 合并到 t：代码相同的语言整条替换，其余追加；ID 为 0 的新语言编号为当前最大 ID 加一。
+// ase_codes 与 aliases 按键合并。
+func (t *LanguageTable) Merge(o *LanguageTable) {
+	if t.ASECodes == nil {
+		t.ASECodes = map[string]string{}
+	}
+	for code, name := range o.ASECodes {
+		t.ASECodes[code] = name
+	}
+	if t.Aliases == nil {
+		t.Aliases = map[string]string{}
+	}
+	for tag, code := range o.Aliases {
+		t.Aliases[tag] = code
+	}
+
+	index := make(map[string]int, len(t.Languages))
+	maxID := 0
+	for i, lang := range t.Languages {
+		index[lang.Code] = i
+		maxID = max(maxID, lang.ID)
+	}
+	for _, lang := range o.Languages {
+		if i, ok := index[lang.Code]; ok {
+			if lang.ID == 0 {
+				lang.ID = t.Languages[i].ID
+			}
+			t.Languages[i] = lang
+			continue
+		}
+		if lang.ID == 0 {
+			lang.ID = maxID + 1
+		}
+		maxID = max(maxID, lang.ID)
+		index[lang.Code] = len(t.Languages)
+		t.Languages = append(t.Languages, lang)
+	}
+}
+
+// NewLanguageMap 校验语言表并建立索引。ase_code 为空时按名称在 ase_codes 中查找；
+// cambricon_mlu 与 huawei_atlas 为空时分别取 "cam." 与 "atlas." 加 nvidia_gpu。
+// 编号、名称、代码、ASE 代码或 ISO 代码重复，ASE 代码不在 ase_codes 中，
+// 或者别名指向不存在的语言时返回错误，错误中列出所有问题。
+func NewLanguageMap(t *LanguageTable) (*LanguageMap, error) {
+	lm := &LanguageMap{
+		byID:           make(map[int]*Language),
+		byName:         make(map[string]*Language),
+		byCode:         make(map[string]*Language),
+		byNvidiaGPU:    make(map[string][]*Language),
+		byCambriconMLU: make(map[string][]*Language),
+		byHuaweiAtlas:  make(map[string][]*Language),
+		byASECode:      make(map[string]*Language),
+		byISO:          make(map[string]*Language),
+	}
+
+	// 按名称推断 ASE 代码时按代码顺序查找，结果与 map 的遍历顺序无关
+	aseCodes := make([]string, 0, len(t.ASECodes))
+	for code := range t.ASECodes {
+		aseCodes = append(aseCodes, code)
+	}
+	sort.Strings(aseCodes)
+
+	var errs []error
+	duplicate := func(lang *Language, field, value string, other *Language) {
+		errs = append(errs, fmt.Errorf("language %d (%s): duplicate %s %q, also used by language %d (%s)",
+			lang.ID, lang.Name, field, value, other.ID, other.Name))
+	}
+
+	languages := make([]Language, len(t.Languages))
+	copy(languages, t.Languages)
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].ID < languages[j].ID })
+
+	for i := range languages {
+		lang := &languages[i]
+		if lang.Code == "" || lang.Name == "" || lang.NvidiaGPU == "" {
+			errs = append(errs, fmt.Errorf("language %d (%s): code, name and nvidia_gpu are required", lang.ID, lang.Name))
+			continue
+		}
+		if lang.CambriconMLU == "" {
+			lang.CambriconMLU = "cam." + lang.NvidiaGPU
+		}
+		if lang.HuaweiAtlas == "" {
+			lang.HuaweiAtlas = "atlas." + lang.NvidiaGPU
+		}
+
+		// 检查ASE代码是否存在，如果不存在，尝试通过名称查找
+		if lang.ASECode == "" {
+			for _, code := range aseCodes {
+				name := t.ASECodes[code]
+				if strings.Contains(lang.Name, name) || strings.Contains(name, strings.TrimSuffix(lang.Name, "语")) {
+					lang.ASECode = code
+					break
+				}
+			}
+		} else if _, ok := t.ASECodes[lang.ASECode]; !ok {
+			errs = append(errs, fmt.Errorf("language %d (%s): unknown ase_code %q", lang.ID, lang.Name, lang.ASECode))
+		}
+
+		if other := lm.byID[lang.ID]; other != nil {
+			duplicate(lang, "id", fmt.Sprint(lang.ID), other)
+		}
+		if other := lm.byName[lang.Name]; other != nil {
+			duplicate(lang, "name", lang.Name, other)
+		}
+		if other := lm.byCode[lang.Code]; other != nil {
+			duplicate(lang, "code", lang.Code, other)
+		}
+		lm.byID[lang.ID] = lang
+		lm.byName[lang.Name] = lang
+		lm.byCode[lang.Code] = lang
+		lm.all = append(lm.all, lang)
+
+		for _, iso := range []string{lang.ISO6391, lang.ISO6393} {
+			if iso == "" {
+				continue
+			}
+			if other := lm.byISO[iso]; other != nil {
+				duplicate(lang, "iso code", iso, other)
+			}
+			lm.byISO[iso] = lang
+		}
+
+		// 添加到GPU映射
+		lm.byNvidiaGPU[lang.NvidiaGPU] = append(lm.byNvidiaGPU[lang.NvidiaGPU], lang)
+		lm.byCambriconMLU[lang.CambriconMLU] = append(lm.byCambriconMLU[lang.CambriconMLU], lang)
+		lm.byHuaweiAtlas[lang.HuaweiAtlas] = append(lm.byHuaweiAtlas[lang.HuaweiAtlas], lang)
+
+		// 如果有ASE代码，添加到ASE映射
+		if lang.ASECode != "" {
+			if other := lm.byASECode[lang.ASECode]; other != nil {
+				duplicate(lang, "ase_code", lang.ASECode, other)
+			}
+			lm.byASECode[lang.ASECode] = lang
+		}
+	}
+
+	tags := make([]string, 0, len(t.Aliases))
+	for tag := range t.Aliases {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		code := t.Aliases[tag]
+		lang := lm.byCode[code]
+		switch {
+		case lang == nil:
+			errs = append(errs, fmt.Errorf("alias %q: unknown language code %q", tag, code))
+		case lm.byISO[tag] != nil && lm.byISO[tag] != lang:
+			errs = append(errs, fmt.Errorf("alias %q: already the iso code of language %d (%s)", tag, lm.byISO[tag].ID, lm.byISO[tag].Name))
+		default:
+			lm.byISO[tag] = lang
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("language table: %w", err)
+	}
+	return lm, nil
+}
+
+// LoadLanguageMap 将 r 中 format 格式的语言表合并到内置语言表（见 LanguageTable.Merge）并建立 LanguageMap。
+func LoadLanguageMap(r io.Reader, format string) (*LanguageMap, error) {
+	o, err := ParseLanguageTable(r, format)
+	if err != nil {
+		return nil, err
+	}
+	t := DefaultLanguageTable()
+	t.Merge(o)
+	return NewLanguageMap(t)
+}
+
+// LoadLanguageMapFile 将 path（.json、.yaml 或 .yml）中的语言表合并到内置语言表并建立 LanguageMap。
+func LoadLanguageMapFile(path string) (*LanguageMap, error) {
+	o, err := LoadLanguageTable(path)
+	if err != nil {
+		return nil, err
+	}
+	t := DefaultLanguageTable()
+	t.Merge(o)
+	lm, err := NewLanguageMap(t)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lm, nil
+}
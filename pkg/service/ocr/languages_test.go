@@ -0,0 +1,144 @@
+package ocr
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fruitbars/goxfyunclient/pkg/emulator"
+)
+
+func TestLanguageQueries(t *testing.T) {
+	if got := Languages(); len(got) != 54 || got[0].Code != "ch_en" || got[53].Code != "uyg" {
+		t.Errorf("Unexpected languages: %d", len(got))
+	}
+	tags := map[string]string{
+		"zh-Hans-CN": "ch_en",
+		"en_US":      "ch_en",
+		"ara":        "ar",
+		"hi":         "hindi",
+		"kk":         "kka",
+		"UG":         "uyg",
+		"nl-BE":      "af",
+		"no":         "nb",
+		"x-unknown":  "",
+		"":           "",
+	}
+	for tag, want := range tags {
+		var got string
+		if lang := GetLanguageByTag(tag); lang != nil {
+			got = lang.Code
+		}
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", tag, want, got)
+		}
+	}
+
+	if got := CategoryFor(BackendNvidia, "ug"); got != "wei" {
+		t.Errorf("Expected wei, got %q", got)
+	}
+	if got := CategoryFor(BackendAtlas, "ru"); got != "atlas.mix1" {
+		t.Errorf("Expected atlas.mix1, got %q", got)
+	}
+	for _, b := range Backends {
+		categories := Categories(b)
+		if len(categories) != 17 {
+			t.Errorf("%s: expected 17 categories, got %v", b, categories)
+		}
+		for _, category := range categories {
+			if len(GetInstance().FindByCategory(b, category)) == 0 {
+				t.Errorf("%s: no languages for %s", b, category)
+			}
+		}
+		parsed, err := ParseBackend(b.String())
+		if err != nil || parsed != b {
+			t.Errorf("ParseBackend(%q) = %v, %v", b, parsed, err)
+		}
+	}
+	if _, err := ParseBackend("tpu"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestLanguageTable(t *testing.T) {
+	const override = `
+ase_codes:
+  my: 缅甸
+aliases:
+  bur: my
+languages:
+  - {name: 缅甸语, code: my, nvidia_gpu: mix10, ase_code: my, iso639_1: my, iso639_3: mya}
+  - {name: 阿拉伯语, code: ar, nvidia_gpu: arabic2, ase_code: ar, iso639_1: ar, iso639_3: ara}
+`
+	lm, err := LoadLanguageMap(strings.NewReader(override), "yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	my := lm.FindByTag("my-MM")
+	if my == nil || my.ID != 55 || my.CambriconMLU != "cam.mix10" || my.HuaweiAtlas != "atlas.mix10" {
+		t.Fatalf("Unexpected added language: %+v", my)
+	}
+	if lm.FindByTag("bur") != my {
+		t.Error("Expected the alias to resolve to the added language")
+	}
+	if ar := lm.FindByCode("ar"); ar.ID != 3 || ar.NvidiaGPU != "arabic2" || len(lm.FindByNvidiaGPU("arabic")) != 0 {
+		t.Errorf("Expected ar to be replaced in place, got %+v", ar)
+	}
+	if len(lm.Languages()) != 55 || len(Languages()) != 54 {
+		t.Errorf("Expected the override to leave the default table alone, got %d and %d", len(lm.Languages()), len(Languages()))
+	}
+
+	// 所有问题一起报告
+	const invalid = `{
+  "aliases": {"xx": "nope"},
+  "languages": [
+    {"id": 1, "name": "重复", "code": "dup", "nvidia_gpu": "mix0", "ase_code": "zz"},
+    {"name": "德语2", "code": "de", "nvidia_gpu": "mix0", "ase_code": "de", "iso639_1": "fr"}
+  ]
+}`
+	_, err = LoadLanguageMap(strings.NewReader(invalid), "json")
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+	for _, want := range []string{`duplicate id "1"`, `unknown ase_code "zz"`, `duplicate iso code "fr"`, `alias "xx": unknown language code "nope"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+	if _, err := LoadLanguageMap(strings.NewReader(`{"languages": [{"code": "x", "typo": 1}]}`), "json"); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+	if _, err := LoadLanguageMapFile(filepath.Join(t.TempDir(), "languages.toml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+
+	// 替换包级语言表后，未指定语言表的客户端立即使用新表
+	srv := httptest.NewServer(emulator.New(echoLanguage))
+	defer srv.Close()
+	ocrClient := NewClient("app-id", "api-key", "api-secret", WithHost(emulator.Endpoint(srv.URL, emulator.ServiceOCR)))
+	recognize := func(category string) string {
+		resp, err := ocrClient.RecognizeAuto(context.Background(), []byte("image"), category)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", category, err)
+		}
+		result, err := resp.Result()
+		if err != nil {
+			t.Fatalf("%s: expected a result, got %v", category, err)
+		}
+		return result.PlainText()
+	}
+	if got := recognize("mix10"); got != "ch_en" {
+		t.Errorf("Expected the default table to fall back to ch_en, got %s", got)
+	}
+	SetLanguageMap(lm)
+	defer SetLanguageMap(nil)
+	if got := recognize("mix10"); got != "my" {
+		t.Errorf("Expected my from the replaced table, got %s", got)
+	}
+	ocrClient.LanguageMap = DefaultLanguageMap()
+	if got := recognize("mix10"); got != "ch_en" {
+		t.Errorf("Expected the client table to take precedence, got %s", got)
+	}
+}
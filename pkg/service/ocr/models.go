@@ -1,5 +1,7 @@
 package ocr
 
+import "github.com/fruitbars/goxfyunclient/pkg/imageprep"
+
 // ----------- Request / Response structs (align with official demo) -----------
 
 type requestBody struct {
@@ -44,4 +46,8 @@ type OcrResponse struct {
 			Text string `json:"text"` // base64 encoded text
 		} `json:"ocr_output_text"`
 	} `json:"payload"`
+
+	// Preprocessing 记录识别前对图片做的预处理（见 WithPreprocess），未预处理时为 nil。
+	// 响应中的坐标属于预处理后的图片，OriginalResult 将其映射回原图。
+	Preprocessing *imageprep.Info `json:"-"`
 }
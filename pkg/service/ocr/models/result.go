@@ -0,0 +1,205 @@
+// Package models 定义通用文字识别（ocr）结果解码后的结构。
+//
+// 响应中 payload.ocr_output_text.text 的 base64 内容解码后是如下 JSON，层级为页面、行、词：
+//
+//	{"category": "ch_en_public_cloud", "version": "...", "pages": [{"angle": 0, "width": 1280, "height": 720,
+//	  "lines": [{"conf": 0.99, "coord": [{"x": 10, "y": 20}, ...], "words": [{"content": "讯飞开放平台", ...}]}]}]}
+//
+// 通过 ocr.OcrResponse.Result 或 Parse 得到 *Result，再用 Lines、PlainText、BoundingBoxes 等方法读取。
+package models
+
+import (
+	"encoding/json"
+	"image"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Result 是一次识别的完整结果。
+type Result struct {
+	Category string `json:"category"`
+	Version  string `json:"version"`
+	Pages    []Page `json:"pages"`
+}
+
+// Page 是一页图片的识别结果。
+type Page struct {
+	Angle     float64 `json:"angle"`  // 图片的旋转角度（度）
+	Width     int     `json:"width"`  // 图片宽度（像素）
+	Height    int     `json:"height"` // 图片高度（像素）
+	Exception int     `json:"exception"`
+	Lines     []Line  `json:"lines"`
+}
+
+// Line 是一行文字。
+type Line struct {
+	Angle     float64 `json:"angle"`
+	Conf      float64 `json:"conf"`  // 置信度，0~1
+	Coord     []Point `json:"coord"` // 外接四边形的顶点，通常从左上角起顺时针
+	Exception int     `json:"exception"`
+	Words     []Word  `json:"words"`
+
+	// WordUnits 是逐字的结果，只在请求了单字信息时返回。
+	WordUnits []Word `json:"word_units,omitempty"`
+}
+
+// Word 是行中的一个词（或 WordUnits 中的一个字）。
+type Word struct {
+	Content string  `json:"content"`
+	Conf    float64 `json:"conf"`
+	Coord   []Point `json:"coord"`
+}
+
+// Point 是图片上的像素坐标。
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Box 是一行文字及其在图片上的外接矩形，见 Result.BoundingBoxes。
+type Box struct {
+	Page int // 页序号，从 0 开始
+	Text string
+	Conf float64
+	Rect image.Rectangle
+}
+
+// Parse 解析解码后的识别结果 JSON。
+func Parse(data []byte) (*Result, error) {
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Text 返回行中所有词拼接后的文字。
+func (l Line) Text() string {
+	if len(l.Words) == 1 {
+		return l.Words[0].Content
+	}
+	var b strings.Builder
+	for _, w := range l.Words {
+		b.WriteString(w.Content)
+	}
+	return b.String()
+}
+
+// Bounds 返回行的外接矩形，没有坐标时返回空矩形。
+func (l Line) Bounds() image.Rectangle {
+	return bounds(l.Coord)
+}
+
+// Bounds 返回词的外接矩形，没有坐标时返回空矩形。
+func (w Word) Bounds() image.Rectangle {
+	return bounds(w.Coord)
+}
+
+// Lines 按页的顺序返回所有行；需要按阅读顺序排列时先调用 SortReadingOrder。
+func (r *Result) Lines() []Line {
+	var lines []Line
+	for _, p := range r.Pages {
+		lines = append(lines, p.Lines...)
+	}
+	return lines
+}
+
+// PlainText 返回纯文本：每行一段，页与页之间空一行。
+func (r *Result) PlainText() string {
+	var b strings.Builder
+	for i, p := range r.Pages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		for j, l := range p.Lines {
+			if j > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(l.Text())
+		}
+	}
+	return b.String()
+}
+
+// BoundingBoxes 返回每一行的文字与外接矩形，顺序同 Lines。
+func (r *Result) BoundingBoxes() []Box {
+	var boxes []Box
+	for i, p := range r.Pages {
+		for _, l := range p.Lines {
+			boxes = append(boxes, Box{Page: i, Text: l.Text(), Conf: l.Conf, Rect: l.Bounds()})
+		}
+	}
+	return boxes
+}
+
+// MapPoints 用 f 原地变换所有行、词与单字的坐标，例如映射回预处理之前的原图，见 ocr.OcrResponse.OriginalResult。
+func (r *Result) MapPoints(f func(Point) Point) {
+	mapAll := func(points []Point) {
+		for i := range points {
+			points[i] = f(points[i])
+		}
+	}
+	for i := range r.Pages {
+		for j := range r.Pages[i].Lines {
+			line := &r.Pages[i].Lines[j]
+			mapAll(line.Coord)
+			for k := range line.Words {
+				mapAll(line.Words[k].Coord)
+			}
+			for k := range line.WordUnits {
+				mapAll(line.WordUnits[k].Coord)
+			}
+		}
+	}
+}
+
+// SortReadingOrder 将每一页的行按阅读顺序（从上到下、同一行内从左到右）原地排序。
+// 垂直方向上的中心相差不到行高一半的行视为同一行，因此略有倾斜的多栏文字也能按行排列。
+func (r *Result) SortReadingOrder() {
+	for i := range r.Pages {
+		sortLines(r.Pages[i].Lines)
+	}
+}
+
+func sortLines(lines []Line) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Bounds().Min.Y < lines[j].Bounds().Min.Y
+	})
+	// 按顶边排序后，把中心高度相近的连续行归为一组，组内按左边排序
+	for start := 0; start < len(lines); {
+		first := lines[start].Bounds()
+		end := start + 1
+		for end < len(lines) {
+			b := lines[end].Bounds()
+			tolerance := float64(min(first.Dy(), b.Dy())) / 2
+			if math.Abs(center(b)-center(first)) > tolerance {
+				break
+			}
+			end++
+		}
+		row := lines[start:end]
+		sort.SliceStable(row, func(i, j int) bool {
+			return row[i].Bounds().Min.X < row[j].Bounds().Min.X
+		})
+		start = end
+	}
+}
+
+func center(r image.Rectangle) float64 {
+	return float64(r.Min.Y+r.Max.Y) / 2
+}
+
+func bounds(points []Point) image.Rectangle {
+	if len(points) == 0 {
+		return image.Rectangle{}
+	}
+	r := image.Rect(points[0].X, points[0].Y, points[0].X, points[0].Y)
+	for _, p := range points[1:] {
+		r.Min.X = min(r.Min.X, p.X)
+		r.Min.Y = min(r.Min.Y, p.Y)
+		r.Max.X = max(r.Max.X, p.X)
+		r.Max.Y = max(r.Max.Y, p.Y)
+	}
+	return r
+}
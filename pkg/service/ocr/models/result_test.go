@@ -0,0 +1,82 @@
+package models
+
+import (
+	"image"
+	"testing"
+)
+
+// 两页：第一页的行按识别顺序打乱，第二行左右两栏略有倾斜。
+const testResult = `{
+	"category": "ch_en_public_cloud",
+	"version": "ch_en_public_cloud_v1.0.1",
+	"pages": [
+		{"angle": 0, "width": 800, "height": 600, "exception": 0, "lines": [
+			{"conf": 0.95, "coord": [{"x":420,"y":102},{"x":700,"y":102},{"x":700,"y":132},{"x":420,"y":132}],
+			 "words": [{"content": "右栏", "conf": 0.95, "coord": [{"x":420,"y":102},{"x":700,"y":132}]}]},
+			{"conf": 0.99, "coord": [{"x":10,"y":20},{"x":400,"y":20},{"x":400,"y":50},{"x":10,"y":50}],
+			 "words": [{"content": "讯飞", "conf": 0.99}, {"content": "开放平台", "conf": 0.98}]},
+			{"conf": 0.97, "coord": [{"x":10,"y":200},{"x":300,"y":200},{"x":300,"y":230},{"x":10,"y":230}],
+			 "words": [{"content": "第三行", "conf": 0.97}]},
+			{"conf": 0.9, "coord": [{"x":10,"y":98},{"x":380,"y":98},{"x":380,"y":128},{"x":10,"y":128}],
+			 "words": [{"content": "左栏", "conf": 0.9}]}
+		]},
+		{"angle": 90, "width": 600, "height": 800, "lines": [
+			{"conf": 1, "words": [{"content": "第二页"}]}
+		]}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(testResult))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if r.Category != "ch_en_public_cloud" || len(r.Pages) != 2 || r.Pages[1].Angle != 90 || r.Pages[0].Width != 800 {
+		t.Fatalf("Unexpected result: %+v", r)
+	}
+	if got := len(r.Lines()); got != 5 {
+		t.Errorf("Expected 5 lines, got %d", got)
+	}
+	if got := r.Pages[0].Lines[1].Text(); got != "讯飞开放平台" {
+		t.Errorf("Expected words to be joined, got %q", got)
+	}
+	if got, want := r.Pages[0].Lines[0].Words[0].Bounds(), image.Rect(420, 102, 700, 132); got != want {
+		t.Errorf("Expected word bounds %v, got %v", want, got)
+	}
+	if _, err := Parse([]byte("not json")); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestResult_SortReadingOrder(t *testing.T) {
+	r, _ := Parse([]byte(testResult))
+	r.SortReadingOrder()
+
+	want := "讯飞开放平台\n左栏\n右栏\n第三行\n\n第二页"
+	if got := r.PlainText(); got != want {
+		t.Errorf("Expected reading order\n%q\ngot\n%q", want, got)
+	}
+
+	boxes := r.BoundingBoxes()
+	if len(boxes) != 5 {
+		t.Fatalf("Expected 5 boxes, got %d", len(boxes))
+	}
+	if b := boxes[0]; b.Page != 0 || b.Text != "讯飞开放平台" || b.Conf != 0.99 || b.Rect != image.Rect(10, 20, 400, 50) {
+		t.Errorf("Unexpected first box: %+v", b)
+	}
+	// 没有坐标的行得到空矩形
+	if b := boxes[4]; b.Page != 1 || !b.Rect.Empty() {
+		t.Errorf("Unexpected box without coordinates: %+v", b)
+	}
+}
+
+func TestResult_MapPoints(t *testing.T) {
+	r, _ := Parse([]byte(testResult))
+	r.MapPoints(func(p Point) Point { return Point{X: p.X * 2, Y: p.Y + 1} })
+	if got, want := r.Pages[0].Lines[0].Bounds(), image.Rect(840, 103, 1400, 133); got != want {
+		t.Errorf("Expected line bounds %v, got %v", want, got)
+	}
+	if got, want := r.Pages[0].Lines[0].Words[0].Bounds(), image.Rect(840, 103, 1400, 133); got != want {
+		t.Errorf("Expected word bounds %v, got %v", want, got)
+	}
+}
@@ -7,7 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
 	"github.com/fruitbars/goxfyunclient/pkg/service/translate/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"io"
 	"log/slog"
 	"net/http"
@@ -15,7 +22,8 @@ import (
 )
 
 const (
-	defaultHost = "https://itrans.xf-yun.com/v1/its"
+	// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+	serviceName = "translate"
 )
 
 // Client for the Xunfei translation service.
@@ -26,6 +34,34 @@ type Client struct {
 	APISecret  string
 	Logger     *slog.Logger
 	HTTPClient *http.Client
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证，AppID/APIKey/APISecret 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 HMAC 签名，可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Retry 是失败时的重试策略，为 nil 时不重试。每次重试都会重新签名。
+	Retry *retry.Policy
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每次调用记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是允许的最大响应体字节数，超出时返回 *xfyunerr.ResponseTooLargeError；
+	// 小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// Fallbacks 是 HostURL 连接失败时依次尝试的备用地址，默认取自 endpoint.Default。
+	Fallbacks []string
+
+	// middlewares 在 NewClient 中包装 HTTPClient 的 Transport。
+	middlewares []middleware.Middleware
 }
 
 // Option is a function that configures a Client.
@@ -36,6 +72,17 @@ func WithHost(host string) Option {
 	return func(c *Client) {
 		if host != "" {
 			c.HostURL = host
+			c.Fallbacks = nil
+		}
+	}
+}
+
+// WithEndpoints sets the primary endpoint and the fallbacks tried in order when it is unreachable,
+// e.g. the result of endpoint.Default.Resolve.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		if len(urls) > 0 {
+			c.HostURL, c.Fallbacks = urls[0], urls[1:]
 		}
 	}
 }
@@ -49,9 +96,80 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithCredentials sets the credential provider resolved on every request.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
+		}
+	}
+}
+
+// WithAuthMode selects whether the signature is sent in the URL query (auth.ModeQuery)
+// or in the Authorization/Date/Host headers (auth.ModeHeader).
+func WithAuthMode(mode auth.Mode) Option {
+	return func(c *Client) {
+		c.AuthMode = mode
+	}
+}
+
+// WithRetry enables retries with the given policy, e.g. retry.DefaultPolicy().
+func WithRetry(policy *retry.Policy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies; larger responses fail with
+// *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
+	}
+}
+
+// WithMiddleware wraps the transport of the HTTP client with mws, e.g. a proxy, mTLS or audit layer.
+// Middlewares apply to every request including retries, in order: the first one is outermost.
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client, e.g. one with a custom Transport or Timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.HTTPClient = client
+		}
+	}
+}
+
 func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 	c := &Client{
-		HostURL:   defaultHost,
 		AppID:     appID,
 		APIKey:    apiKey,
 		APISecret: apiSecret,
@@ -59,56 +177,106 @@ func NewClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 			Timeout: 30 * time.Second,
 		},
 		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer: auth.DefaultSigner,
 	}
+	c.HostURL, c.Fallbacks = endpoint.Default.Lookup(serviceName)
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	c.HTTPClient = middleware.WrapClient(c.HTTPClient, c.middlewares...)
 	return c
 }
 
 // Translate performs the translation using RESTful API.
+// Credentials are resolved on every attempt, so rotated keys take effect on retries.
 func (c *Client) Translate(ctx context.Context, text, from, to string) (string, error) {
-	authURL, err := auth.AssembleAuthURL(c.HostURL, "POST", c.APIKey, c.APISecret)
-	if err != nil {
-		return "", fmt.Errorf("构建认证URL失败: %w", err)
-	}
+	var result string
+	err := c.Retry.Do(ctx, func(ctx context.Context) error {
+		creds, err := c.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		requestBody, err := c.buildRequestBody(creds.AppID, text, from, to)
+		if err != nil {
+			return fmt.Errorf("构建请求体失败: %w", err)
+		}
+		ctx, call := c.Telemetry.Start(ctx, serviceName, "translate", c.HostURL)
+		call.AddBytesSent(len(requestBody))
+		result, err = endpoint.Failover(c.Logger, c.endpoints(), func(host string) (string, error) {
+			return c.send(ctx, host, creds, requestBody, from, to)
+		})
+		call.End(err)
+		return err
+	})
+	return result, err
+}
 
-	requestBody, err := c.buildRequestBody(text, from, to)
+// credentials 解析本次请求使用的凭证。
+func (c *Client) credentials(ctx context.Context) (auth.Credentials, error) {
+	return auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		APIKey:    c.APIKey,
+		APISecret: c.APISecret,
+	})
+}
+
+// endpoints 返回主地址与备用地址。
+func (c *Client) endpoints() []string {
+	return append([]string{c.HostURL}, c.Fallbacks...)
+}
+
+// send 构造、签名并向 host 发送一次翻译请求。每次重试都会调用 send 重新签名，因为签名中的 date 会过期。
+func (c *Client) send(ctx context.Context, host string, creds auth.Credentials, requestBody []byte, from, to string) (string, error) {
+	release, err := c.Limiter.Acquire(ctx)
 	if err != nil {
-		return "", fmt.Errorf("构建请求体失败: %w", err)
+		return "", err
 	}
+	defer release()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, host, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return "", fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	c.Logger.Debug("sending translate request", "url", authURL, "from", from, "to", to)
+	if err := c.Signer.Sign(req, c.AuthMode, creds.APIKey, creds.APISecret, auth.SchemeTypeAPIKey); err != nil {
+		return "", fmt.Errorf("构建认证URL失败: %w", err)
+	}
+	redactedURL := auth.RedactURL(req.URL.String())
+
+	c.Logger.Debug("sending translate request", "url", redactedURL, "auth_mode", c.AuthMode, "from", from, "to", to)
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		c.Logger.Error("sending translate request failed", "url", authURL, "error", err)
+		err = &xfyunerr.NetworkError{Service: serviceName, Err: auth.RedactError(err)}
+		c.Logger.Error("sending translate request failed", "url", redactedURL, "error", err)
 		return "", fmt.Errorf("发送HTTP请求失败: %w", err)
 	}
 	defer resp.Body.Close()
+	c.Signer.ObserveResponse(resp)
 
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 	if err != nil {
 		c.Logger.Error("reading translate response body failed", "error", err)
 		return "", fmt.Errorf("读取响应体失败: %w", err)
 	}
 
-	return c.parseResponse(responseBody)
+	// 网关鉴权失败时返回非 200 状态码和 {"message": ...}，不含 header.code
+	if resp.StatusCode != http.StatusOK {
+		c.Logger.Error("translate request failed", "status_code", resp.StatusCode, "response", string(responseBody))
+		return "", xfyunerr.FromStatus(serviceName, resp.StatusCode, responseBody)
+	}
+
+	return c.parseResponse(ctx, responseBody)
 }
 
-func (c *Client) buildRequestBody(text, from, to string) ([]byte, error) {
+func (c *Client) buildRequestBody(appID, text, from, to string) ([]byte, error) {
 	encodedText := base64.StdEncoding.EncodeToString([]byte(text))
 
 	reqBody := models.RequestBody{
 		Header: models.RequestHeader{
-			AppID:  c.AppID,
+			AppID:  appID,
 			Status: 3,
 		},
 		Parameter: models.RequestParameter{
@@ -129,7 +297,7 @@ func (c *Client) buildRequestBody(text, from, to string) ([]byte, error) {
 	return json.Marshal(reqBody)
 }
 
-func (c *Client) parseResponse(body []byte) (string, error) {
+func (c *Client) parseResponse(ctx context.Context, body []byte) (string, error) {
 	var respData models.ResponseBody
 	if err := json.Unmarshal(body, &respData); err != nil {
 		c.Logger.Error("unmarshalling translate response failed", "body", string(body), "error", err)
@@ -142,9 +310,9 @@ func (c *Client) parseResponse(body []byte) (string, error) {
 			"message", respData.Header.Message,
 			"sid", respData.Header.Sid,
 		)
-		return "", fmt.Errorf("API返回错误: code=%d, message=%s, sid=%s",
-			respData.Header.Code, respData.Header.Message, respData.Header.Sid)
+		return "", &xfyunerr.APIError{Service: serviceName, Code: respData.Header.Code, Message: respData.Header.Message, SID: respData.Header.Sid, HTTPStatus: http.StatusOK}
 	}
+	telemetry.SetSID(ctx, respData.Header.Sid)
 
 	decodedText, err := base64.StdEncoding.DecodeString(respData.Payload.Result.Text)
 	if err != nil {
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
 	"github.com/fruitbars/goxfyunclient/pkg/service/tts/models"
+	"github.com/fruitbars/goxfyunclient/pkg/stream"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
 	"github.com/fruitbars/goxfyunclient/pkg/utils"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"io"
 	"log/slog"
 	"net/http"
@@ -21,6 +27,9 @@ const (
 	APIHost     = "tts-api.xfyun.cn"
 	APIEndpoint = "/v2/tts"
 	Scheme      = "wss"
+
+	// serviceName 用于标识 xfyunerr 错误来自哪个服务。
+	serviceName = "tts"
 )
 
 // Client TTSClient holds the configuration for the Text-to-Speech client.
@@ -29,15 +38,55 @@ type Client struct {
 	APIKey     string
 	APISecret  string
 	HTTPClient *http.Client
-	conn       *websocket.Conn
+	conn       Conn
+	connAppID  string          // 建立当前连接时解析出的 AppID
+	release    func()          // 归还当前连接占用的并发名额
+	call       *telemetry.Call // 当前会话的 span，Close 时结束
+	callErr    error           // 当前会话中服务端返回的错误，Close 时记录到 span
 	Logger     *slog.Logger
-	testURL    string // for testing
+	host       string // WebSocket 地址，默认取自 endpoint.Default，可由 WithHost 或 WithTestURL 指定
 
 	// 默认参数，可以在调用方法时被覆盖
 	DefaultVoiceName   string
 	DefaultAudioFormat string
+
+	// CredentialProvider 非空时，每次建立连接都从它解析凭证，AppID/APIKey/APISecret 字段将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	// Signer 负责 HMAC 签名，可注入时钟和时钟偏差，默认为 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Limiter 限制 QPS 与并发请求数，可与其他客户端共享（见 ratelimit.ForAppID），为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Telemetry 为每个 WebSocket 会话记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// MaxResponseSize 是握手失败时响应体与每个 WebSocket 帧允许的最大字节数，超出时返回
+	// *xfyunerr.ResponseTooLargeError；小于等于 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// Dial 建立 WebSocket 连接，为 nil 时使用 websocket.DefaultDialer。
+	Dial DialFunc
+
+	// Fallbacks 是主地址连接失败时依次尝试的备用地址，默认取自 endpoint.Default。
+	Fallbacks []string
+}
+
+// Conn 是 TTS 会话使用的 WebSocket 连接，*websocket.Conn 实现了该接口。
+type Conn interface {
+	WriteMessage(messageType int, data []byte) error
+	ReadMessage() (messageType int, p []byte, err error)
+	Close() error
 }
 
+// DialFunc 用签名后的 url 与握手请求头建立 WebSocket 连接。
+// 握手被拒绝时应返回非 nil 的 *http.Response，以便客户端解析网关的错误信息。
+type DialFunc func(ctx context.Context, url string, header http.Header) (Conn, *http.Response, error)
+
 // Option is a function that configures a TTSClient.
 type Option func(*Client)
 
@@ -54,7 +103,85 @@ func WithLogger(logger *slog.Logger) Option {
 // This is intended for testing purposes only.
 func WithTestURL(url string) Option {
 	return func(c *Client) {
-		c.testURL = url
+		c.host = url
+		c.Fallbacks = nil
+	}
+}
+
+// WithHost sets the WebSocket URL, e.g. "ws://127.0.0.1:8080/v2/tts" for a local emulator.
+func WithHost(url string) Option {
+	return func(c *Client) {
+		if url != "" {
+			c.host = url
+			c.Fallbacks = nil
+		}
+	}
+}
+
+// WithEndpoints sets the primary WebSocket URL and the fallbacks tried in order when it is unreachable,
+// e.g. the result of endpoint.Default.Resolve.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		if len(urls) > 0 {
+			c.host, c.Fallbacks = urls[0], urls[1:]
+		}
+	}
+}
+
+// WithDialer replaces the WebSocket dialer, e.g. with a record/replay cassette (see pkg/cassette).
+func WithDialer(dial DialFunc) Option {
+	return func(c *Client) {
+		if dial != nil {
+			c.Dial = dial
+		}
+	}
+}
+
+// WithCredentials sets the credential provider resolved on every connection.
+func WithCredentials(provider auth.CredentialProvider) Option {
+	return func(c *Client) {
+		if provider != nil {
+			c.CredentialProvider = provider
+		}
+	}
+}
+
+// WithSigner sets the signer used to sign requests, e.g. one with an injected clock or skew.
+func WithSigner(signer *auth.Signer) Option {
+	return func(c *Client) {
+		if signer != nil {
+			c.Signer = signer
+		}
+	}
+}
+
+// WithAuthMode selects whether the signature is sent in the URL query (auth.ModeQuery)
+// or in the Authorization/Date/Host headers (auth.ModeHeader).
+func WithAuthMode(mode auth.Mode) Option {
+	return func(c *Client) {
+		c.AuthMode = mode
+	}
+}
+
+// WithLimiter attaches a QPS/concurrency limiter; share one limiter across clients using the same AppID.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.Limiter = limiter
+	}
+}
+
+// WithTelemetry enables OpenTelemetry spans and metrics, see telemetry.New.
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(c *Client) {
+		c.Telemetry = t
+	}
+}
+
+// WithMaxResponseSize limits the size of the handshake error body and of each WebSocket frame;
+// larger responses fail with *xfyunerr.ResponseTooLargeError. Defaults to stream.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseSize = n
 	}
 }
 
@@ -67,10 +194,12 @@ func NewTTSClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 			Timeout: 10 * time.Second,
 		},
 		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Signer: auth.DefaultSigner,
 		// 设置默认值
 		DefaultVoiceName:   "x4_yezi",
 		DefaultAudioFormat: "raw",
 	}
+	c.host, c.Fallbacks = endpoint.Default.Lookup(serviceName)
 
 	for _, opt := range opts {
 		opt(c)
@@ -81,49 +210,150 @@ func NewTTSClient(appID, apiKey, apiSecret string, opts ...Option) *Client {
 
 // Connect establishes a WebSocket connection to the TTS service.
 func (c *Client) Connect() error {
-	if c.AppID == "" || c.APIKey == "" || c.APISecret == "" || strings.TrimSpace(c.AppID) == "" {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext establishes a WebSocket connection to the TTS service,
+// resolving credentials with the given context. An existing session is closed first.
+func (c *Client) ConnectContext(ctx context.Context) error {
+	// 未 Close 就再次连接时先结束旧会话，避免泄漏连接、并发名额与 span
+	if c.conn != nil {
+		c.Close()
+	}
+	ctx, call := c.Telemetry.Start(ctx, serviceName, "session", c.endpoint())
+	if err := c.connect(ctx); err != nil {
+		call.End(err)
+		return err
+	}
+	c.call = call
+	c.callErr = nil
+	return nil
+}
+
+// connect 解析凭证、签名并建立 WebSocket 连接。
+func (c *Client) connect(ctx context.Context) error {
+	creds, err := auth.ResolveCredentials(ctx, c.CredentialProvider, auth.Credentials{
+		AppID:     c.AppID,
+		APIKey:    c.APIKey,
+		APISecret: c.APISecret,
+	})
+	if err != nil {
+		return err
+	}
+	if creds.AppID == "" || creds.APIKey == "" || creds.APISecret == "" || strings.TrimSpace(creds.AppID) == "" {
 		return fmt.Errorf("AppID, APIKey, or APISecret is not configured")
 	}
 
-	//authURL, err := c.buildAuthURL()
-	authURL, err := auth.AssembleAuthURLWithHostPath(Scheme, APIHost, APIEndpoint, "GET", c.APIKey, c.APISecret)
-	c.Logger.Debug("connecting to tts websocket", "url", authURL)
+	// 一个 WebSocket 会话占用一个并发名额，直到 Close
+	release, err := c.Limiter.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := endpoint.Failover(c.Logger, c.endpoints(), func(url string) (Conn, error) {
+		return c.dialEndpoint(ctx, url, creds)
+	})
+	if err != nil {
+		release()
+		return err
+	}
+	c.Logger.Info("tts websocket connection established")
+
+	c.conn = conn
+	c.connAppID = creds.AppID
+	c.release = release
+
+	return nil
+}
+
+// dialEndpoint 为 url 签名并建立 WebSocket 连接。
+func (c *Client) dialEndpoint(ctx context.Context, url string, creds auth.Credentials) (Conn, error) {
+	// 握手请求只用于签名：ModeQuery 下签名写入 URL，ModeHeader 下写入握手请求头
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err == nil {
+		err = c.Signer.Sign(req, c.AuthMode, creds.APIKey, creds.APISecret, auth.SchemeTypeAPIKey)
+	}
 	if err != nil {
 		c.Logger.Error("could not build auth url", "error", err)
-		return fmt.Errorf("could not build auth url: %w", err)
+		return nil, fmt.Errorf("could not build auth url: %w", err)
+	}
+	authURL := req.URL.String()
+	redactedURL := auth.RedactURL(authURL)
+	c.Logger.Debug("connecting to tts websocket", "url", redactedURL, "auth_mode", c.AuthMode)
+
+	var header http.Header
+	if c.AuthMode == auth.ModeHeader {
+		header = req.Header
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, authURL, nil)
+	conn, resp, err := c.dial(dialCtx, authURL, header)
+	c.Signer.ObserveResponse(resp)
 	if err != nil {
-		c.Logger.Error("websocket dial failed", "url", authURL, "error", err)
+		err = auth.RedactError(err)
+		c.Logger.Error("websocket dial failed", "url", redactedURL, "error", err)
 
 		if resp != nil {
-			bodyBytes, readBodyErr := io.ReadAll(resp.Body)
+			bodyBytes, readBodyErr := stream.ReadAll(serviceName, resp.Body, c.MaxResponseSize)
 			if readBodyErr != nil {
 				c.Logger.Error("failed to read response body", "error", readBodyErr)
-				return err // 返回原始的拨号错误
+				return nil, err // 返回原始的拨号错误
 			}
 			respBodyShort := utils.SafeSnippet(bodyBytes, 512)
 			c.Logger.Debug("WebSocket handshake response", "status", resp.Status, "headers", resp.Header, "body", respBodyShort)
-			return fmt.Errorf("websocket dial failed: %w, status=%s, body=%s", err, resp.Status, respBodyShort)
+			// 握手被网关拒绝（例如签名错误），响应体为 {"message": ...}
+			return nil, fmt.Errorf("websocket dial failed: %w", xfyunerr.FromStatus(serviceName, resp.StatusCode, bodyBytes))
 		}
 
-		return fmt.Errorf("websocket dial failed: %w", err)
+		return nil, fmt.Errorf("websocket dial failed: %w", &xfyunerr.NetworkError{Service: serviceName, Err: err})
 	}
-	c.Logger.Info("tts websocket connection established")
+	// 单帧超过 MaxResponseSize 时 ReadMessage 返回 websocket.ErrReadLimit，见 ReceiveAudio
+	if l, ok := conn.(interface{ SetReadLimit(int64) }); ok {
+		l.SetReadLimit(c.maxResponseSize())
+	}
+	return conn, nil
+}
 
-	c.conn = conn
+func (c *Client) maxResponseSize() int64 {
+	if c.MaxResponseSize <= 0 {
+		return stream.DefaultMaxResponseSize
+	}
+	return c.MaxResponseSize
+}
 
-	return nil
+// endpoints 返回主地址与备用地址。
+func (c *Client) endpoints() []string {
+	return append([]string{c.endpoint()}, c.Fallbacks...)
+}
+
+// endpoint 返回主 WebSocket 地址，endpoint.Default 未登记 tts 时使用 APIHost。
+func (c *Client) endpoint() string {
+	if c.host != "" {
+		return c.host
+	}
+	return fmt.Sprintf("%s://%s%s", Scheme, APIHost, APIEndpoint)
+}
+
+// dial 使用 c.Dial 建立连接，未设置时使用 websocket.DefaultDialer。
+func (c *Client) dial(ctx context.Context, url string, header http.Header) (Conn, *http.Response, error) {
+	if c.Dial != nil {
+		return c.Dial(ctx, url, header)
+	}
+	return websocket.DefaultDialer.DialContext(ctx, url, header)
 }
 
 // Close closes the WebSocket connection.
 func (c *Client) Close() {
 	if c.conn != nil {
 		c.conn.Close()
+		c.conn = nil
+	}
+	if c.release != nil {
+		c.release()
+		c.release = nil
 	}
+	c.call.End(c.callErr)
+	c.call = nil
 }
 
 // SendText sends a chunk of text to be synthesized.
@@ -153,14 +383,19 @@ func (c *Client) SendText(text, voiceName, audioFormat string, isLast bool) erro
 	}
 
 	payload := models.RequestPayload{
-		Common:   models.RequestCommon{AppID: c.AppID},
+		Common:   models.RequestCommon{AppID: c.connAppID},
 		Business: business,
 		Data: models.RequestData{
 			Status: status,
 			Text:   base64.StdEncoding.EncodeToString([]byte(text)),
 		},
 	}
-	return c.conn.WriteJSON(payload)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	c.call.AddBytesSent(len(data))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 // ReceiveAudio receives audio data from the WebSocket connection.
@@ -175,6 +410,10 @@ func (c *Client) ReceiveAudio() ([]byte, bool, error) {
 		if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
 			return nil, true, nil // Clean close
 		}
+		if errors.Is(err, websocket.ErrReadLimit) {
+			c.callErr = &xfyunerr.ResponseTooLargeError{Service: serviceName, Limit: c.maxResponseSize()}
+			return nil, false, c.callErr
+		}
 		return nil, false, fmt.Errorf("error reading message: %w", err)
 	}
 
@@ -190,8 +429,10 @@ func (c *Client) ReceiveAudio() ([]byte, bool, error) {
 			"message", resp.Message,
 			"sid", resp.SID,
 		)
-		return nil, false, fmt.Errorf("server error: code=%d, message=%s, sid=%s", resp.Code, resp.Message, resp.SID)
+		c.callErr = &xfyunerr.APIError{Service: serviceName, Code: resp.Code, Message: resp.Message, SID: resp.SID}
+		return nil, false, c.callErr
 	}
+	c.call.SetSID(resp.SID)
 
 	audioData, err := base64.StdEncoding.DecodeString(resp.Data.Audio)
 	if err != nil {
@@ -225,12 +466,12 @@ func (c *Client) TextToSpeech(ctx context.Context, text, voiceName, audioFormat
 }
 
 func (c *Client) StreamTextReader(ctx context.Context, text, voiceName, audioFormat string) (io.ReadCloser, error) {
-	pr, pw := io.Pipe()
-
-	if err := c.Connect(); err != nil {
+	if err := c.ConnectContext(ctx); err != nil {
 		return nil, err
 	}
 
+	pr, pw := io.Pipe()
+
 	go func() {
 		defer func() {
 			c.Close()
@@ -3,11 +3,15 @@ package tts
 import (
 	"context"
 	"encoding/base64"
-	"goxfyunclient/internal/service/tts/models"
+	"errors"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/service/tts/models"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -88,8 +92,64 @@ func TestTTSClient_TextToSpeech_ApiError(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected an error, got nil")
 	}
-	expectedError := "API返回错误: code=10106, message=invalid parameter"
+	expectedError := "code=10106, message=invalid parameter"
 	if !strings.Contains(err.Error(), expectedError) {
 		t.Errorf("Expected error containing '%s', got '%s'", expectedError, err.Error())
 	}
 }
+
+func TestTTSClient_TextToSpeech_FrameTooLarge(t *testing.T) {
+	server := mockWebSocketServer(t, func(conn *websocket.Conn) {
+		var req models.RequestPayload
+		conn.ReadJSON(&req)
+		audioBase64 := base64.StdEncoding.EncodeToString(make([]byte, 4096))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code": 0, "data": {"status": 2, "audio": "`+audioBase64+`"}}`))
+	})
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	client := NewTTSClient("app-id", "api-key", "api-secret", WithTestURL(wsURL), WithMaxResponseSize(1024))
+
+	_, err := client.TextToSpeech(context.Background(), "hello", "xiaoyan", "raw")
+	var tooLarge *xfyunerr.ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Fatalf("Expected ResponseTooLargeError, got %v", err)
+	}
+}
+
+// closeCounter 是只记录 Close 次数的连接。
+type closeCounter struct {
+	Conn
+	closed *int
+}
+
+func (c closeCounter) Close() error {
+	*c.closed++
+	return nil
+}
+
+// 未 Close 就再次连接时先关闭旧连接并归还并发名额。
+func TestTTSClient_ConnectTwice(t *testing.T) {
+	closed := 0
+	dial := func(ctx context.Context, url string, header http.Header) (Conn, *http.Response, error) {
+		return closeCounter{closed: &closed}, nil, nil
+	}
+	limiter := ratelimit.New(ratelimit.Config{QPS: 100, Burst: 10, MaxInFlight: 1})
+	client := NewTTSClient("app-id", "api-key", "api-secret", WithDialer(dial), WithLimiter(limiter))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if err := client.ConnectContext(ctx); err != nil {
+			t.Fatalf("Connect %d: expected no error, got %v", i+1, err)
+		}
+	}
+	if closed != 1 {
+		t.Errorf("Expected the first connection to be closed, closed %d", closed)
+	}
+	client.Close()
+	client.Close()
+	if closed != 2 {
+		t.Errorf("Expected each connection to be closed once, closed %d", closed)
+	}
+}
@@ -0,0 +1,387 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+// DefaultMaxResponseSize 是客户端未设置 WithMaxResponseSize 时允许的最大响应体字节数。
+const DefaultMaxResponseSize = 64 << 20
+
+// LimitReader 返回最多读取 limit 字节的 r，超出时返回 *xfyunerr.ResponseTooLargeError。
+// limit 小于等于 0 时使用 DefaultMaxResponseSize。
+func LimitReader(service string, r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	return &limitedReader{r: r, service: service, limit: limit, n: limit}
+}
+
+// ReadAll 读取 r 的全部内容，超过 limit 字节时返回 *xfyunerr.ResponseTooLargeError，见 LimitReader。
+func ReadAll(service string, r io.Reader, limit int64) ([]byte, error) {
+	return io.ReadAll(LimitReader(service, r, limit))
+}
+
+type limitedReader struct {
+	r       io.Reader
+	service string
+	limit   int64
+	n       int64 // 剩余可读字节数
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// 恰好读满 limit 时再读一个字节，区分“刚好等于上限”与“超出上限”
+		var b [1]byte
+		if n, err := l.r.Read(b[:]); n == 0 {
+			return 0, err
+		}
+		return 0, &xfyunerr.ResponseTooLargeError{Service: l.service, Limit: l.limit}
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// DecodeJSON 流式解析 r 中的 JSON 响应：payload.<name>.text 字段的 base64 内容边读边解码，
+// 写入 text(name) 返回的 Writer（返回 nil 时丢弃），不在内存中保留 base64 字符串；
+// 响应的其余部分按 json.Unmarshal 的规则解码到 v，其中的 text 字段为空字符串。
+//
+// llmocr 等服务的结果以 base64 放在 payload.result.text 中，大文档扫描的结果可达数十 MB，
+// 先 io.ReadAll 再 json.Unmarshal 与 base64 解码会在内存中同时保留三份。
+func DecodeJSON(r io.Reader, v any, text func(name string) io.Writer) error {
+	d := &decoder{r: bufio.NewReader(r), text: text}
+	if err := d.value(nil); err != nil {
+		return err
+	}
+	if err := d.skipSpace(); err != io.EOF {
+		if err == nil {
+			return d.syntaxError("unexpected data after top-level value")
+		}
+		return err
+	}
+	return json.Unmarshal(d.out.Bytes(), v)
+}
+
+// decoder 逐字节扫描 JSON，把除 payload.*.text 以外的内容原样复制到 out。
+type decoder struct {
+	r    *bufio.Reader
+	out  bytes.Buffer
+	off  int64
+	text func(name string) io.Writer
+}
+
+func (d *decoder) readByte() (byte, error) {
+	c, err := d.r.ReadByte()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		d.off++
+	}
+	return c, err
+}
+
+// skipSpace 跳过空白，返回下一个字节前的状态；输入结束时返回 io.EOF。
+func (d *decoder) skipSpace() error {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			d.off++
+		default:
+			return d.r.UnreadByte()
+		}
+	}
+}
+
+func (d *decoder) peek() (byte, error) {
+	if err := d.skipSpace(); err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return d.r.ReadByte()
+}
+
+func (d *decoder) syntaxError(msg string) error {
+	return fmt.Errorf("stream: invalid JSON at offset %d: %s", d.off, msg)
+}
+
+// value 解析一个 JSON 值，path 是从顶层到该值的对象键。
+func (d *decoder) value(path []string) error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	d.off++
+	switch {
+	case c == '{':
+		return d.object(path)
+	case c == '[':
+		return d.array(path)
+	case c == '"':
+		if len(path) == 3 && path[0] == "payload" && path[2] == "text" && d.text != nil {
+			return d.streamText(path[1])
+		}
+		d.out.WriteByte('"')
+		return d.copyString()
+	case c == '-' || c >= '0' && c <= '9' || c == 't' || c == 'f' || c == 'n':
+		d.out.WriteByte(c)
+		return d.copyLiteral()
+	}
+	return d.syntaxError(fmt.Sprintf("unexpected character %q", c))
+}
+
+func (d *decoder) object(path []string) error {
+	d.out.WriteByte('{')
+	for first := true; ; first = false {
+		c, err := d.peek()
+		if err != nil {
+			return err
+		}
+		d.off++
+		if c == '}' {
+			d.out.WriteByte('}')
+			return nil
+		}
+		if !first {
+			if c != ',' {
+				return d.syntaxError("expected ',' or '}' in object")
+			}
+			d.out.WriteByte(',')
+			if c, err = d.peek(); err != nil {
+				return err
+			}
+			d.off++
+		}
+		if c != '"' {
+			return d.syntaxError("expected object key")
+		}
+		start := d.out.Len()
+		d.out.WriteByte('"')
+		if err := d.copyString(); err != nil {
+			return err
+		}
+		var key string
+		if err := json.Unmarshal(d.out.Bytes()[start:], &key); err != nil {
+			return d.syntaxError(err.Error())
+		}
+		if c, err = d.peek(); err != nil {
+			return err
+		}
+		d.off++
+		if c != ':' {
+			return d.syntaxError("expected ':' after object key")
+		}
+		d.out.WriteByte(':')
+		if err := d.value(append(path[:len(path):len(path)], key)); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *decoder) array(path []string) error {
+	d.out.WriteByte('[')
+	// 数组元素不属于 payload.*.text，用不可能出现的路径段标记
+	elem := append(path[:len(path):len(path)], "[]")
+	for first := true; ; first = false {
+		c, err := d.peek()
+		if err != nil {
+			return err
+		}
+		if c == ']' {
+			d.off++
+			d.out.WriteByte(']')
+			return nil
+		}
+		if !first {
+			d.off++
+			if c != ',' {
+				return d.syntaxError("expected ',' or ']' in array")
+			}
+			d.out.WriteByte(',')
+		} else if err := d.r.UnreadByte(); err != nil {
+			return err
+		}
+		if err := d.value(elem); err != nil {
+			return err
+		}
+	}
+}
+
+// copyString 复制字符串的剩余部分（开头的引号已读取），包括结尾的引号。
+func (d *decoder) copyString() error {
+	for {
+		c, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		d.out.WriteByte(c)
+		switch {
+		case c == '"':
+			return nil
+		case c == '\\':
+			if c, err = d.readByte(); err != nil {
+				return err
+			}
+			d.out.WriteByte(c)
+		case c < 0x20:
+			return d.syntaxError("control character in string")
+		}
+	}
+}
+
+// copyLiteral 复制数字、true、false 或 null 的剩余部分（第一个字节已写入 out）。
+func (d *decoder) copyLiteral() error {
+	for {
+		c, err := d.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch c {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+			return d.r.UnreadByte()
+		}
+		d.off++
+		d.out.WriteByte(c)
+	}
+}
+
+// streamText 将字符串的内容按 base64 解码后写入 d.text(name)，out 中写入空字符串。
+func (d *decoder) streamText(name string) error {
+	d.out.WriteString(`""`)
+	w := d.text(name)
+	s := &stringReader{d: d}
+	if w != nil {
+		if _, err := io.Copy(w, base64.NewDecoder(base64.StdEncoding, s)); err != nil {
+			if s.err != nil {
+				return s.err
+			}
+			return fmt.Errorf("stream: decode payload.%s.text: %w", name, err)
+		}
+	}
+	// 解码器遇到填充后不再读取，跳过字符串的剩余部分
+	if _, err := io.Copy(io.Discard, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stringReader 读取 JSON 字符串的内容并处理转义，读到结尾的引号时返回 io.EOF。
+type stringReader struct {
+	d    *decoder
+	done bool
+	err  error // 读取或语法错误，与 base64 解码错误区分
+	buf  [utf8.UTFMax]byte
+	pend []byte // 已解码尚未返回的字节（\u 转义）
+}
+
+func (s *stringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.pend) > 0 {
+			k := copy(p[n:], s.pend)
+			s.pend = s.pend[k:]
+			n += k
+			continue
+		}
+		if s.done {
+			return n, io.EOF
+		}
+		c, err := s.d.readByte()
+		if err != nil {
+			s.err = err
+			return n, err
+		}
+		switch {
+		case c == '"':
+			s.done = true
+		case c == '\\':
+			r, err := s.escape()
+			if err != nil {
+				s.err = err
+				return n, err
+			}
+			s.pend = s.buf[:utf8.EncodeRune(s.buf[:], r)]
+		case c < 0x20:
+			s.err = s.d.syntaxError("control character in string")
+			return n, s.err
+		default:
+			p[n] = c
+			n++
+			n += s.copyPlain(p[n:])
+		}
+		// 尽量不阻塞在已有数据上：缓冲区中没有更多数据时先返回
+		if n > 0 && s.d.r.Buffered() == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+// copyPlain 从缓冲区中直接复制不含引号、反斜杠与控制字符的一段字节到 p，返回复制的字节数。
+func (s *stringReader) copyPlain(p []byte) int {
+	buf, _ := s.d.r.Peek(min(s.d.r.Buffered(), len(p)))
+	k := 0
+	for k < len(buf) && buf[k] != '"' && buf[k] != '\\' && buf[k] >= 0x20 {
+		k++
+	}
+	copy(p, buf[:k])
+	s.d.r.Discard(k)
+	s.d.off += int64(k)
+	return k
+}
+
+// escape 解析反斜杠之后的转义序列。
+func (s *stringReader) escape() (rune, error) {
+	c, err := s.d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch c {
+	case '"', '\\', '/':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		var hex [4]byte
+		for i := range hex {
+			if hex[i], err = s.d.readByte(); err != nil {
+				return 0, err
+			}
+		}
+		r, err := strconv.ParseUint(string(hex[:]), 16, 16)
+		if err != nil {
+			return 0, s.d.syntaxError("invalid \\u escape")
+		}
+		return rune(r), nil
+	}
+	return 0, s.d.syntaxError(fmt.Sprintf("invalid escape %q", c))
+}
@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+type testResponse struct {
+	Header struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		SID     string `json:"sid"`
+	} `json:"header"`
+	Payload map[string]struct {
+		Encoding string `json:"encoding"`
+		Status   int    `json:"status"`
+		Text     string `json:"text"`
+	} `json:"payload"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	result := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(result)
+	markdown := []byte("# 标题\n\n正文")
+
+	body := `{"header":{"code":0,"message":"success","sid":"ocr001"},
+		"payload":{
+			"result":{"encoding":"utf8","status":3,"text":"` + base64.StdEncoding.EncodeToString(result) + `"},
+			"markdown":{"text":"` + strings.ReplaceAll(base64.StdEncoding.EncodeToString(markdown), "/", `\/`) + `","status":3},
+			"ignored":{"text":"aWdub3JlZA=="}
+		},
+		"extra":[1,-2.5e3,true,null,{"text":"not payload"}]}`
+
+	var resp testResponse
+	texts := map[string]*bytes.Buffer{"result": {}, "markdown": {}}
+	err := DecodeJSON(iotest.OneByteReader(strings.NewReader(body)), &resp, func(name string) io.Writer {
+		if buf, ok := texts[name]; ok {
+			return buf
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.Header.SID != "ocr001" || resp.Payload["result"].Status != 3 || resp.Payload["result"].Encoding != "utf8" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.Payload["result"].Text != "" {
+		t.Errorf("expected streamed text to be empty in v, got %q", resp.Payload["result"].Text)
+	}
+	if !bytes.Equal(texts["result"].Bytes(), result) {
+		t.Error("decoded result does not match")
+	}
+	if got := texts["markdown"].String(); got != string(markdown) {
+		t.Errorf("expected markdown %q, got %q", markdown, got)
+	}
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	discard := func(string) io.Writer { return io.Discard }
+	for _, body := range []string{
+		``,
+		`{"payload":{"result":{"text":"abc`,
+		`{"payload":{"result":{"text":"!!!!"}}}`,
+		`{"a":1 "b":2}`,
+		`{"a":1} x`,
+		`[1,]`,
+	} {
+		var v map[string]any
+		if err := DecodeJSON(strings.NewReader(body), &v, discard); err == nil {
+			t.Errorf("%q: expected error, got nil", body)
+		}
+	}
+}
+
+// FuzzDecodeJSON 检查不流式解码 text 时，DecodeJSON 与 json.Unmarshal 接受相同的输入并得到相同的结果。
+func FuzzDecodeJSON(f *testing.F) {
+	for _, body := range []string{
+		`{"header":{"code":0,"sid":"ocr001"},"payload":{"result":{"text":"aGk=","status":3}}}`,
+		`[1,-2.5e3,true,null,{"a":"\u00e9\"\\"}]`,
+		` "x" `,
+		`{"a":1 "b":2}`,
+		`{"a":1} x`,
+		`[1,]`,
+		`[1"a"]`,
+		`{"\ud800":tru}`,
+	} {
+		f.Add([]byte(body))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var got, want any
+		err := DecodeJSON(bytes.NewReader(data), &got, nil)
+		wantErr := json.Unmarshal(data, &want)
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("%q: DecodeJSON error %v, json.Unmarshal error %v", data, err, wantErr)
+		}
+		if err == nil && !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q: DecodeJSON got %#v, json.Unmarshal got %#v", data, got, want)
+		}
+	})
+}
+
+// FuzzDecodeJSON_Text 检查流式解码的 payload.*.text 与 base64 解码 json.Unmarshal 得到的字符串相同，
+// 包括 text 中含有转义字符的情况。
+func FuzzDecodeJSON_Text(f *testing.F) {
+	f.Add([]byte("hello"), false)
+	f.Add(bytes.Repeat([]byte{0xff, 0xfe, '/'}, 100), true)
+	f.Fuzz(func(t *testing.T, text []byte, escape bool) {
+		encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(text))
+		if escape {
+			encoded = bytes.ReplaceAll(encoded, []byte("/"), []byte(`\/`))
+			encoded = bytes.ReplaceAll(encoded, []byte("A"), []byte(`\u0041`))
+		}
+		body := `{"header":{"code":0},"payload":{"result":{"text":` + string(encoded) + `,"status":3}}}`
+
+		var resp testResponse
+		var got bytes.Buffer
+		err := DecodeJSON(iotest.HalfReader(strings.NewReader(body)), &resp, func(string) io.Writer { return &got })
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", body, err)
+		}
+		if !bytes.Equal(got.Bytes(), text) || resp.Payload["result"].Status != 3 || resp.Payload["result"].Text != "" {
+			t.Fatalf("%s: got text %q and response %+v", body, got.Bytes(), resp)
+		}
+	})
+}
+
+func TestLimitReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 100)
+
+	if got, err := ReadAll("ocr", bytes.NewReader(data), 100); err != nil || len(got) != 100 {
+		t.Fatalf("expected 100 bytes at the limit, got %d, %v", len(got), err)
+	}
+
+	_, err := ReadAll("ocr", bytes.NewReader(data), 99)
+	var tooLarge *xfyunerr.ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Service != "ocr" || tooLarge.Limit != 99 {
+		t.Fatalf("expected ResponseTooLargeError, got %v", err)
+	}
+
+	// 流式解码同样受限，错误不被包装成 base64 解码错误
+	body := `{"payload":{"result":{"text":"` + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("y"), 1000)) + `"}}}`
+	err = DecodeJSON(LimitReader("llmocr", strings.NewReader(body), 500), new(map[string]any), func(string) io.Writer { return io.Discard })
+	if !errors.As(err, &tooLarge) || tooLarge.Service != "llmocr" {
+		t.Fatalf("expected ResponseTooLargeError, got %v", err)
+	}
+}
+
+// 结果 base64 约 10MB 时的解码开销，对比 BenchmarkDecodeStream。
+func BenchmarkDecodeReadAll(b *testing.B) {
+	body := benchmarkResponse()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _ := io.ReadAll(bytes.NewReader(body))
+		var resp testResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			b.Fatal(err)
+		}
+		text, _ := base64.StdEncoding.DecodeString(resp.Payload["result"].Text)
+		_ = string(text)
+	}
+}
+
+func BenchmarkDecodeStream(b *testing.B) {
+	body := benchmarkResponse()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var resp testResponse
+		var text strings.Builder
+		text.Grow(len(body) / 4 * 3) // 客户端按 Content-Length 预留
+		if err := DecodeJSON(bytes.NewReader(body), &resp, func(string) io.Writer { return &text }); err != nil {
+			b.Fatal(err)
+		}
+		_ = text.String()
+	}
+}
+
+func benchmarkResponse() []byte {
+	result := bytes.Repeat([]byte(`{"text":"识别结果"},`), 8<<20/25)
+	return []byte(`{"header":{"code":0,"sid":"bench"},"payload":{"result":{"status":3,"text":"` +
+		base64.StdEncoding.EncodeToString(result) + `"}}}`)
+}
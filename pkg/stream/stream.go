@@ -0,0 +1,140 @@
+// Package stream 以流式 io.Reader 构造带有大块 base64 字段的 JSON 请求体，
+// 避免先生成完整的 base64 字符串、再由 json.Marshal 复制一遍。
+//
+// 请求结构体中的 base64 字段先填入 Placeholder，JSON 将其替换为 src 的内容：
+//
+//	body.Payload.Image.Image = stream.Placeholder
+//	b, err := stream.JSON(body, stream.Bytes(image))
+//	req, err := b.NewRequest(ctx, http.MethodPost, url)
+//
+// 读取请求体时才逐块编码，同一个 Body 可以多次读取，用于重试与故障切换。
+//
+// 响应方向，LimitReader 限制响应体的大小，DecodeJSON 边读边解码响应中 payload.*.text 的 base64 内容。
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fruitbars/goxfyunclient/pkg/utils"
+)
+
+// Placeholder 是请求结构体中待替换的字段值，序列化结果中必须恰好出现一次。
+const Placeholder = "\x1egoxfyunclient-stream\x1e"
+
+// chunkSize 是每次编码的原始字节数，为 3 的倍数，保证只有最后一块带填充。
+const chunkSize = 3 * 1024
+
+// Source 是写入 Placeholder 位置的 base64 内容，见 Bytes 与 Encoded。
+type Source struct {
+	size int64
+	open func() io.Reader
+}
+
+// Bytes 返回读取时才编码为 base64 的 data，data 在请求结束前不能修改。
+func Bytes(data []byte) Source {
+	return Source{
+		size: int64(base64.StdEncoding.EncodedLen(len(data))),
+		open: func() io.Reader { return &base64Reader{src: data} },
+	}
+}
+
+// Encoded 返回已编码的 base64 字符串 s，按原样写入；含有需要 JSON 转义的字符时先转义。
+func Encoded(s string) Source {
+	if needsEscape(s) {
+		b, _ := json.Marshal(s) // 字符串总能序列化
+		s = string(b[1 : len(b)-1])
+	}
+	return Source{
+		size: int64(len(s)),
+		open: func() io.Reader { return strings.NewReader(s) },
+	}
+}
+
+// Body 是可以多次读取的流式 JSON 请求体。
+type Body struct {
+	prefix, suffix []byte
+	src            Source
+}
+
+// JSON 序列化 v，并将其中的 Placeholder 替换为 src。v 中必须恰好有一个字段的值为 Placeholder。
+func JSON(v any, src Source) (*Body, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	marker, _ := json.Marshal(Placeholder)
+	marker = marker[1 : len(marker)-1] // 两侧的引号留在 prefix 与 suffix 中
+	i := bytes.Index(data, marker)
+	if i < 0 || bytes.Index(data[i+len(marker):], marker) >= 0 {
+		return nil, fmt.Errorf("stream: expected exactly one placeholder in %T", v)
+	}
+	return &Body{prefix: data[:i], suffix: data[i+len(marker):], src: src}, nil
+}
+
+// Reader 返回从头读取请求体的 io.Reader，每次调用都返回新的 Reader。
+func (b *Body) Reader() io.Reader {
+	return io.MultiReader(bytes.NewReader(b.prefix), b.src.open(), bytes.NewReader(b.suffix))
+}
+
+// Len 返回请求体的字节数。
+func (b *Body) Len() int64 {
+	return int64(len(b.prefix)) + b.src.size + int64(len(b.suffix))
+}
+
+// Snippet 返回用于调试日志的请求体摘要，base64 内容以其长度代替。
+func (b *Body) Snippet(maxLen int) string {
+	return utils.SafeSnippet(b.prefix, maxLen) + fmt.Sprintf("<%d bytes>", b.src.size) + utils.SafeSnippet(b.suffix, maxLen)
+}
+
+// NewRequest 创建以 b 为请求体的 HTTP 请求，设置 Content-Length 与 GetBody（用于重定向）。
+func (b *Body) NewRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, b.Reader())
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = b.Len()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(b.Reader()), nil
+	}
+	return req, nil
+}
+
+// base64Reader 在读取时按 chunkSize 逐块编码 src。
+type base64Reader struct {
+	src []byte
+	buf [chunkSize / 3 * 4]byte
+	out []byte
+}
+
+func (r *base64Reader) Read(p []byte) (int, error) {
+	if len(r.out) == 0 {
+		if len(r.src) == 0 {
+			return 0, io.EOF
+		}
+		n := min(len(r.src), chunkSize)
+		m := base64.StdEncoding.EncodedLen(n)
+		base64.StdEncoding.Encode(r.buf[:m], r.src[:n])
+		r.src, r.out = r.src[n:], r.buf[:m]
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// needsEscape 判断 s 是否含有 json.Marshal 会转义的字符。
+func needsEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 0x20, c == '"', c == '\\', c == '<', c == '>', c == '&', c >= 0x80:
+			return true
+		}
+	}
+	return false
+}
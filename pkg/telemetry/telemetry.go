@@ -0,0 +1,239 @@
+// Package telemetry 为各服务客户端提供可选的 OpenTelemetry 链路追踪与指标。
+//
+// 每次 API 调用（含每次重试）产生一个 span，记录服务、接口、sid、header.code、请求体大小与调用序号；
+// 同时记录耗时直方图、按错误码区分的错误计数与发送字节数。
+//
+// 客户端通过 WithTelemetry 选项启用，nil 的 *Telemetry 不做任何记录：
+//
+//	tel, err := telemetry.New(telemetry.WithTracerProvider(tp), telemetry.WithMeterProvider(mp))
+//	client := ocr.NewClient(appID, apiKey, apiSecret, ocr.WithTelemetry(tel))
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+// ScopeName 是 tracer 与 meter 的 instrumentation scope 名称。
+const ScopeName = "github.com/fruitbars/goxfyunclient"
+
+// span 与指标使用的属性名。
+const (
+	AttrService     = attribute.Key("xfyun.service")
+	AttrOperation   = attribute.Key("xfyun.operation")
+	AttrEndpoint    = attribute.Key("xfyun.endpoint")
+	AttrAttempt     = attribute.Key("xfyun.attempt")
+	AttrPayloadSize = attribute.Key("xfyun.payload_size")
+	AttrSID         = attribute.Key("xfyun.sid")
+	AttrCode        = attribute.Key("xfyun.code")
+	AttrFailType    = attribute.Key("xfyun.fail_type")
+	AttrHTTPStatus  = attribute.Key("http.response.status_code")
+	AttrErrorType   = attribute.Key("error.type")
+)
+
+// 指标名称。
+const (
+	MetricDuration  = "xfyun.client.duration"
+	MetricErrors    = "xfyun.client.errors"
+	MetricBytesSent = "xfyun.client.bytes_sent"
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option 配置 Telemetry。
+type Option func(*config)
+
+// WithTracerProvider 指定 TracerProvider，默认为 otel.GetTracerProvider()。
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.tracerProvider = tp
+		}
+	}
+}
+
+// WithMeterProvider 指定 MeterProvider，默认为 otel.GetMeterProvider()。
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		if mp != nil {
+			c.meterProvider = mp
+		}
+	}
+}
+
+// Telemetry 持有 tracer 与各指标，可在多个客户端间共享，并发安全。nil 的 *Telemetry 不做任何记录。
+type Telemetry struct {
+	tracer    trace.Tracer
+	duration  metric.Float64Histogram
+	errors    metric.Int64Counter
+	bytesSent metric.Int64Counter
+}
+
+// New 创建 Telemetry，未指定的 provider 使用 otel 的全局 provider。
+func New(opts ...Option) (*Telemetry, error) {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meter := cfg.meterProvider.Meter(ScopeName)
+	duration, err := meter.Float64Histogram(MetricDuration,
+		metric.WithDescription("xfyun API 调用耗时"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	errCounter, err := meter.Int64Counter(MetricErrors,
+		metric.WithDescription("失败的 xfyun API 调用次数"), metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+	bytesSent, err := meter.Int64Counter(MetricBytesSent,
+		metric.WithDescription("发送给 xfyun 的请求体字节数"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Telemetry{
+		tracer:    cfg.tracerProvider.Tracer(ScopeName),
+		duration:  duration,
+		errors:    errCounter,
+		bytesSent: bytesSent,
+	}, nil
+}
+
+// Call 记录一次 API 调用，由 Start 创建、End 结束。nil 的 *Call 的方法不做任何事。
+type Call struct {
+	t     *Telemetry
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+	sent  int
+	attrs []attribute.KeyValue // 指标公共属性：service、operation
+}
+
+// Start 为服务 service 的一次调用开始一个 span，span 名称为 "service.operation"。
+// endpoint 是请求的 URL，查询参数（其中可能含签名）会被去掉；调用序号取自 retry.Attempt(ctx)。
+// 返回的 ctx 携带该 span，应传给后续发送请求的代码。t 为 nil 时原样返回 ctx 与 nil 的 *Call。
+func (t *Telemetry) Start(ctx context.Context, service, operation, endpoint string) (context.Context, *Call) {
+	if t == nil {
+		return ctx, nil
+	}
+	attrs := []attribute.KeyValue{AttrService.String(service), AttrOperation.String(operation)}
+	ctx, span := t.tracer.Start(ctx, service+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(
+			AttrEndpoint.String(stripQuery(endpoint)),
+			AttrAttempt.Int(retry.Attempt(ctx)),
+		),
+	)
+	return ctx, &Call{t: t, ctx: ctx, span: span, start: time.Now(), attrs: attrs}
+}
+
+// AddBytesSent 记录本次调用发送的请求体字节数，可多次调用累加（例如 WebSocket 的多个数据帧）。
+func (c *Call) AddBytesSent(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+	c.t.bytesSent.Add(c.ctx, int64(n), metric.WithAttributes(c.attrs...))
+	c.sent += n
+	c.span.SetAttributes(AttrPayloadSize.Int(c.sent))
+}
+
+// SetSID 记录讯飞返回的 sid，用于成功响应；失败响应的 sid 由 End 从 *xfyunerr.APIError 中取得。
+func (c *Call) SetSID(sid string) {
+	if c == nil || sid == "" {
+		return
+	}
+	c.span.SetAttributes(AttrSID.String(sid))
+}
+
+// End 结束本次调用，记录耗时；err 非 nil 时记录错误码并计入错误计数。
+// sid、header.code 与 HTTP 状态码取自 err 中的 *xfyunerr.APIError。
+func (c *Call) End(err error) {
+	if c == nil {
+		return
+	}
+	code := 0
+	var apiErr *xfyunerr.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.Code
+		if apiErr.SID != "" {
+			c.span.SetAttributes(AttrSID.String(apiErr.SID))
+		}
+		if apiErr.HTTPStatus != 0 {
+			c.span.SetAttributes(AttrHTTPStatus.Int(apiErr.HTTPStatus))
+		}
+	}
+	var taskErr *xfyunerr.TaskError
+	if errors.As(err, &taskErr) {
+		c.span.SetAttributes(AttrFailType.Int(taskErr.FailType))
+	}
+	c.span.SetAttributes(AttrCode.Int(code))
+
+	attrs := append(c.attrs, AttrCode.Int(code))
+	if err != nil {
+		errType := errorType(err)
+		c.span.RecordError(err)
+		c.span.SetStatus(codes.Error, err.Error())
+		c.span.SetAttributes(AttrErrorType.String(errType))
+		c.t.errors.Add(c.ctx, 1, metric.WithAttributes(append(attrs, AttrErrorType.String(errType))...))
+	}
+	c.t.duration.Record(c.ctx, time.Since(c.start).Seconds(), metric.WithAttributes(attrs...))
+	c.span.End()
+}
+
+// SetSID 为 ctx 中由 Start 创建的 span 记录 sid，ctx 中没有 span 时不做任何事。
+// 用于无法直接拿到 *Call 的响应解析代码。
+func SetSID(ctx context.Context, sid string) {
+	if sid != "" {
+		trace.SpanFromContext(ctx).SetAttributes(AttrSID.String(sid))
+	}
+}
+
+// errorType 返回错误在 error.type 属性中的取值。
+func errorType(err error) string {
+	var apiErr *xfyunerr.APIError
+	var netErr *xfyunerr.NetworkError
+	var taskErr *xfyunerr.TaskError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.As(err, &apiErr):
+		return "api"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.As(err, &taskErr):
+		return "task"
+	}
+	return "other"
+}
+
+// stripQuery 去掉 URL 中的查询参数与 fragment，无法解析时返回空字符串。
+func stripQuery(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.User = nil
+	return u.String()
+}
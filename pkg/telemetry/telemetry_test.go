@@ -0,0 +1,127 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+func newTestTelemetry(t *testing.T) (*Telemetry, *tracetest.InMemoryExporter, *sdkmetric.ManualReader) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	tel, err := New(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return tel, exporter, reader
+}
+
+func spanAttr(span tracetest.SpanStub, key string) (string, bool) {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+func TestCall_Span(t *testing.T) {
+	tel, exporter, _ := newTestTelemetry(t)
+
+	policy := &retry.Policy{MaxAttempts: 2, Classifier: func(error) bool { return true }}
+	_ = policy.Do(context.Background(), func(ctx context.Context) error {
+		ctx, call := tel.Start(ctx, "ocr", "recognize", "https://api.xf-yun.com/v1/ocr?authorization=secret")
+		call.AddBytesSent(128)
+		if retry.Attempt(ctx) == 1 {
+			err := &xfyunerr.APIError{Service: "ocr", Code: 10700, SID: "sid-busy", HTTPStatus: 200}
+			call.End(err)
+			return err
+		}
+		SetSID(ctx, "sid-ok")
+		call.End(nil)
+		return nil
+	})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	want := []map[string]string{
+		{"xfyun.attempt": "1", "xfyun.code": "10700", "xfyun.sid": "sid-busy", "error.type": "api"},
+		{"xfyun.attempt": "2", "xfyun.code": "0", "xfyun.sid": "sid-ok"},
+	}
+	for i, span := range spans {
+		if span.Name != "ocr.recognize" {
+			t.Errorf("Expected span name 'ocr.recognize', got '%s'", span.Name)
+		}
+		if got, _ := spanAttr(span, "xfyun.endpoint"); got != "https://api.xf-yun.com/v1/ocr" {
+			t.Errorf("Expected endpoint without query, got '%s'", got)
+		}
+		if got, _ := spanAttr(span, "xfyun.payload_size"); got != "128" {
+			t.Errorf("Expected payload size 128, got '%s'", got)
+		}
+		for key, value := range want[i] {
+			if got, _ := spanAttr(span, key); got != value {
+				t.Errorf("Span %d: expected %s=%s, got '%s'", i+1, key, value, got)
+			}
+		}
+	}
+}
+
+func TestCall_Metrics(t *testing.T) {
+	tel, _, reader := newTestTelemetry(t)
+
+	_, call := tel.Start(context.Background(), "translate", "translate", "https://itrans.xf-yun.com/v1/its")
+	call.AddBytesSent(64)
+	call.End(&xfyunerr.APIError{Service: "translate", Code: 11203})
+
+	_, call = tel.Start(context.Background(), "translate", "translate", "https://itrans.xf-yun.com/v1/its")
+	call.AddBytesSent(32)
+	call.End(nil)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					got[m.Name] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					got[m.Name] += int64(dp.Count)
+				}
+			}
+		}
+	}
+	if got[MetricDuration] != 2 || got[MetricErrors] != 1 || got[MetricBytesSent] != 96 {
+		t.Errorf("Unexpected metrics: %v", got)
+	}
+}
+
+func TestNil_NoOp(t *testing.T) {
+	var tel *Telemetry
+	ctx := context.Background()
+	gotCtx, call := tel.Start(ctx, "ocr", "recognize", "")
+	if gotCtx != ctx || call != nil {
+		t.Error("Expected nil Telemetry to return the context unchanged and a nil Call")
+	}
+	call.AddBytesSent(1)
+	call.End(nil)
+	SetSID(ctx, "sid")
+}
@@ -0,0 +1,356 @@
+// Package xfyun 提供所有讯飞服务的统一入口：用一份 Config 创建 Client，按需取得各服务的客户端，
+// 它们共享凭证、日志、HTTP 连接池、重试策略与限流器。
+//
+//	client := xfyun.New(xfyun.Config{
+//		Credentials: auth.Credentials{AppID: appID, APIKey: apiKey, APISecret: apiSecret, SecretKey: secretKey},
+//		Retry:       retry.DefaultPolicy(),
+//		Limiter:     ratelimit.ForAppID(appID, ratelimit.Config{QPS: 20, MaxInFlight: 5}),
+//	})
+//	text, err := client.Translate().Translate(ctx, "你好", "cn", "en")
+package xfyun
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fruitbars/goxfyunclient/pkg/auth"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/retry"
+	"github.com/fruitbars/goxfyunclient/pkg/service/detectlanguage"
+	"github.com/fruitbars/goxfyunclient/pkg/service/iocrld"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ist"
+	"github.com/fruitbars/goxfyunclient/pkg/service/llmocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/service/translate"
+	"github.com/fruitbars/goxfyunclient/pkg/service/tts"
+	"github.com/fruitbars/goxfyunclient/pkg/telemetry"
+)
+
+// 服务名，用作 Region 的键，取值与 xfyunerr.APIError.Service 相同。
+const (
+	ServiceOCR            = endpoint.ServiceOCR
+	ServiceLLMOCR         = endpoint.ServiceLLMOCR
+	ServiceIOCRLD         = endpoint.ServiceIOCRLD
+	ServiceTranslate      = endpoint.ServiceTranslate
+	ServiceDetectLanguage = endpoint.ServiceDetectLanguage
+	ServiceIST            = endpoint.ServiceIST
+	ServiceTTS            = endpoint.ServiceTTS
+)
+
+// 各服务在公有云上的路径，RegionAt 将它们拼接到私有化部署或本地模拟器的根地址上。
+var servicePaths = map[string]string{
+	ServiceOCR:            "/v1/ocr",
+	ServiceLLMOCR:         "/v1/private/se75ocrbm",
+	ServiceIOCRLD:         "/v1/private/s15fc3900",
+	ServiceTranslate:      "/v1/its",
+	ServiceDetectLanguage: "/v1/private/s0ed5898e",
+	ServiceIST:            "/v2/api",
+	ServiceTTS:            "/v2/tts",
+}
+
+// Region 是各服务的接入地址，键为服务名（ServiceOCR 等），值为传给对应客户端 WithHost 的地址。
+// 未列出的服务使用客户端内置的公有云地址，因此 nil 的 Region 即为公有云。
+type Region map[string]string
+
+// RegionAt 返回所有服务都部署在 baseURL 之下、路径与公有云相同的 Region，
+// 适用于私有化部署与本地模拟器（cmd/xfyun-emulator）。tts 使用对应的 ws:// 或 wss:// 地址。
+func RegionAt(baseURL string) Region {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	r := make(Region, len(servicePaths))
+	for service, path := range servicePaths {
+		base := baseURL
+		if service == ServiceTTS {
+			base = "ws" + strings.TrimPrefix(baseURL, "http")
+		}
+		r[service] = base + path
+	}
+	return r
+}
+
+// Config 是所有服务客户端共享的配置，零值字段使用各客户端的默认值。
+type Config struct {
+	// Credentials 是静态凭证：AppID、APIKey、APISecret 用于 HMAC 签名的服务，AppID、SecretKey 用于 ist。
+	Credentials auth.Credentials
+
+	// CredentialProvider 非空时，每次请求都从它解析凭证，Credentials 将被忽略。
+	CredentialProvider auth.CredentialProvider
+
+	Logger *slog.Logger
+
+	// HTTPClient 由所有 REST 客户端共享，为 nil 时创建一个 30 秒超时的客户端。
+	// ist 上传使用相同的 Transport，超时为 10 分钟。
+	HTTPClient *http.Client
+
+	// Retry 是 REST 客户端的重试策略，为 nil 时不重试。
+	Retry *retry.Policy
+
+	// Limiter 由所有客户端共享，为 nil 时不限制。
+	Limiter *ratelimit.Limiter
+
+	// Region 是各服务的接入地址，为 nil 时使用公有云地址。
+	Region Region
+
+	// Endpoints 与 EndpointRegion 从 endpoint.Registry 解析 Region 中未列出的服务的主备地址，
+	// 两者都为零值时使用各客户端的默认地址；Endpoints 为 nil 时使用 endpoint.Default。
+	Endpoints      *endpoint.Registry
+	EndpointRegion string
+
+	// Signer 负责 HMAC 签名，为 nil 时使用 auth.DefaultSigner。
+	Signer *auth.Signer
+
+	// AuthMode 决定签名放在 URL 查询参数（默认）还是请求头中。
+	AuthMode auth.Mode
+
+	// Telemetry 为所有客户端记录 OpenTelemetry span 与指标，为 nil 时不记录。
+	Telemetry *telemetry.Telemetry
+
+	// Middlewares 包装所有 REST 客户端的 Transport，见 middleware 包。
+	Middlewares []middleware.Middleware
+
+	// MaxResponseSize 是所有客户端允许的最大响应体字节数，为 0 时使用 stream.DefaultMaxResponseSize。
+	MaxResponseSize int64
+
+	// OCRBackend 是私有化部署的通用文字识别引擎所用的硬件，决定语种对应的分类名，默认英伟达 GPU。
+	OCRBackend ocr.Backend
+
+	// OCRLanguages 是通用文字识别使用的语言表（见 ocr.LoadLanguageMapFile），为 nil 时使用 ocr.GetInstance()。
+	OCRLanguages *ocr.LanguageMap
+
+	// ImagePreprocess 在 ocr 与 llmocr 识别前预处理图片（见 imageprep 包），为 nil 时原样发送。
+	ImagePreprocess *imageprep.Pipeline
+}
+
+// uploadTimeout 是 ist 上传音频的超时时间。
+const uploadTimeout = 10 * time.Minute
+
+// Client 按需创建并缓存各服务的客户端，并发安全。
+type Client struct {
+	cfg Config
+
+	ocrOnce            sync.Once
+	ocr                *ocr.Client
+	llmocrOnce         sync.Once
+	llmocr             *llmocr.Client
+	iocrldOnce         sync.Once
+	iocrld             *iocrld.Client
+	translateOnce      sync.Once
+	translate          *translate.Client
+	detectLanguageOnce sync.Once
+	detectLanguage     *detectlanguage.Client
+	istOnce            sync.Once
+	ist                *ist.Client
+}
+
+// New 根据 cfg 创建 Client，各服务的客户端在第一次使用时创建。
+func New(cfg Config) *Client {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.Signer == nil {
+		cfg.Signer = auth.DefaultSigner
+	}
+	return &Client{cfg: cfg}
+}
+
+// Config 返回 Client 使用的配置。
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
+// endpoints 返回 service 的地址：Region 优先，其次是 Endpoints 中 EndpointRegion 的主备地址。
+// 返回 nil 时使用客户端的默认地址。
+func (c *Client) endpoints(service string) []string {
+	cfg := c.cfg
+	if host := cfg.Region[service]; host != "" {
+		return []string{host}
+	}
+	if cfg.Endpoints == nil && cfg.EndpointRegion == "" {
+		return nil
+	}
+	registry := cfg.Endpoints
+	if registry == nil {
+		registry = endpoint.Default
+	}
+	urls, err := registry.Resolve(service, cfg.EndpointRegion)
+	if err != nil {
+		cfg.Logger.Warn("using the default endpoint", "service", service, "error", err)
+		return nil
+	}
+	return urls
+}
+
+// OCR 返回通用文字识别客户端。
+func (c *Client) OCR() *ocr.Client {
+	c.ocrOnce.Do(func() {
+		cfg := c.cfg
+		opts := []ocr.Option{
+			ocr.WithLogger(cfg.Logger.With("service", ServiceOCR)),
+			ocr.WithHTTPClient(cfg.HTTPClient),
+			ocr.WithRetry(cfg.Retry),
+			ocr.WithLimiter(cfg.Limiter),
+			ocr.WithSigner(cfg.Signer),
+			ocr.WithAuthMode(cfg.AuthMode),
+			ocr.WithTelemetry(cfg.Telemetry),
+			ocr.WithMiddleware(cfg.Middlewares...),
+			ocr.WithCredentials(cfg.CredentialProvider),
+			ocr.WithMaxResponseSize(cfg.MaxResponseSize),
+			ocr.WithBackend(cfg.OCRBackend),
+			ocr.WithLanguageMap(cfg.OCRLanguages),
+			ocr.WithPreprocess(cfg.ImagePreprocess),
+			// 语种识别客户端在 RecognizeAutoDetect 第一次需要时才创建
+			ocr.WithLanguageDetector(ocr.LanguageDetectorFunc(func(ctx context.Context, text string) (map[string]float64, error) {
+				return c.DetectLanguage().DetectProbs(ctx, text)
+			})),
+		}
+		opts = append(opts, ocr.WithEndpoints(c.endpoints(ServiceOCR)...))
+		creds := cfg.Credentials
+		c.ocr = ocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+	})
+	return c.ocr
+}
+
+// LLMOCR 返回大模型文字识别客户端。
+func (c *Client) LLMOCR() *llmocr.Client {
+	c.llmocrOnce.Do(func() {
+		cfg := c.cfg
+		opts := []llmocr.Option{
+			llmocr.WithLogger(cfg.Logger.With("service", ServiceLLMOCR)),
+			llmocr.WithHTTPClient(cfg.HTTPClient),
+			llmocr.WithRetry(cfg.Retry),
+			llmocr.WithLimiter(cfg.Limiter),
+			llmocr.WithSigner(cfg.Signer),
+			llmocr.WithAuthMode(cfg.AuthMode),
+			llmocr.WithTelemetry(cfg.Telemetry),
+			llmocr.WithMiddleware(cfg.Middlewares...),
+			llmocr.WithCredentials(cfg.CredentialProvider),
+			llmocr.WithMaxResponseSize(cfg.MaxResponseSize),
+			llmocr.WithPreprocess(cfg.ImagePreprocess),
+		}
+		opts = append(opts, llmocr.WithEndpoints(c.endpoints(ServiceLLMOCR)...))
+		creds := cfg.Credentials
+		c.llmocr = llmocr.NewClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+	})
+	return c.llmocr
+}
+
+// IOCRLD 返回文档版面识别客户端。
+func (c *Client) IOCRLD() *iocrld.Client {
+	c.iocrldOnce.Do(func() {
+		cfg := c.cfg
+		opts := []iocrld.Option{
+			iocrld.WithLogger(cfg.Logger.With("service", ServiceIOCRLD)),
+			iocrld.WithHTTPClient(cfg.HTTPClient),
+			iocrld.WithRetry(cfg.Retry),
+			iocrld.WithLimiter(cfg.Limiter),
+			iocrld.WithSigner(cfg.Signer),
+			iocrld.WithAuthMode(cfg.AuthMode),
+			iocrld.WithTelemetry(cfg.Telemetry),
+			iocrld.WithMiddleware(cfg.Middlewares...),
+			iocrld.WithCredentials(cfg.CredentialProvider),
+			iocrld.WithMaxResponseSize(cfg.MaxResponseSize),
+		}
+		opts = append(opts, iocrld.WithEndpoints(c.endpoints(ServiceIOCRLD)...))
+		creds := cfg.Credentials
+		c.iocrld = iocrld.NewClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+	})
+	return c.iocrld
+}
+
+// Translate 返回机器翻译客户端。
+func (c *Client) Translate() *translate.Client {
+	c.translateOnce.Do(func() {
+		cfg := c.cfg
+		opts := []translate.Option{
+			translate.WithLogger(cfg.Logger.With("service", ServiceTranslate)),
+			translate.WithHTTPClient(cfg.HTTPClient),
+			translate.WithRetry(cfg.Retry),
+			translate.WithLimiter(cfg.Limiter),
+			translate.WithSigner(cfg.Signer),
+			translate.WithAuthMode(cfg.AuthMode),
+			translate.WithTelemetry(cfg.Telemetry),
+			translate.WithMiddleware(cfg.Middlewares...),
+			translate.WithCredentials(cfg.CredentialProvider),
+			translate.WithMaxResponseSize(cfg.MaxResponseSize),
+		}
+		opts = append(opts, translate.WithEndpoints(c.endpoints(ServiceTranslate)...))
+		creds := cfg.Credentials
+		c.translate = translate.NewClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+	})
+	return c.translate
+}
+
+// DetectLanguage 返回语种识别客户端。
+func (c *Client) DetectLanguage() *detectlanguage.Client {
+	c.detectLanguageOnce.Do(func() {
+		cfg := c.cfg
+		opts := []detectlanguage.Option{
+			detectlanguage.WithLogger(cfg.Logger.With("service", ServiceDetectLanguage)),
+			detectlanguage.WithHTTPClient(cfg.HTTPClient),
+			detectlanguage.WithRetry(cfg.Retry),
+			detectlanguage.WithLimiter(cfg.Limiter),
+			detectlanguage.WithSigner(cfg.Signer),
+			detectlanguage.WithAuthMode(cfg.AuthMode),
+			detectlanguage.WithTelemetry(cfg.Telemetry),
+			detectlanguage.WithMiddleware(cfg.Middlewares...),
+			detectlanguage.WithCredentials(cfg.CredentialProvider),
+			detectlanguage.WithMaxResponseSize(cfg.MaxResponseSize),
+		}
+		opts = append(opts, detectlanguage.WithEndpoints(c.endpoints(ServiceDetectLanguage)...))
+		creds := cfg.Credentials
+		c.detectLanguage = detectlanguage.NewClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+	})
+	return c.detectLanguage
+}
+
+// IST 返回录音文件转写客户端。ist 使用 signa 鉴权，不使用 Retry、AuthMode 与 Credentials.APIKey/APISecret。
+func (c *Client) IST() *ist.Client {
+	c.istOnce.Do(func() {
+		cfg := c.cfg
+		upload := &http.Client{Transport: cfg.HTTPClient.Transport, Timeout: uploadTimeout}
+		opts := []ist.Option{
+			ist.WithLogger(cfg.Logger.With("service", ServiceIST)),
+			ist.WithHTTPClients(upload, cfg.HTTPClient),
+			ist.WithLimiter(cfg.Limiter),
+			ist.WithSigner(cfg.Signer),
+			ist.WithTelemetry(cfg.Telemetry),
+			ist.WithMiddleware(cfg.Middlewares...),
+			ist.WithCredentials(cfg.CredentialProvider),
+			ist.WithMaxResponseSize(cfg.MaxResponseSize),
+		}
+		if urls := c.endpoints(ServiceIST); len(urls) > 0 {
+			opts = append(opts, ist.WithHost(urls[0]))
+		}
+		c.ist = ist.NewClient(cfg.Credentials.AppID, cfg.Credentials.SecretKey, opts...)
+	})
+	return c.ist
+}
+
+// TTS 返回语音合成客户端。tts.Client 持有一个 WebSocket 会话，不能并发使用，
+// 因此每次调用都返回新的客户端；它们共享凭证、签名器、限流器与 Telemetry。
+// WebSocket 不经过 HTTPClient、Retry 与 Middlewares。
+func (c *Client) TTS() *tts.Client {
+	cfg := c.cfg
+	opts := []tts.Option{
+		tts.WithLogger(cfg.Logger.With("service", ServiceTTS)),
+		tts.WithLimiter(cfg.Limiter),
+		tts.WithSigner(cfg.Signer),
+		tts.WithAuthMode(cfg.AuthMode),
+		tts.WithTelemetry(cfg.Telemetry),
+		tts.WithCredentials(cfg.CredentialProvider),
+		tts.WithMaxResponseSize(cfg.MaxResponseSize),
+		tts.WithEndpoints(c.endpoints(ServiceTTS)...),
+	}
+	creds := cfg.Credentials
+	return tts.NewTTSClient(creds.AppID, creds.APIKey, creds.APISecret, opts...)
+}
@@ -0,0 +1,202 @@
+package xfyun
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fruitbars/goxfyunclient/pkg/emulator"
+	"github.com/fruitbars/goxfyunclient/pkg/endpoint"
+	"github.com/fruitbars/goxfyunclient/pkg/fakegateway"
+	"github.com/fruitbars/goxfyunclient/pkg/imageprep"
+	"github.com/fruitbars/goxfyunclient/pkg/middleware"
+	"github.com/fruitbars/goxfyunclient/pkg/ratelimit"
+	"github.com/fruitbars/goxfyunclient/pkg/service/ocr"
+	"github.com/fruitbars/goxfyunclient/pkg/xfyunerr"
+)
+
+func TestClient_AllServices(t *testing.T) {
+	srv := httptest.NewServer(emulator.New(emulator.WithCredentials(fakegateway.DefaultCredentials)))
+	defer srv.Close()
+
+	var requests atomic.Int32
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			return next.RoundTrip(req)
+		})
+	}
+	client := New(Config{
+		Credentials: fakegateway.DefaultCredentials,
+		Region:      RegionAt(srv.URL),
+		Limiter:     ratelimit.New(ratelimit.Config{MaxInFlight: 1}),
+		Middlewares: []middleware.Middleware{count},
+	})
+	ctx := context.Background()
+
+	if resp, err := client.OCR().RecognizeBytes(ctx, []byte("image"), "jpg", "ch_en"); err != nil {
+		t.Errorf("OCR: expected no error, got %v", err)
+	} else if result, err := resp.Result(); err != nil || result.PlainText() != "讯飞开放平台" {
+		t.Errorf("OCR: expected the typed result, got %+v, %v", result, err)
+	}
+	if _, err := client.LLMOCR().RecognizeBytes(ctx, []byte("image"), "jpg", "uid"); err != nil {
+		t.Errorf("LLMOCR: expected no error, got %v", err)
+	}
+	if _, err := client.IOCRLD().Process(ctx, "track", base64.StdEncoding.EncodeToString([]byte("image")), nil); err != nil {
+		t.Errorf("IOCRLD: expected no error, got %v", err)
+	}
+	if _, err := client.Translate().Translate(ctx, "你好", "cn", "en"); err != nil {
+		t.Errorf("Translate: expected no error, got %v", err)
+	}
+	if _, err := client.DetectLanguage().DetectContext(ctx, "你好"); err != nil {
+		t.Errorf("DetectLanguage: expected no error, got %v", err)
+	}
+
+	audio := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(audio, []byte("RIFF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.IST().Process(ctx, audio); err != nil {
+		t.Errorf("IST: expected no error, got %v", err)
+	}
+	if _, err := client.TTS().TextToSpeech(ctx, "你好", "xiaoyan", "raw"); err != nil {
+		t.Errorf("TTS: expected no error, got %v", err)
+	}
+
+	// 5 个 REST 服务各 1 次，ist 上传与查询各 1 次
+	if got := requests.Load(); got != 7 {
+		t.Errorf("Expected the shared middleware to see 7 requests, got %d", got)
+	}
+}
+
+func TestClient_SharesConfiguration(t *testing.T) {
+	httpClient := &http.Client{}
+	limiter := ratelimit.New(ratelimit.Config{QPS: 10})
+	client := New(Config{Credentials: fakegateway.DefaultCredentials, HTTPClient: httpClient, Limiter: limiter})
+
+	if client.OCR() != client.OCR() || client.IST() != client.IST() {
+		t.Error("Expected sub-clients to be created once")
+	}
+	if client.TTS() == client.TTS() {
+		t.Error("Expected a new TTS client per call")
+	}
+	if client.OCR().HTTPClient != httpClient || client.Translate().HTTPClient != httpClient || client.IST().HTTPClient != httpClient {
+		t.Error("Expected REST clients to share the HTTP client")
+	}
+	if client.IST().UploadClient.Transport != httpClient.Transport || client.IST().UploadClient.Timeout != uploadTimeout {
+		t.Error("Expected the IST upload client to share the transport with a longer timeout")
+	}
+	if client.OCR().Limiter != limiter || client.IST().Limiter != limiter || client.TTS().Limiter != limiter {
+		t.Error("Expected all clients to share the limiter")
+	}
+	if client.OCR().Host == "" || client.OCR().AppID != fakegateway.DefaultCredentials.AppID {
+		t.Errorf("Expected the default host and configured AppID, got host=%q app_id=%q", client.OCR().Host, client.OCR().AppID)
+	}
+	languages := ocr.DefaultLanguageMap()
+	ocrClient := New(Config{Credentials: fakegateway.DefaultCredentials, OCRBackend: ocr.BackendCambricon, OCRLanguages: languages}).OCR()
+	if ocrClient.Backend != ocr.BackendCambricon || ocrClient.LanguageMap != languages {
+		t.Errorf("Expected the configured backend and language table, got %s, %p", ocrClient.Backend, ocrClient.LanguageMap)
+	}
+	pipeline := imageprep.Default()
+	if c := New(Config{Credentials: fakegateway.DefaultCredentials, ImagePreprocess: pipeline}); c.OCR().Preprocess != pipeline || c.LLMOCR().Preprocess != pipeline {
+		t.Error("Expected ocr and llmocr to share the preprocessing pipeline")
+	}
+}
+
+func TestClient_EndpointFailover(t *testing.T) {
+	srv := httptest.NewServer(emulator.New(emulator.WithCredentials(fakegateway.DefaultCredentials)))
+	defer srv.Close()
+
+	// 监听后立即关闭，得到一个拒绝连接的地址
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "http://" + ln.Addr().String()
+	ln.Close()
+
+	registry := endpoint.NewRegistry()
+	for _, service := range []string{ServiceOCR, ServiceTranslate, ServiceTTS} {
+		registry.Register(service, "test", RegionAt(dead)[service], RegionAt(srv.URL)[service])
+	}
+	client := New(Config{Credentials: fakegateway.DefaultCredentials, Endpoints: registry, EndpointRegion: "test"})
+	ctx := context.Background()
+
+	if _, err := client.OCR().RecognizeBytes(ctx, []byte("image"), "jpg", "ch_en"); err != nil {
+		t.Errorf("OCR: expected to fail over, got %v", err)
+	}
+	if _, err := client.Translate().Translate(ctx, "你好", "cn", "en"); err != nil {
+		t.Errorf("Translate: expected to fail over, got %v", err)
+	}
+	if _, err := client.TTS().TextToSpeech(ctx, "你好", "xiaoyan", "raw"); err != nil {
+		t.Errorf("TTS: expected to fail over, got %v", err)
+	}
+	// 区域未登记的服务使用默认地址
+	if got := client.IOCRLD().Host; got == "" || got == RegionAt(dead)[ServiceIOCRLD] {
+		t.Errorf("Expected the default iocrld host, got %q", got)
+	}
+}
+
+func TestClient_MaxResponseSize(t *testing.T) {
+	result := strings.Repeat(`{"name":"line","value":"讯飞开放平台"},`, 1000)
+	srv := httptest.NewServer(emulator.New(
+		emulator.WithCredentials(fakegateway.DefaultCredentials),
+		emulator.WithResponse(emulator.ServiceLLMOCR, emulator.Response{Result: result}),
+		emulator.WithResponse(emulator.ServiceOCR, emulator.Response{Result: result}),
+	))
+	defer srv.Close()
+	ctx := context.Background()
+
+	// 流式解码得到完整的结果
+	client := New(Config{Credentials: fakegateway.DefaultCredentials, Region: RegionAt(srv.URL), MaxResponseSize: 1 << 20})
+	got, err := client.LLMOCR().RecognizeBytes(ctx, []byte("image"), "jpg", "uid")
+	if err != nil || got != result {
+		t.Fatalf("LLMOCR: expected the decoded result, got %d bytes, %v", len(got), err)
+	}
+
+	client = New(Config{Credentials: fakegateway.DefaultCredentials, Region: RegionAt(srv.URL), MaxResponseSize: 4096})
+	var tooLarge *xfyunerr.ResponseTooLargeError
+	if _, err := client.LLMOCR().RecognizeBytes(ctx, []byte("image"), "jpg", "uid"); !errors.As(err, &tooLarge) || tooLarge.Service != ServiceLLMOCR || tooLarge.Limit != 4096 {
+		t.Errorf("LLMOCR: expected ResponseTooLargeError, got %v", err)
+	}
+	if _, err := client.OCR().RecognizeBytes(ctx, []byte("image"), "jpg", "ch_en"); !errors.As(err, &tooLarge) || tooLarge.Service != ServiceOCR {
+		t.Errorf("OCR: expected ResponseTooLargeError, got %v", err)
+	}
+}
+
+// ocr 的 RecognizeAutoDetect 通过共享配置的语种识别客户端判断拉丁字母等文字的语种。
+func TestClient_OCRLanguageDetector(t *testing.T) {
+	srv := httptest.NewServer(emulator.New(
+		emulator.WithCredentials(fakegateway.DefaultCredentials),
+		emulator.WithResponse(emulator.ServiceDetectLanguage, emulator.Response{Result: `{"ar": 0.7, "en": 0.3}`}),
+	))
+	defer srv.Close()
+
+	client := New(Config{Credentials: fakegateway.DefaultCredentials, Region: RegionAt(srv.URL)})
+	probs, err := client.OCR().LanguageDetector.DetectProbs(context.Background(), "lvwlo ilc")
+	if err != nil || probs["ar"] != 0.7 {
+		t.Errorf("Expected the probabilities from detectlanguage, got %v, %v", probs, err)
+	}
+}
+
+func TestRegionAt(t *testing.T) {
+	r := RegionAt("http://127.0.0.1:8080/")
+	if r[ServiceOCR] != "http://127.0.0.1:8080/v1/ocr" || r[ServiceTTS] != "ws://127.0.0.1:8080/v2/tts" {
+		t.Errorf("Unexpected region: %v", r)
+	}
+	// 路径与本地模拟器一致
+	h := RegionAt("http://h")
+	for _, service := range emulator.Services {
+		if want := emulator.Endpoint("http://h", service); h[service] != want {
+			t.Errorf("Expected %s at %s, got %s", service, want, h[service])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package xfyunerr
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// 目录中的命名空间。服务专有的错误码以服务名（与 APIError.Service 相同）为命名空间，
+// 查找时先查服务专有的命名空间，再查 CatalogCommon。
+const (
+	// CatalogCommon 是各服务通用的讯飞 WebAPI 错误码。
+	CatalogCommon = "common"
+
+	// CatalogISTFailType 是 ist 查询结果中 content.orderInfo.failType 的取值。
+	CatalogISTFailType = "ist.failType"
+)
+
+// Entry 是错误码目录中的一项。
+type Entry struct {
+	Namespace   string // 所属命名空间，例如 "common"、"ist"
+	Code        int    // 错误码
+	Description string // 错误码的含义与排查建议
+	Category    error  // 所属分类（ErrAuth 等），无法归类时为 nil
+	Retryable   bool   // 是否为临时性错误，稍后重试可能成功
+}
+
+//go:embed codes.json
+var catalogJSON []byte
+
+var categoriesByName = map[string]error{
+	"auth":          ErrAuth,
+	"quota":         ErrQuota,
+	"invalid_input": ErrInvalidInput,
+	"server_busy":   ErrServerBusy,
+	"timeout":       ErrTimeout,
+}
+
+// catalog 按命名空间、错误码索引，在包初始化时从内嵌的 codes.json 加载。
+var catalog = mustLoadCatalog(catalogJSON)
+
+func mustLoadCatalog(data []byte) map[string]map[int]Entry {
+	var raw map[string][]struct {
+		Code        int    `json:"code"`
+		Category    string `json:"category"`
+		Retryable   bool   `json:"retryable"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		panic(fmt.Sprintf("xfyunerr: invalid codes.json: %v", err))
+	}
+
+	c := make(map[string]map[int]Entry, len(raw))
+	for ns, items := range raw {
+		entries := make(map[int]Entry, len(items))
+		for _, it := range items {
+			category, ok := categoriesByName[it.Category]
+			if it.Category != "" && !ok {
+				panic(fmt.Sprintf("xfyunerr: unknown category %q for %s/%d", it.Category, ns, it.Code))
+			}
+			entries[it.Code] = Entry{Namespace: ns, Code: it.Code, Description: it.Description, Category: category, Retryable: it.Retryable}
+		}
+		c[ns] = entries
+	}
+	return c
+}
+
+// Lookup 查找 service 的错误码 code，service 专有的条目优先，其次是通用错误码。
+func Lookup(service string, code int) (Entry, bool) {
+	if e, ok := catalog[service][code]; ok {
+		return e, true
+	}
+	e, ok := catalog[CatalogCommon][code]
+	return e, ok
+}
+
+// Entries 返回命名空间 namespace 中的全部条目（不含通用错误码），按错误码升序排列。
+func Entries(namespace string) []Entry {
+	entries := make([]Entry, 0, len(catalog[namespace]))
+	for _, e := range catalog[namespace] {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// Namespaces 返回目录中的全部命名空间，按名称排序。
+func Namespaces() []string {
+	names := make([]string, 0, len(catalog))
+	for ns := range catalog {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CategoryName 返回分类哨兵错误在目录中的名称，例如 ErrQuota 为 "quota"，未知分类返回空字符串。
+func CategoryName(category error) string {
+	for name, c := range categoriesByName {
+		if c == category {
+			return name
+		}
+	}
+	return ""
+}
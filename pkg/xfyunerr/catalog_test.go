@@ -0,0 +1,73 @@
+package xfyunerr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	e, ok := Lookup("ist", 26601)
+	if !ok || e.Namespace != "ist" || e.Category != ErrAuth || e.Retryable {
+		t.Errorf("Unexpected ist entry: %+v, ok=%v", e, ok)
+	}
+
+	// 服务没有专有条目时回落到通用错误码
+	e, ok = Lookup("ocr", 11202)
+	if !ok || e.Namespace != CatalogCommon || e.Category != ErrQuota || !e.Retryable {
+		t.Errorf("Unexpected common entry: %+v, ok=%v", e, ok)
+	}
+
+	if _, ok := Lookup("ocr", 99999); ok {
+		t.Error("Expected unknown code not to be found")
+	}
+}
+
+func TestCatalogIntegrity(t *testing.T) {
+	for _, ns := range Namespaces() {
+		entries := Entries(ns)
+		if len(entries) == 0 {
+			t.Errorf("Namespace %s has no entries", ns)
+		}
+		for _, e := range entries {
+			if e.Description == "" {
+				t.Errorf("%s/%d has no description", ns, e.Code)
+			}
+			if e.Retryable && e.Category == nil {
+				t.Errorf("%s/%d is retryable but has no category", ns, e.Code)
+			}
+		}
+	}
+}
+
+func TestAPIError_Description(t *testing.T) {
+	err := &APIError{Service: "ocr", Code: 10163, Message: "$.payload.image.encoding is invalid", SID: "ocr000"}
+	if !strings.Contains(err.Error(), "参数校验失败") {
+		t.Errorf("Expected description in error, got '%s'", err.Error())
+	}
+	if err.Retryable() {
+		t.Error("Expected 10163 not to be retryable")
+	}
+
+	unknown := &APIError{Service: "ocr", Code: 99999, Message: "boom"}
+	if strings.Contains(unknown.Error(), "(") {
+		t.Errorf("Expected no description for unknown code, got '%s'", unknown.Error())
+	}
+}
+
+func TestTaskError(t *testing.T) {
+	err := &TaskError{Service: "ist", TaskID: "DKHJQ2022", Status: -1, FailType: 3}
+	if !strings.Contains(err.Error(), "音频时长超限") {
+		t.Errorf("Expected failType description in error, got '%s'", err.Error())
+	}
+	if !errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrServerBusy) {
+		t.Error("Expected failType 3 to match ErrInvalidInput only")
+	}
+	if err.Retryable() {
+		t.Error("Expected failType 3 not to be retryable")
+	}
+
+	if !(&TaskError{Service: "ist", FailType: 2}).Retryable() {
+		t.Error("Expected failType 2 to be retryable")
+	}
+}
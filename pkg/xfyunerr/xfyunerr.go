@@ -0,0 +1,196 @@
+// Package xfyunerr 定义各服务客户端共用的错误类型。
+//
+// 服务端返回的失败（HTTP 状态码非 200 或业务错误码非 0）统一为 *APIError，
+// 发送请求阶段的失败（连接失败、超时等）为 *NetworkError，异步任务执行失败为 *TaskError。
+// 三者都可以通过
+// errors.Is 与本包的分类哨兵错误比较，例如：
+//
+//	if errors.Is(err, xfyunerr.ErrQuota) { ... }
+//
+// 或通过 errors.As 取得错误码与 sid：
+//
+//	var apiErr *xfyunerr.APIError
+//	if errors.As(err, &apiErr) { log.Println(apiErr.Code, apiErr.SID) }
+//
+// 错误码的含义、分类与是否可重试来自内嵌的错误码目录（codes.json），可通过 Lookup 查询。
+package xfyunerr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// 错误分类。APIError 与 NetworkError 通过 Is 方法匹配对应的分类。
+var (
+	// ErrAuth 表示鉴权失败：签名错误、date 过期、AppID 无效或无权限。
+	ErrAuth = errors.New("xfyun: authentication failed")
+
+	// ErrQuota 表示配额或流控超限。
+	ErrQuota = errors.New("xfyun: quota exceeded")
+
+	// ErrInvalidInput 表示请求参数或数据不合法。
+	ErrInvalidInput = errors.New("xfyun: invalid input")
+
+	// ErrServerBusy 表示服务端繁忙或内部错误，通常可以稍后重试。
+	ErrServerBusy = errors.New("xfyun: server busy")
+
+	// ErrTimeout 表示请求或会话超时。
+	ErrTimeout = errors.New("xfyun: timeout")
+)
+
+// APIError 表示讯飞服务端返回的失败。
+// 网关层失败（例如签名错误）只有 HTTPStatus，Code 为 0；业务失败 Code 非 0，HTTPStatus 通常为 200。
+type APIError struct {
+	Service    string // 服务名，例如 "ocr"
+	Code       int    // header.code（ist 为 code 字段转换后的整数）
+	Message    string // header.message，网关层失败时为响应体中的 message
+	SID        string // 会话 ID，排查问题时提供给讯飞
+	HTTPStatus int    // HTTP 状态码
+}
+
+func (e *APIError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("xfyun %s error: status=%d, message=%s", e.Service, e.HTTPStatus, e.Message)
+	}
+	msg := fmt.Sprintf("xfyun %s error: code=%d, message=%s, sid=%s", e.Service, e.Code, e.Message, e.SID)
+	if entry, ok := Lookup(e.Service, e.Code); ok {
+		msg += " (" + entry.Description + ")"
+	}
+	return msg
+}
+
+// Is 使 errors.Is(err, ErrAuth) 等分类判断成立。
+func (e *APIError) Is(target error) bool {
+	return target != nil && e.Category() == target
+}
+
+// Category 返回错误所属的分类哨兵错误，无法归类时返回 nil。
+// 业务错误按错误码目录归类，网关层错误按 HTTP 状态码归类。
+func (e *APIError) Category() error {
+	if e.Code != 0 {
+		entry, _ := Lookup(e.Service, e.Code)
+		return entry.Category
+	}
+	switch {
+	case e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden:
+		return ErrAuth
+	case e.HTTPStatus == http.StatusTooManyRequests:
+		return ErrQuota
+	case e.HTTPStatus == http.StatusRequestTimeout || e.HTTPStatus == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case e.HTTPStatus == http.StatusBadRequest || e.HTTPStatus == http.StatusRequestEntityTooLarge:
+		return ErrInvalidInput
+	case e.HTTPStatus >= 500:
+		return ErrServerBusy
+	}
+	return nil
+}
+
+// Retryable 报告错误是否为临时性错误：业务错误按错误码目录判断，
+// 网关层错误在 HTTP 429 与 5xx 时可重试。
+func (e *APIError) Retryable() bool {
+	if e.Code != 0 {
+		entry, _ := Lookup(e.Service, e.Code)
+		return entry.Retryable
+	}
+	return e.HTTPStatus == http.StatusTooManyRequests || e.HTTPStatus >= 500
+}
+
+// ParseCode 将 ist 等服务的字符串错误码（如 "26601"）转换为整数，无法转换时返回 -1。
+func ParseCode(code string) int {
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// TaskError 表示异步任务（例如 ist 转写订单）在服务端执行失败。
+// 请求本身是成功的，失败原因由任务状态中的 FailType 给出，其含义见错误码目录中的
+// Service+".failType" 命名空间（例如 CatalogISTFailType）。
+type TaskError struct {
+	Service  string
+	TaskID   string
+	Status   int
+	FailType int
+	Message  string
+}
+
+func (e *TaskError) Error() string {
+	msg := fmt.Sprintf("xfyun %s task %s failed: status=%d, failType=%d", e.Service, e.TaskID, e.Status, e.FailType)
+	if entry, ok := e.entry(); ok {
+		msg += " (" + entry.Description + ")"
+	}
+	if e.Message != "" {
+		msg += ", message=" + e.Message
+	}
+	return msg
+}
+
+// Is 使 errors.Is(err, ErrInvalidInput) 等分类判断按 FailType 成立。
+func (e *TaskError) Is(target error) bool {
+	entry, ok := e.entry()
+	return ok && target != nil && entry.Category == target
+}
+
+// Retryable 报告重新提交任务是否可能成功。
+func (e *TaskError) Retryable() bool {
+	entry, _ := e.entry()
+	return entry.Retryable
+}
+
+func (e *TaskError) entry() (Entry, bool) {
+	entry, ok := catalog[e.Service+".failType"][e.FailType]
+	return entry, ok
+}
+
+// NetworkError 表示请求未得到服务端响应的失败，例如连接失败或超时。
+type NetworkError struct {
+	Service string
+	Err     error
+}
+
+func (e *NetworkError) Error() string { return e.Err.Error() }
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// Is 使超时类的网络错误匹配 ErrTimeout。
+func (e *NetworkError) Is(target error) bool {
+	if target != ErrTimeout {
+		return false
+	}
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// FromStatus 根据 HTTP 状态码非 200 的响应构造 APIError。
+// 网关层错误的响应体形如 {"message": "..."}，能解析时使用其中的 message，否则使用响应体原文。
+func FromStatus(service string, status int, body []byte) *APIError {
+	msg := string(body)
+	var gw struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &gw) == nil && gw.Message != "" {
+		msg = gw.Message
+	}
+	return &APIError{Service: service, HTTPStatus: status, Message: msg}
+}
+
+// ResponseTooLargeError 表示响应体超过了客户端允许的最大字节数，见各客户端的 WithMaxResponseSize。
+// 响应体未被完整读取，重试通常不会成功。
+type ResponseTooLargeError struct {
+	Service string
+	Limit   int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("xfyun %s error: response body exceeds %d bytes", e.Service, e.Limit)
+}
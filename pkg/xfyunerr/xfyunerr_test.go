@@ -0,0 +1,71 @@
+package xfyunerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		want error
+	}{
+		{name: "gateway 401", err: &APIError{Service: "ocr", HTTPStatus: http.StatusUnauthorized}, want: ErrAuth},
+		{name: "gateway 403", err: &APIError{Service: "ocr", HTTPStatus: http.StatusForbidden}, want: ErrAuth},
+		{name: "http 429", err: &APIError{Service: "ocr", HTTPStatus: http.StatusTooManyRequests}, want: ErrQuota},
+		{name: "http 502", err: &APIError{Service: "ocr", HTTPStatus: http.StatusBadGateway}, want: ErrServerBusy},
+		{name: "http 504", err: &APIError{Service: "ocr", HTTPStatus: http.StatusGatewayTimeout}, want: ErrTimeout},
+		{name: "invalid appid", err: &APIError{Service: "ocr", Code: 10313, HTTPStatus: 200}, want: ErrAuth},
+		{name: "qps limit", err: &APIError{Service: "ocr", Code: 11202, HTTPStatus: 200}, want: ErrQuota},
+		{name: "invalid param", err: &APIError{Service: "ocr", Code: 10106, HTTPStatus: 200}, want: ErrInvalidInput},
+		{name: "engine busy", err: &APIError{Service: "ocr", Code: 10700, HTTPStatus: 200}, want: ErrServerBusy},
+		{name: "session timeout", err: &APIError{Service: "tts", Code: 10114}, want: ErrTimeout},
+		{name: "ist illegal app", err: &APIError{Service: "ist", Code: ParseCode("26601"), HTTPStatus: 200}, want: ErrAuth},
+	}
+	all := []error{ErrAuth, ErrQuota, ErrInvalidInput, ErrServerBusy, ErrTimeout}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("request failed: %w", tt.err)
+			for _, category := range all {
+				if got := errors.Is(wrapped, category); got != (category == tt.want) {
+					t.Errorf("errors.Is(%v): expected %v, got %v", category, category == tt.want, got)
+				}
+			}
+			var apiErr *APIError
+			if !errors.As(wrapped, &apiErr) || apiErr != tt.err {
+				t.Error("Expected errors.As to return the original *APIError")
+			}
+		})
+	}
+}
+
+func TestAPIError_UnknownCode(t *testing.T) {
+	err := &APIError{Service: "ocr", Code: 99999, HTTPStatus: 200}
+	if err.Category() != nil {
+		t.Errorf("Expected no category for unknown code, got %v", err.Category())
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	err := FromStatus("ocr", http.StatusUnauthorized, []byte(`{"message":"HMAC signature does not match"}`))
+	if err.Message != "HMAC signature does not match" {
+		t.Errorf("Expected gateway message, got '%s'", err.Message)
+	}
+	if err := FromStatus("ocr", http.StatusBadGateway, []byte("<html>bad gateway</html>")); err.Message != "<html>bad gateway</html>" {
+		t.Errorf("Expected raw body, got '%s'", err.Message)
+	}
+}
+
+func TestNetworkError_Timeout(t *testing.T) {
+	if !errors.Is(&NetworkError{Service: "ocr", Err: context.DeadlineExceeded}, ErrTimeout) {
+		t.Error("Expected deadline exceeded to match ErrTimeout")
+	}
+	if errors.Is(&NetworkError{Service: "ocr", Err: errors.New("connection refused")}, ErrTimeout) {
+		t.Error("Expected connection refused not to match ErrTimeout")
+	}
+}